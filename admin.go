@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminTokenCaller is the caller identity of requests authenticated with ADMIN_TOKEN
+const adminTokenCaller = "admin-token"
+
+// newAdminRouter builds the router of the /admin endpoints. It is separate from the API router so
+// operator controls have their own authentication, and can be served on their own listener
+// (ADMIN_LISTEN_ADDR) that the Task Service never reaches:
+//   - with ADMIN_TOKEN set, every request needs "Authorization: Bearer <token>";
+//   - otherwise callers authenticate like on the API (client certificate, trusted headers,
+//     ServiceAccount token, API key with the admin scope) and must be listed in ADMIN_CALLERS.
+//
+// On the admin listener, ADMIN_CLIENT_CA_FILE additionally requires a client certificate (mTLS),
+// which without ADMIN_CALLERS admits any caller with a certificate signed by that CA. With none of
+// these, the endpoints aren't served at all (see adminAuthConfigured).
+func newAdminRouter(config *Config) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery(), injectConfig, traceRequests, logRequests, authenticateClientCertificate)
+	if config.AdminToken != "" {
+		r.Use(requireAdminToken)
+	} else {
+		r.Use(authenticateTrustedHeaders, authenticateServiceAccountToken, authenticateAPIKey, requireAdminCaller)
+	}
+
+	admin := r.Group("/admin")
+	admin.GET("/loglevel", getLogLevel)
+	admin.PUT("/loglevel", putLogLevel)
+	admin.GET("/config", getConfig)
+	admin.POST("/reload", postReload)
+	admin.POST("/scripts/reload", postScriptsReload)
+	admin.GET("/maintenance", getMaintenance)
+	admin.PUT("/maintenance", putMaintenance)
+	admin.POST("/preflight", postPreflight)
+	admin.POST("/permissions/check", postPermissionsCheck)
+	return r
+}
+
+// requireAdminToken is middleware admitting only requests bearing ADMIN_TOKEN.
+func requireAdminToken(c *gin.Context) {
+	presented, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(presented), []byte(configFromContext(c).AdminToken)) != 1 {
+		logger.Warn().Msgf("Rejected request to %s from %s: invalid admin token", c.FullPath(), c.ClientIP())
+		writeJSON(c, http.StatusUnauthorized, problem(http.StatusUnauthorized, codeUnauthenticated, "Missing or invalid admin token"))
+		c.Abort()
+		return
+	}
+	if _, authenticated := c.Get(callerContextKey); !authenticated {
+		c.Set(callerContextKey, Caller{Name: adminTokenCaller})
+	}
+	c.Next()
+}
+
+// requireAdminCaller is middleware admitting only callers allowed by ADMIN_CALLERS.
+func requireAdminCaller(c *gin.Context) {
+	caller := callerFromContext(c)
+	if !mayAdminister(configFromContext(c), caller) {
+		writeJSON(c, http.StatusForbidden, problem(http.StatusForbidden, codeForbidden, fmt.Sprintf("Caller '%s' may not use admin endpoints", caller.Name)))
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// mayAdminister reports whether the caller may use the /admin endpoints: only callers with one of
+// the ADMIN_CALLERS names or groups, or without ADMIN_CALLERS, callers authenticated by a client
+// certificate on an mTLS admin listener. Anonymous callers never may.
+func mayAdminister(config *Config, caller Caller) bool {
+	if caller.Name == anonymousCaller.Name {
+		return false
+	}
+	if len(config.AdminCallers) == 0 {
+		return adminRequiresClientCertificate(config)
+	}
+	if containsString(config.AdminCallers, caller.Name) {
+		return true
+	}
+	for _, group := range caller.Groups {
+		if containsString(config.AdminCallers, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// adminAuthConfigured reports whether any admin authentication is configured: ADMIN_TOKEN,
+// ADMIN_CALLERS, or an mTLS admin listener. Without one, no caller could ever be admitted, so the
+// admin router isn't mounted.
+func adminAuthConfigured(config *Config) bool {
+	return config.AdminToken != "" || len(config.AdminCallers) > 0 || adminRequiresClientCertificate(config)
+}
+
+// adminRequiresClientCertificate reports whether the admin endpoints are served only on their own
+// listener, to clients with a certificate signed by ADMIN_CLIENT_CA_FILE.
+func adminRequiresClientCertificate(config *Config) bool {
+	return config.AdminListenAddr != "" && config.AdminClientCAFile != ""
+}
+
+// withAdminRoutes serves /admin/ requests with the admin router and everything else with the API
+// router, for when the admin endpoints share the API listener.
+func withAdminRoutes(api, admin http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/admin/") {
+			admin.ServeHTTP(w, req)
+			return
+		}
+		api.ServeHTTP(w, req)
+	})
+}
+
+// startAdminServer serves the admin router on ADMIN_LISTEN_ADDR, over HTTPS when
+// ADMIN_TLS_CERT_FILE is set and with required client certificates when ADMIN_CLIENT_CA_FILE is.
+func startAdminServer(config *Config, admin http.Handler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              config.AdminListenAddr,
+		Handler:           admin,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+	if config.AdminTLSCertFile == "" {
+		logger.Info().Msgf("Starting admin server on %s...", config.AdminListenAddr)
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatal().Msgf("Failed to start admin server: %v", err)
+			}
+		}()
+		return server, nil
+	}
+	tlsFiles, err := newReloadingTLSFiles(config.AdminTLSCertFile, config.AdminTLSKeyFile, config.AdminClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = tlsFiles.tlsConfig("require")
+	logger.Info().Msgf("Starting admin HTTPS server on %s (client CA: '%s')...", config.AdminListenAddr, config.AdminClientCAFile)
+	go func() {
+		if err := server.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+			logger.Fatal().Msgf("Failed to start admin server: %v", err)
+		}
+	}()
+	return server, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestMayAdminister(t *testing.T) {
+	callers := &Config{AdminCallers: []string{"platform-admin", "sre"}}
+	mtls := &Config{AdminListenAddr: ":9090", AdminClientCAFile: "/etc/admin/ca.pem"}
+	tests := []struct {
+		name   string
+		config *Config
+		caller Caller
+		want   bool
+	}{
+		{"listed name", callers, Caller{Name: "platform-admin"}, true},
+		{"listed group", callers, Caller{Name: "alice", Groups: []string{"dev", "sre"}}, true},
+		{"unlisted caller", callers, Caller{Name: "alice", Groups: []string{"dev"}}, false},
+		{"anonymous with callers", callers, anonymousCaller, false},
+		{"anonymous listed by name", &Config{AdminCallers: []string{anonymousCaller.Name}}, anonymousCaller, false},
+		{"certificate on mTLS listener", mtls, Caller{Name: "ops-laptop"}, true},
+		{"anonymous on mTLS listener", mtls, anonymousCaller, false},
+		{"nothing configured", &Config{}, Caller{Name: "alice"}, false},
+		{"listener without client CA", &Config{AdminListenAddr: ":9090"}, Caller{Name: "alice"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mayAdminister(tt.config, tt.caller); got != tt.want {
+				t.Errorf("mayAdminister(%+v) = %v, want %v", tt.caller, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminAuthConfigured(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   bool
+	}{
+		{"nothing set", &Config{}, false},
+		{"admin token", &Config{AdminToken: "s3cret"}, true},
+		{"admin callers", &Config{AdminCallers: []string{"platform-admin"}}, true},
+		{"mTLS admin listener", &Config{AdminListenAddr: ":9090", AdminClientCAFile: "/tls/ca.crt"}, true},
+		{"admin listener without client CA", &Config{AdminListenAddr: ":9090"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := adminAuthConfigured(tt.config); got != tt.want {
+				t.Errorf("adminAuthConfigured(%+v) = %v, want %v", tt.config, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// podAffinityEntry pins a TrackingID to the pod that served its first execution
+type podAffinityEntry struct {
+	Pod     string
+	Expires time.Time
+}
+
+// podAffinityCache remembers which pod served each TrackingID, so multi-call workflows that
+// reuse a TrackingID see intermediate files written by earlier steps (STICKY_POD_AFFINITY).
+type podAffinityCache struct {
+	mu      sync.Mutex
+	entries map[string]podAffinityEntry
+}
+
+// stickyPods is the process-wide TrackingID -> pod pin cache
+var stickyPods = &podAffinityCache{entries: make(map[string]podAffinityEntry)}
+
+// podAffinityKey scopes a pin to the workload: the same TrackingID may drive scripts on different selectors.
+func podAffinityKey(trackingID, namespace string, selectors []string) string {
+	return trackingID + "|" + namespace + "|" + strings.Join(selectors, ",")
+}
+
+// Get returns the pinned pod for a key, if any and not expired. With REDIS_URL, pins are shared
+// by all replicas; if Redis fails, this replica's own pins are used.
+func (c *podAffinityCache) Get(key string) (string, bool) {
+	if sharedState != nil && key != "" {
+		pod, ok, err := getSharedPin(key)
+		if err == nil {
+			return pod, ok
+		}
+		logger.Warn().Msgf("Shared pod affinity unavailable, using this replica's pins: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.Expires) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.Pod, true
+}
+
+// Set pins a key to a pod for the given TTL, pruning expired pins along the way. The pin is kept
+// in memory as well, for when Redis fails.
+func (c *podAffinityCache) Set(key, pod string, ttl time.Duration) {
+	if sharedState != nil {
+		if err := setSharedPin(key, pod, ttl); err != nil {
+			logger.Warn().Msgf("Failed to share the pod pin of '%s': %v", key, err)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.Expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = podAffinityEntry{Pod: pod, Expires: now.Add(ttl)}
+}
+
+// isPodRunning reports whether the named pod still exists and is in the Running phase.
+func isPodRunning(namespace, podName string) (bool, error) {
+	out, err := exec.Command("kubectl", "get", "pod", podName, "-n", namespace, "-o", "jsonpath={.status.phase}").Output()
+	if err != nil {
+		stderr := ""
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = string(exitErr.Stderr)
+		}
+		if strings.Contains(stderr, "NotFound") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get pod %s (namespace: %s): %v, stderr: %s", podName, namespace, err, stderr)
+	}
+	return strings.TrimSpace(string(out)) == "Running", nil
+}
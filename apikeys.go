@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyHeader carries the API key on requests
+const apiKeyHeader = "X-API-Key"
+
+// API key scopes, each granting access to a group of endpoints
+const (
+	scopeOptions = "options" // GET /v1/options and /v1/quotas
+	scopeExecute = "execute" // POST /v1/execute and /v1/execute/dry-run
+	scopeCatalog = "catalog" // GET /v1/catalog/export
+	scopeAudit   = "audit"   // GET /v1/audit
+	scopeHistory = "history" // GET /v1/executions
+	scopeAdmin   = "admin"   // /admin endpoints
+)
+
+// endpointScopes maps routes to the scope an API key needs to call them. Routes not listed here
+// (health and version probes, the separately token-protected trigger endpoint) need no API key.
+var endpointScopes = map[string]string{
+	"/v1/options":               scopeOptions,
+	"/v1/execute":               scopeExecute,
+	"/v1/execute/dry-run":       scopeExecute,
+	"/v1/catalog/export":        scopeCatalog,
+	"/v1/scripts/validate":      scopeOptions,
+	"/v1/quotas":                scopeOptions,
+	"/v1/status":                scopeOptions,
+	"/v1/audit":                 scopeAudit,
+	"/v1/executions":            scopeHistory,
+	"/v1/executions/compare":    scopeHistory,
+	"/v1/executions/:id":        scopeHistory,
+	"/v1/executions/:id/output": scopeHistory,
+	"/v1/executions/:id/rerun":  scopeExecute,
+	"/v1/executions/:id/cancel": scopeExecute,
+	"/admin/loglevel":           scopeAdmin,
+	"/admin/config":             scopeAdmin,
+	"/admin/reload":             scopeAdmin,
+	"/admin/scripts/reload":     scopeAdmin,
+	"/admin/maintenance":        scopeAdmin,
+	"/admin/preflight":          scopeAdmin,
+	"/admin/permissions/check":  scopeAdmin,
+}
+
+// APIKey is one entry of the API keys file (API_KEYS_PATH), typically mounted from a Secret.
+type APIKey struct {
+	Name   string   `json:"name"`             // Caller identity of requests made with this key
+	Key    string   `json:"key"`              // The secret key value
+	Scopes []string `json:"scopes"`           // Endpoints the key may call, see endpointScopes
+	Tags   []string `json:"tags,omitempty"`   // If set, the key may only see and run scripts with one of these tags
+	Groups []string `json:"groups,omitempty"` // Groups of the caller, for allowedGroups and policies
+}
+
+// loadAPIKeys reads the API keys file.
+func loadAPIKeys(keysPath string) ([]APIKey, error) {
+	content, err := os.ReadFile(keysPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file '%s': %v", keysPath, err)
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file '%s': %v", keysPath, err)
+	}
+	for i, key := range keys {
+		if key.Name == "" || key.Key == "" {
+			return nil, fmt.Errorf("API key %d in '%s' is missing 'name' or 'key'", i, keysPath)
+		}
+	}
+	return keys, nil
+}
+
+// activeAPIKeys holds the keys of API_KEYS_PATH, loaded at startup and again on every reload
+var activeAPIKeys atomic.Pointer[[]APIKey]
+
+// reloadAPIKeys (re)loads the API keys file, if one is configured. On error the active keys stay in use.
+func reloadAPIKeys(config *Config) error {
+	if config.APIKeysPath == "" {
+		return nil
+	}
+	keys, err := loadAPIKeys(config.APIKeysPath)
+	if err != nil {
+		return err
+	}
+	activeAPIKeys.Store(&keys)
+	return nil
+}
+
+// findAPIKey returns the key matching the presented value. Keys are compared through their
+// SHA-256 digests in constant time, so neither a key nor its length leaks through timing.
+func findAPIKey(keys []APIKey, presented string) *APIKey {
+	presentedDigest := sha256.Sum256([]byte(presented))
+	var found *APIKey
+	for i := range keys {
+		keyDigest := sha256.Sum256([]byte(keys[i].Key))
+		if subtle.ConstantTimeCompare(presentedDigest[:], keyDigest[:]) == 1 {
+			found = &keys[i]
+		}
+	}
+	return found
+}
+
+// authenticateAPIKey is middleware enforcing API key authentication when API_KEYS_PATH is set.
+// Requests to scoped endpoints must present a key with the endpoint's scope, unless the caller was
+// already authenticated by another method. The key's name, groups and tag restriction become the Caller.
+func authenticateAPIKey(c *gin.Context) {
+	config := configFromContext(c)
+	scope, scoped := endpointScopes[c.FullPath()]
+	if config.APIKeysPath == "" || !scoped {
+		c.Next()
+		return
+	}
+
+	presented := c.GetHeader(apiKeyHeader)
+	if presented == "" {
+		if _, authenticated := c.Get(callerContextKey); authenticated {
+			c.Next()
+			return
+		}
+		writeJSON(c, http.StatusUnauthorized, problem(http.StatusUnauthorized, codeUnauthenticated, fmt.Sprintf("Missing %s header", apiKeyHeader)))
+		c.Abort()
+		return
+	}
+
+	keys := activeAPIKeys.Load()
+	if keys == nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, "API key authentication is misconfigured"))
+		c.Abort()
+		return
+	}
+	key := findAPIKey(*keys, presented)
+	if key == nil {
+		logger.Warn().Msgf("Rejected request to %s from %s: invalid API key", c.FullPath(), c.ClientIP())
+		writeJSON(c, http.StatusUnauthorized, problem(http.StatusUnauthorized, codeUnauthenticated, "Invalid API key"))
+		c.Abort()
+		return
+	}
+	if !containsString(key.Scopes, scope) {
+		logger.Warn().Msgf("Rejected request to %s by API key '%s': missing scope '%s'", c.FullPath(), key.Name, scope)
+		writeJSON(c, http.StatusForbidden, problem(http.StatusForbidden, codeForbidden, fmt.Sprintf("API key '%s' lacks the '%s' scope", key.Name, scope)))
+		c.Abort()
+		return
+	}
+
+	c.Set(callerContextKey, Caller{Name: key.Name, Groups: key.Groups, ScriptTags: key.Tags})
+	c.Next()
+}
+
+// containsString reports whether values contains value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
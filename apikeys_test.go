@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthenticateAPIKeyScopes(t *testing.T) {
+	keysPath := filepath.Join(t.TempDir(), "api-keys.json")
+	keys := `[
+		{"name": "catalog-reader", "key": "options-key", "scopes": ["options"]},
+		{"name": "task-service", "key": "execute-key", "scopes": ["options", "execute"]},
+		{"name": "auditor", "key": "history-key", "scopes": ["history", "audit"]},
+		{"name": "operator", "key": "admin-key", "scopes": ["admin"]}
+	]`
+	if err := os.WriteFile(keysPath, []byte(keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{APIKeysPath: keysPath}
+	if err := reloadAPIKeys(config); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(configContextKey, config) }, authenticateAPIKey)
+	for _, route := range []string{"/v1/options", "/v1/execute", "/v1/executions/:id", "/v1/executions/:id/rerun", "/v1/audit", "/admin/loglevel", "/livez"} {
+		r.GET(route, func(c *gin.Context) { c.String(http.StatusOK, callerFromContext(c).Name) })
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		key        string
+		wantStatus int
+		wantCaller string
+	}{
+		{"options scope", "/v1/options", "options-key", http.StatusOK, "catalog-reader"},
+		{"execute without scope", "/v1/execute", "options-key", http.StatusForbidden, ""},
+		{"execute with scope", "/v1/execute", "execute-key", http.StatusOK, "task-service"},
+		{"execution by id", "/v1/executions/0b6f3c1e", "history-key", http.StatusOK, "auditor"},
+		{"rerun needs execute", "/v1/executions/0b6f3c1e/rerun", "history-key", http.StatusForbidden, ""},
+		{"rerun with execute", "/v1/executions/0b6f3c1e/rerun", "execute-key", http.StatusOK, "task-service"},
+		{"audit scope", "/v1/audit", "history-key", http.StatusOK, "auditor"},
+		{"admin without scope", "/admin/loglevel", "execute-key", http.StatusForbidden, ""},
+		{"admin with scope", "/admin/loglevel", "admin-key", http.StatusOK, "operator"},
+		{"missing key", "/v1/options", "", http.StatusUnauthorized, ""},
+		{"unknown key", "/v1/options", "guessed-key", http.StatusUnauthorized, ""},
+		{"unscoped route", "/livez", "", http.StatusOK, anonymousCaller.Name},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(apiKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GET %s with key %q: status %d, want %d (body: %s)", tt.path, tt.key, w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCaller != "" && w.Body.String() != tt.wantCaller {
+				t.Errorf("GET %s with key %q: caller %q, want %q", tt.path, tt.key, w.Body.String(), tt.wantCaller)
+			}
+		})
+	}
+}
+
+func TestEndpointScopesCoverExecutingRoutes(t *testing.T) {
+	// Routes that run scripts must never be callable with a read-only key
+	for _, route := range []string{"/v1/execute", "/v1/execute/dry-run", "/v1/executions/:id/rerun", "/v1/executions/:id/cancel"} {
+		if scope := endpointScopes[route]; scope != scopeExecute {
+			t.Errorf("endpointScopes[%q] = %q, want %q", route, scope, scopeExecute)
+		}
+	}
+}
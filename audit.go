@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit sinks (AUDIT_LOG_SINK)
+const (
+	auditSinkOff  = "off"
+	auditSinkFile = "file" // JSON lines appended to AUDIT_LOG_PATH
+	auditSinkDB   = "db"   // audit_events table of the history database
+)
+
+// Audit outcomes
+const (
+	auditOutcomeSuccessful = "SUCCESSFUL"
+	auditOutcomeFailed     = "FAILED"   // The script ran and failed, or could not be started
+	auditOutcomeRejected   = "REJECTED" // Refused before running: unknown script, authorization, policy, quota, invalid parameters
+	auditOutcomeDryRun     = "DRY_RUN"
+)
+
+// auditRedacted replaces the values of sensitive parameters in audit events
+const auditRedacted = "[REDACTED]"
+
+// auditEvent is one execute attempt as recorded in the audit log
+type auditEvent struct {
+	ID            string            `json:"id"`
+	Time          time.Time         `json:"time"`
+	ExecutionID   string            `json:"executionId,omitempty"`
+	TrackingID    string            `json:"trackingId,omitempty"`
+	TaskName      string            `json:"taskName,omitempty"`
+	Caller        string            `json:"caller"`
+	CallerGroups  []string          `json:"callerGroups,omitempty"`
+	ClientIP      string            `json:"clientIp,omitempty"`
+	Script        string            `json:"script"`
+	ScriptVersion string            `json:"scriptVersion,omitempty"`
+	Parameters    map[string]string `json:"parameters,omitempty"` // Declared parameters; sensitive values redacted
+	TargetPod     string            `json:"targetPod,omitempty"`
+	CommandSHA256 string            `json:"commandSha256,omitempty"` // Hash of the constructed command, which may embed secrets
+	Outcome       string            `json:"outcome"`
+	StatusCode    int               `json:"statusCode"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// auditSink is an append-only destination for audit events
+type auditSink interface {
+	Append(event auditEvent) error
+}
+
+// auditLog is the configured sink, nil when auditing is off
+var auditLog auditSink
+
+// fileAuditSink appends events as JSON lines to a file opened in append-only mode
+type fileAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Append writes the event as one JSON line.
+func (s *fileAuditSink) Append(event auditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// dbAuditSink inserts events into the audit_events table; rows are never updated or deleted by the executor
+type dbAuditSink struct {
+	store *ExecutionStore
+}
+
+// Append inserts the event.
+func (s *dbAuditSink) Append(event auditEvent) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.store.db.Exec(rebindQuery(s.store.dialect,
+		`INSERT INTO audit_events (id, occurred_at, execution_id, tracking_id, caller, script_name, outcome, status_code, event) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		event.ID, event.Time.UnixMilli(), event.ExecutionID, event.TrackingID, event.Caller, event.Script, event.Outcome, event.StatusCode, string(eventJSON))
+	return err
+}
+
+// newAuditSink creates the configured audit sink. The database sink requires the history store.
+func newAuditSink(config *Config) (auditSink, error) {
+	switch config.AuditLogSink {
+	case auditSinkOff, "":
+		return nil, nil
+	case auditSinkFile:
+		file, err := os.OpenFile(config.AuditLogPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log '%s': %v", config.AuditLogPath, err)
+		}
+		return &fileAuditSink{file: file}, nil
+	case auditSinkDB:
+		if executionStore == nil {
+			return nil, fmt.Errorf("AUDIT_LOG_SINK=db requires HISTORY_DB_DSN")
+		}
+		return &dbAuditSink{store: executionStore}, nil
+	default:
+		return nil, fmt.Errorf("unknown AUDIT_LOG_SINK '%s' (supported: off, file, db)", config.AuditLogSink)
+	}
+}
+
+// newAuditEvent starts the audit event of an execute attempt.
+func newAuditEvent(request TaskServiceRequest, opts executionOptions) *auditEvent {
+	now := time.Now()
+	return &auditEvent{
+		ID:           newRecordID(),
+		Time:         now.UTC(),
+		TrackingID:   request.TrackingID,
+		TaskName:     request.TaskName,
+		Caller:       opts.Caller.Name,
+		CallerGroups: opts.Caller.Groups,
+		ClientIP:     opts.ClientIP,
+	}
+}
+
+// setParameter records a resolved parameter, redacting sensitive values.
+func (e *auditEvent) setParameter(param InputParameterDef, value string) {
+	if e.Parameters == nil {
+		e.Parameters = make(map[string]string)
+	}
+	if param.Sensitive {
+		value = auditRedacted
+	}
+	e.Parameters[param.Name] = value
+}
+
+// setCommands records the hash of the constructed command(s).
+func (e *auditEvent) setCommands(commands ...string) {
+	digest := sha256.New()
+	for _, command := range commands {
+		digest.Write([]byte(command))
+		digest.Write([]byte{0})
+	}
+	e.CommandSHA256 = hex.EncodeToString(digest.Sum(nil))
+}
+
+// finish completes the event from the execution outcome and appends it to the audit log.
+// Audit failures are logged but don't fail the execution, which has already happened.
+func (e *auditEvent) finish(outcome executionOutcome, dryRun bool) {
+	if auditLog == nil {
+		return
+	}
+	e.StatusCode = outcome.StatusCode
+	e.Outcome = executionOutcomeLabel(outcome, dryRun)
+	if errMsg, ok := outcome.Body["error"]; ok {
+		e.Error = fmt.Sprintf("%v", errMsg)
+	}
+	if err := auditLog.Append(*e); err != nil {
+		logger.Error().Msgf("Failed to write audit event for script '%s' (caller: %s): %v", e.Script, e.Caller, err)
+	}
+}
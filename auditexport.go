@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits of GET /v1/audit
+const (
+	auditDefaultRange = 24 * time.Hour
+	auditDefaultLimit = 1000
+	auditMaxLimit     = 10000
+)
+
+// auditQuery filters audit events for export
+type auditQuery struct {
+	From, To time.Time
+	Caller   string
+	Script   string
+	Scripts  []string // Unless nil, only events of these scripts: those the caller's tag restriction admits
+	Limit    int
+}
+
+// matches reports whether an event falls within the query.
+func (q auditQuery) matches(event auditEvent) bool {
+	return !event.Time.Before(q.From) && event.Time.Before(q.To) &&
+		(q.Caller == "" || event.Caller == q.Caller) && (q.Script == "" || event.Script == q.Script) &&
+		(q.Scripts == nil || containsString(q.Scripts, event.Script))
+}
+
+// auditReader is implemented by audit sinks that can be queried
+type auditReader interface {
+	Query(query auditQuery) ([]auditEvent, error)
+}
+
+// Query scans the audit log file in order, returning the first matching events.
+func (s *fileAuditSink) Query(query auditQuery) ([]auditEvent, error) {
+	file, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	events := []auditEvent{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() && len(events) < query.Limit {
+		var event auditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue // A line torn by a crash mid-write must not hide the rest of the log
+		}
+		if query.matches(event) {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	return events, nil
+}
+
+// Query selects matching events from the audit_events table in time order.
+func (s *dbAuditSink) Query(query auditQuery) ([]auditEvent, error) {
+	sqlQuery := `SELECT event FROM audit_events WHERE occurred_at >= ? AND occurred_at < ?`
+	args := []interface{}{query.From.UnixMilli(), query.To.UnixMilli()}
+	if query.Caller != "" {
+		sqlQuery += ` AND caller = ?`
+		args = append(args, query.Caller)
+	}
+	if query.Script != "" {
+		sqlQuery += ` AND script_name = ?`
+		args = append(args, query.Script)
+	}
+	if query.Scripts != nil {
+		sqlQuery += ` AND script_name IN (NULL` + strings.Repeat(", ?", len(query.Scripts)) + `)`
+		for _, script := range query.Scripts {
+			args = append(args, script)
+		}
+	}
+	sqlQuery += fmt.Sprintf(` ORDER BY occurred_at, id LIMIT %d`, query.Limit)
+
+	rows, err := s.store.db.Query(rebindQuery(s.store.dialect, sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit events: %v", err)
+	}
+	defer rows.Close()
+	events := []auditEvent{}
+	for rows.Next() {
+		var eventJSON string
+		if err := rows.Scan(&eventJSON); err != nil {
+			return nil, fmt.Errorf("failed to read audit event: %v", err)
+		}
+		var event auditEvent
+		if err := json.Unmarshal([]byte(eventJSON), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode audit event: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// parseAuditQuery reads the time range (RFC 3339 `from`/`to`, default the last 24 hours), the
+// optional `caller` and `script` filters and the `limit` of GET /v1/audit.
+func parseAuditQuery(c *gin.Context) (auditQuery, error) {
+	query := auditQuery{To: time.Now(), Caller: c.Query("caller"), Script: c.Query("script"), Limit: auditDefaultLimit}
+	if to := c.Query("to"); to != "" {
+		parsed, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return query, fmt.Errorf("invalid 'to' time '%s': expected RFC 3339", to)
+		}
+		query.To = parsed
+	}
+	query.From = query.To.Add(-auditDefaultRange)
+	if from := c.Query("from"); from != "" {
+		parsed, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return query, fmt.Errorf("invalid 'from' time '%s': expected RFC 3339", from)
+		}
+		query.From = parsed
+	}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			return query, fmt.Errorf("invalid 'limit' '%s'", limit)
+		}
+		query.Limit = parsed
+	}
+	if query.Limit > auditMaxLimit {
+		query.Limit = auditMaxLimit
+	}
+	return query, nil
+}
+
+// mayReadAudit reports whether the caller may export audit records. With AUDIT_READERS set, only
+// callers with one of those names or groups may; otherwise access follows the API key scope.
+func mayReadAudit(config *Config, caller Caller) bool {
+	if len(config.AuditReaders) == 0 {
+		return true
+	}
+	if containsString(config.AuditReaders, caller.Name) {
+		return true
+	}
+	for _, group := range caller.Groups {
+		if containsString(config.AuditReaders, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// exportAudit handles GET /v1/audit?from=&to=&caller=&script=&limit=&format=json|csv, so the security
+// team can pull audit records without access to the pod filesystem or the database.
+func exportAudit(c *gin.Context) {
+	config := configFromContext(c)
+	caller := callerFromContext(c)
+	if !mayReadAudit(config, caller) {
+		writeJSON(c, http.StatusForbidden, problem(http.StatusForbidden, codeForbidden, fmt.Sprintf("Caller '%s' may not read the audit log", caller.Name)))
+		return
+	}
+	reader, ok := auditLog.(auditReader)
+	if !ok {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotEnabled, "Audit log is not enabled (AUDIT_LOG_SINK)"))
+		return
+	}
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, err.Error()))
+		return
+	}
+	if query.Scripts, err = visibleScripts(config, caller); err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && format != "csv" {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Unsupported audit format '%s' (supported: json, csv)", format)))
+		return
+	}
+
+	events, err := reader.Query(query)
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	if format == "json" {
+		writeJSON(c, http.StatusOK, events)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=audit-%s.csv", query.From.UTC().Format("20060102T150405Z")))
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"id", "time", "executionId", "trackingId", "taskName", "caller", "callerGroups", "clientIp",
+		"script", "scriptVersion", "parameters", "targetPod", "commandSha256", "outcome", "statusCode", "error"})
+	for _, event := range events {
+		parameters := ""
+		if len(event.Parameters) > 0 {
+			parametersJSON, _ := json.Marshal(event.Parameters)
+			parameters = string(parametersJSON)
+		}
+		writer.Write([]string{event.ID, event.Time.Format(time.RFC3339Nano), event.ExecutionID, event.TrackingID, event.TaskName,
+			event.Caller, strings.Join(event.CallerGroups, ";"), event.ClientIP, event.Script, event.ScriptVersion, parameters,
+			event.TargetPod, event.CommandSHA256, event.Outcome, strconv.Itoa(event.StatusCode), event.Error})
+	}
+	writer.Flush()
+}
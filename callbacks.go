@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers of signed callback requests
+const (
+	callbackSignatureHeader = "X-Executor-Signature" // "sha256=" + hex HMAC-SHA256 of "<timestamp>.<body>"
+	callbackTimestampHeader = "X-Executor-Timestamp" // Unix seconds, part of the signed content against replays
+)
+
+// callbackMaxAttempts bounds delivery attempts of one callback; attempts back off exponentially from 1s
+const callbackMaxAttempts = 3
+
+// callbackClient posts execution callbacks
+var callbackClient = &http.Client{Timeout: 15 * time.Second}
+
+// validateCallbackURL checks the callbackUrl of a script definition. Unlike request callbacks it
+// needs no allow-listing: definitions are written by script authors, not API callers.
+func validateCallbackURL(def *ScriptDefinition) error {
+	if def.CallbackURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(def.CallbackURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("'%s' is not an absolute http(s) URL", def.CallbackURL)
+	}
+	return nil
+}
+
+// checkCallbackURL validates a callbackUrl supplied in an execute request. Request callbacks make
+// the executor call out to caller-chosen URLs, so their host must be listed in CALLBACK_ALLOWED_HOSTS
+// (exact host or "*.domain" suffix); without that list they are refused.
+func checkCallbackURL(config *Config, callbackURL string) error {
+	parsed, err := url.Parse(callbackURL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return fmt.Errorf("callbackUrl '%s' is not an absolute http(s) URL", callbackURL)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, allowed := range config.CallbackAllowedHosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return nil
+		}
+	}
+	return fmt.Errorf("callbackUrl host '%s' is not allowed (see CALLBACK_ALLOWED_HOSTS)", host)
+}
+
+// signCallback returns the signature header value of a callback body sent at timestamp.
+func signCallback(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendCompletionCallbacks POSTs the outcome of an execution, as an execution.finished CloudEvent
+// including the output, to the script's callbackUrl and the one given in the request, if any.
+// Delivery happens in the background with retries; failures are logged.
+func sendCompletionCallbacks(config *Config, def *ScriptDefinition, requestCallbackURL string, event completionEvent) {
+	var urls []string
+	for _, callbackURL := range []string{def.CallbackURL, requestCallbackURL} {
+		if callbackURL != "" && !containsString(urls, callbackURL) {
+			urls = append(urls, callbackURL)
+		}
+	}
+	if len(urls) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(newExecutionCloudEvent(config, executionFinishedEventType, event, true))
+	if err != nil {
+		logger.Error().Str("trackingId", event.TrackingID).Msgf("Failed to marshal callback payload for script '%s': %v", event.Script, err)
+		return
+	}
+
+	for _, callbackURL := range urls {
+		go func(callbackURL string) {
+			backoff := time.Second
+			for attempt := 1; ; attempt++ {
+				err := postCallback(config, callbackURL, body)
+				if err == nil {
+					logger.Info().Str("trackingId", event.TrackingID).Msgf("Delivered callback for script '%s' to %s.", event.Script, callbackURL)
+					return
+				}
+				if attempt == callbackMaxAttempts {
+					logger.Error().Str("trackingId", event.TrackingID).Msgf("Giving up on callback for script '%s' to %s after %d attempts: %v", event.Script, callbackURL, attempt, err)
+					return
+				}
+				logger.Warn().Str("trackingId", event.TrackingID).Msgf("Callback for script '%s' to %s failed (attempt %d/%d): %v. Retrying in %s...", event.Script, callbackURL, attempt, callbackMaxAttempts, err, backoff)
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+		}(callbackURL)
+	}
+}
+
+// postCallback sends one callback request, signed with CALLBACK_SIGNING_SECRET when configured.
+func postCallback(config *Config, callbackURL string, body []byte) error {
+	req, err := http.NewRequest("POST", callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", cloudEventsContentType)
+	if config.CallbackSigningSecret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(callbackTimestampHeader, timestamp)
+		req.Header.Set(callbackSignatureHeader, signCallback(config.CallbackSigningSecret, timestamp, body))
+	}
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
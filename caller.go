@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// callerContextKey is the gin context key under which authentication middleware stores the Caller
+const callerContextKey = "executor.caller"
+
+// Caller is the identity on whose behalf a script runs, as established by authentication.
+type Caller struct {
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+	// ScriptTags restricts the caller to scripts carrying at least one of these tags (API keys with tags)
+	ScriptTags []string `json:"scriptTags,omitempty"`
+	// ServiceAccount is set for callers authenticated by a ServiceAccount token, whose identity pod
+	// execs can impersonate (IMPERSONATE_SERVICE_ACCOUNTS)
+	ServiceAccount bool `json:"-"`
+}
+
+// Identities of executions not started by an HTTP caller
+var (
+	anonymousCaller = Caller{Name: "anonymous"}
+	schedulerCaller = Caller{Name: "system:scheduler", Groups: []string{"system:executor"}}
+	// triggerCaller runs /v1/trigger requests, which authenticate with TRIGGER_TOKEN rather than as a caller
+	triggerCaller = Caller{Name: "system:trigger", Groups: []string{"system:executor"}}
+)
+
+// callerFromContext returns the caller authenticated for the request, or anonymousCaller.
+func callerFromContext(c *gin.Context) Caller {
+	if value, exists := c.Get(callerContextKey); exists {
+		if caller, ok := value.(Caller); ok {
+			return caller
+		}
+	}
+	return anonymousCaller
+}
+
+// authenticateTrustedHeaders is middleware that takes the caller identity from headers set by an
+// authenticating proxy or service mesh in front of the executor (TRUSTED_CALLER_HEADER and
+// TRUSTED_GROUPS_HEADER, groups comma-separated). Only enable it when clients can't reach the
+// executor without passing through that proxy, as the headers are not verified otherwise.
+func authenticateTrustedHeaders(c *gin.Context) {
+	config := configFromContext(c)
+	if config.TrustedCallerHeader == "" {
+		c.Next()
+		return
+	}
+	if name := strings.TrimSpace(c.GetHeader(config.TrustedCallerHeader)); name != "" {
+		caller := Caller{Name: name}
+		if config.TrustedGroupsHeader != "" {
+			for _, group := range strings.Split(c.GetHeader(config.TrustedGroupsHeader), ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					caller.Groups = append(caller.Groups, group)
+				}
+			}
+		}
+		c.Set(callerContextKey, caller)
+	}
+	c.Next()
+}
+
+// callerMaySeeScript reports whether the caller's tag restriction, if any, admits the script.
+func callerMaySeeScript(caller Caller, def *ScriptDefinition) bool {
+	if len(caller.ScriptTags) == 0 {
+		return true
+	}
+	for _, tag := range def.Tags {
+		if containsString(caller.ScriptTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleScripts returns the names of the scripts the caller's tag restriction admits, or nil for a
+// caller without one. Records of scripts that are no longer defined stay hidden from restricted callers.
+func visibleScripts(config *Config, caller Caller) ([]string, error) {
+	if len(caller.ScriptTags) == 0 {
+		return nil, nil
+	}
+	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	if err != nil {
+		return nil, err
+	}
+	visible := []string{}
+	for i := range definitions {
+		if callerMaySeeScript(caller, &definitions[i]) && !containsString(visible, definitions[i].Name) {
+			visible = append(visible, definitions[i].Name)
+		}
+	}
+	return visible, nil
+}
+
+// authorizeCaller checks the caller against the script's allowedCallers/allowedGroups.
+// Scripts without either list may be run by any caller allowed by its tag restriction. Scheduled
+// runs don't get here (executionOptions.Scheduled): a caller's name alone never bypasses the lists.
+func authorizeCaller(def *ScriptDefinition, caller Caller) error {
+	if !callerMaySeeScript(caller, def) {
+		return fmt.Errorf("caller '%s' may only run scripts tagged %v", caller.Name, caller.ScriptTags)
+	}
+	if len(def.AllowedCallers) == 0 && len(def.AllowedGroups) == 0 {
+		return nil
+	}
+	for _, allowed := range def.AllowedCallers {
+		if allowed == caller.Name {
+			return nil
+		}
+	}
+	for _, allowed := range def.AllowedGroups {
+		for _, group := range caller.Groups {
+			if allowed == group {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("caller '%s' is not allowed to run script '%s'", caller.Name, def.Name)
+}
+
+// impersonatedCaller returns the identity pod execs impersonate for a caller: the caller itself when
+// it authenticated with a ServiceAccount token and IMPERSONATE_SERVICE_ACCOUNTS is on, else nil.
+func impersonatedCaller(config *Config, caller Caller) *Caller {
+	if !config.ImpersonateServiceAccounts || !caller.ServiceAccount {
+		return nil
+	}
+	return &caller
+}
+
+// impersonationArgs are the kubectl flags impersonating a caller with its groups; none for nil.
+func impersonationArgs(as *Caller) []string {
+	if as == nil {
+		return nil
+	}
+	args := []string{"--as=" + as.Name}
+	for _, group := range as.Groups {
+		args = append(args, "--as-group="+group)
+	}
+	return args
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestAuthorizeCaller(t *testing.T) {
+	restricted := &ScriptDefinition{Name: "restore", AllowedCallers: []string{"task-service"}, AllowedGroups: []string{"dba"}, Tags: []string{"db"}}
+	open := &ScriptDefinition{Name: "where am i", Tags: []string{"diagnostics"}}
+	tests := []struct {
+		name    string
+		def     *ScriptDefinition
+		caller  Caller
+		allowed bool
+	}{
+		{"unrestricted script", open, Caller{Name: "anyone"}, true},
+		{"listed caller", restricted, Caller{Name: "task-service"}, true},
+		{"listed group", restricted, Caller{Name: "alice", Groups: []string{"dev", "dba"}}, true},
+		{"unlisted caller", restricted, Caller{Name: "alice", Groups: []string{"dev"}}, false},
+		{"anonymous caller", restricted, anonymousCaller, false},
+		{"scheduler name spoofed", restricted, Caller{Name: schedulerCaller.Name}, false},
+		{"scheduler identity", restricted, schedulerCaller, false},
+		{"trigger identity", restricted, triggerCaller, false},
+		{"trigger identity listed", &ScriptDefinition{Name: "nightly", AllowedCallers: []string{triggerCaller.Name}}, triggerCaller, true},
+		{"matching tag", open, Caller{Name: "ci", ScriptTags: []string{"diagnostics"}}, true},
+		{"other tag", restricted, Caller{Name: "task-service", ScriptTags: []string{"diagnostics"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorizeCaller(tt.def, tt.caller)
+			if (err == nil) != tt.allowed {
+				t.Errorf("authorizeCaller(%s, %+v) = %v, want allowed=%v", tt.def.Name, tt.caller, err, tt.allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// executionStopped is the error of an execution whose context ended before the script did: the
+// client disconnected, the request timed out, the execution was cancelled or the executor shut down.
+func executionStopped(ctx context.Context) error {
+	return fmt.Errorf("execution stopped: %v", context.Cause(ctx))
+}
+
+// How a stopped execution's script was terminated, as recorded on the execution
+const (
+	terminationSIGTERM    = "SIGTERM"     // The script exited within the grace period after SIGTERM
+	terminationSIGKILL    = "SIGKILL"     // The script still ran after the grace period and was killed
+	terminationPodDeleted = "POD_DELETED" // The script's own pod was deleted with the grace period
+)
+
+// terminateInPod stops the processes of an execution still running in its pod, found by the
+// EXECUTION_ID they carry and their children inherit: they get SIGTERM, and SIGKILL if any of them
+// still runs after TERMINATION_GRACE_SECONDS. It returns terminationSIGTERM or terminationSIGKILL,
+// or "" if nothing ran anymore or the pod couldn't be reached.
+func terminateInPod(ctx context.Context, run *executorRun) string {
+	xlog := executionLog(ctx)
+	marked := markedProcessesCommand(run.ExecutionID)
+	// The script runs in a single exec, so the grace period isn't stretched by one exec per check
+	command := fmt.Sprintf(`pids=$(%[1]s); [ -n "$pids" ] || exit 0; kill -TERM $pids 2>/dev/null; `+
+		`i=0; while [ $i -lt %[2]d ] && [ -n "$(%[1]s)" ]; do sleep 1; i=$((i+1)); done; `+
+		`pids=$(%[1]s); if [ -z "$pids" ]; then echo %[3]s; else kill -KILL $pids 2>/dev/null; echo %[4]s; fi`,
+		marked, int(run.Config.TerminationGracePeriod.Seconds()), terminationSIGTERM, terminationSIGKILL)
+	output, err := execInPodAs(context.WithoutCancel(ctx), run.Config.Namespace, run.TargetPod, command, run.Impersonate)
+	if err != nil {
+		xlog.Warn().Msgf("Failed to stop script '%s' in pod '%s': %v. Output: %s", run.Definition.Name, run.TargetPod, err, strings.TrimSpace(output))
+		return ""
+	}
+	termination := ""
+	if lines := strings.Fields(output); len(lines) > 0 {
+		if last := lines[len(lines)-1]; last == terminationSIGTERM || last == terminationSIGKILL {
+			termination = last
+		}
+	}
+	if termination == "" {
+		xlog.Info().Msgf("Script '%s' no longer ran in pod '%s' when stopped: %v", run.Definition.Name, run.TargetPod, context.Cause(ctx))
+		return ""
+	}
+	xlog.Info().Msgf("Stopped script '%s' in pod '%s' with %s: %v", run.Definition.Name, run.TargetPod, termination, context.Cause(ctx))
+	return termination
+}
+
+// cancelExecution handles POST /v1/executions/:id/cancel, stopping an execution running on this
+// replica, given its execution ID or process ID. Its request then fails like a failed script.
+func cancelExecution(c *gin.Context) {
+	caller := callerFromContext(c)
+	executionID, control, ok := inFlightExecutions.lookup(c.Param("id"))
+	if !ok {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotFound, fmt.Sprintf("Execution '%s' is not running on this replica", c.Param("id"))))
+		return
+	}
+	if err := authorizeCaller(control.definition, caller); err != nil {
+		writeJSON(c, http.StatusForbidden, problem(http.StatusForbidden, codeForbidden, err.Error()))
+		return
+	}
+	control.cancel(fmt.Errorf("cancelled by %s", caller.Name))
+	logger.Info().Str("executionId", executionID).Msgf("Execution of script '%s' cancelled by %s.", control.definition.Name, caller.Name)
+	writeJSON(c, http.StatusAccepted, gin.H{"executionId": executionID, "cancelled": true})
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- Backstage Template entities (scaffolder.backstage.io/v1beta3) ---
+
+// BackstageTemplate is a Backstage software template that runs one script through /v1/execute
+type BackstageTemplate struct {
+	APIVersion string                    `json:"apiVersion"`
+	Kind       string                    `json:"kind"`
+	Metadata   BackstageTemplateMetadata `json:"metadata"`
+	Spec       BackstageTemplateSpec     `json:"spec"`
+}
+
+// BackstageTemplateMetadata is the entity metadata of a BackstageTemplate
+type BackstageTemplateMetadata struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// BackstageTemplateSpec holds the form parameters and the steps of a BackstageTemplate
+type BackstageTemplateSpec struct {
+	Owner      string                   `json:"owner"`
+	Type       string                   `json:"type"`
+	Parameters []BackstageParameterPage `json:"parameters"`
+	Steps      []BackstageTemplateStep  `json:"steps"`
+}
+
+// BackstageParameterPage is one JSON-schema form page of a template
+type BackstageParameterPage struct {
+	Title      string                              `json:"title"`
+	Required   []string                            `json:"required,omitempty"`
+	Properties map[string]BackstageParameterSchema `json:"properties"`
+}
+
+// BackstageParameterSchema is the JSON schema of one form field
+type BackstageParameterSchema struct {
+	Title       string `json:"title"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// BackstageTemplateStep is a scaffolder action invocation
+type BackstageTemplateStep struct {
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	Action string                 `json:"action"`
+	Input  map[string]interface{} `json:"input"`
+}
+
+// --- ServiceNow catalog items ---
+
+// ServiceNowCatalogItem is a ServiceNow service catalog item definition for one script
+type ServiceNowCatalogItem struct {
+	Name             string                      `json:"name"`
+	ShortDescription string                      `json:"short_description"`
+	Description      string                      `json:"description,omitempty"`
+	Category         string                      `json:"category"`
+	ScriptID         string                      `json:"u_script_id"`
+	Variables        []ServiceNowCatalogVariable `json:"variables"`
+}
+
+// ServiceNowCatalogVariable is a question on a ServiceNow catalog item form
+type ServiceNowCatalogVariable struct {
+	Name         string `json:"name"`
+	QuestionText string `json:"question_text"`
+	Type         string `json:"type"`
+	Mandatory    bool   `json:"mandatory"`
+	Order        int    `json:"order"`
+	HelpText     string `json:"help_text,omitempty"`
+}
+
+// exportCatalog handles GET /v1/catalog/export?format=backstage|servicenow.
+// It renders the script catalog in the format of an existing self-service portal, so scripts
+// surface there without maintaining a second copy of their definitions.
+func exportCatalog(c *gin.Context) {
+	config := configFromContext(c)
+	format := strings.ToLower(c.DefaultQuery("format", "backstage"))
+	if format != "backstage" && format != "servicenow" {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Unsupported catalog format '%s' (supported: backstage, servicenow)", format)))
+		return
+	}
+
+	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	if err != nil {
+		logger.Error().Msgf("Error loading script definitions for catalog export: %v", err)
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeDefinitionsInvalid, fmt.Sprintf("Failed to load script definitions: %v", err)))
+		return
+	}
+
+	definitions = latestScriptVersions(definitions)
+	caller := callerFromContext(c)
+
+	if format == "servicenow" {
+		items := make([]ServiceNowCatalogItem, 0, len(definitions))
+		for i := range definitions {
+			if definitions[i].Disabled || !callerMaySeeScript(caller, &definitions[i]) {
+				continue
+			}
+			items = append(items, toServiceNowCatalogItem(&definitions[i], config))
+		}
+		c.JSON(http.StatusOK, items)
+		return
+	}
+
+	templates := make([]BackstageTemplate, 0, len(definitions))
+	for i := range definitions {
+		if definitions[i].Disabled || !callerMaySeeScript(caller, &definitions[i]) {
+			continue
+		}
+		templates = append(templates, toBackstageTemplate(&definitions[i], config))
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// catalogDescription returns the best available human-readable description of a script.
+func catalogDescription(def *ScriptDefinition) string {
+	if def.Description != "" {
+		return def.Description
+	}
+	if def.Label != "" {
+		return def.Label
+	}
+	return def.Name
+}
+
+// toBackstageTemplate renders a script as a Backstage Template whose single step calls /v1/execute.
+func toBackstageTemplate(def *ScriptDefinition, config *Config) BackstageTemplate {
+	page := BackstageParameterPage{Title: "Parameters", Properties: make(map[string]BackstageParameterSchema)}
+	taskData := map[string]interface{}{"name": def.Name}
+	for _, param := range def.Parameters {
+		schemaType := "string"
+		switch strings.ToLower(param.Type) {
+		case "number", "integer", "boolean":
+			schemaType = strings.ToLower(param.Type)
+		}
+		page.Properties[param.Name] = BackstageParameterSchema{Title: param.Name, Type: schemaType, Description: param.Description}
+		if !param.Optional {
+			page.Required = append(page.Required, param.Name)
+		}
+		taskData[param.Name] = fmt.Sprintf("${{ parameters['%s'] }}", param.Name)
+	}
+
+	return BackstageTemplate{
+		APIVersion: "scaffolder.backstage.io/v1beta3",
+		Kind:       "Template",
+		Metadata: BackstageTemplateMetadata{
+			Name:        def.ID,
+			Title:       def.Name,
+			Description: catalogDescription(def),
+			Tags:        def.Tags,
+		},
+		Spec: BackstageTemplateSpec{
+			Owner:      config.CatalogOwner,
+			Type:       "script",
+			Parameters: []BackstageParameterPage{page},
+			Steps: []BackstageTemplateStep{{
+				ID:     "execute",
+				Name:   "Execute script",
+				Action: "http:backstage:request",
+				Input: map[string]interface{}{
+					"method": "POST",
+					"path":   strings.TrimSuffix(config.CatalogExecutorURL, "/") + "/v1/execute",
+					"headers": map[string]string{
+						"content-type": "application/json",
+					},
+					"body": map[string]interface{}{
+						"taskName": def.Name,
+						"taskData": taskData,
+					},
+				},
+			}},
+		},
+	}
+}
+
+// toServiceNowCatalogItem renders a script as a ServiceNow catalog item with one variable per parameter.
+func toServiceNowCatalogItem(def *ScriptDefinition, config *Config) ServiceNowCatalogItem {
+	item := ServiceNowCatalogItem{
+		Name:             def.Name,
+		ShortDescription: catalogDescription(def),
+		Description:      def.Description,
+		Category:         config.CatalogCategory,
+		ScriptID:         def.ID,
+		Variables:        []ServiceNowCatalogVariable{},
+	}
+	for i, param := range def.Parameters {
+		variableType := "single_line_text"
+		if strings.EqualFold(param.Type, "boolean") {
+			variableType = "checkbox"
+		}
+		item.Variables = append(item.Variables, ServiceNowCatalogVariable{
+			Name:         sanitizeEnvVarName(param.Name),
+			QuestionText: param.Name,
+			Type:         variableType,
+			Mandatory:    !param.Optional,
+			Order:        (i + 1) * 100,
+			HelpText:     param.Description,
+		})
+	}
+	return item
+}
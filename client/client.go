@@ -0,0 +1,340 @@
+// Package client is the Go client of the k8s-script-executor API. It wraps the endpoints used by
+// the Task Service and other callers with typed requests and responses, authentication and
+// retries of requests that can safely be repeated:
+//
+//	c := client.New("http://script-executor:8080", client.WithAPIKey(os.Getenv("EXECUTOR_API_KEY")))
+//	scripts, err := c.Options(ctx, client.OptionsFilter{Tags: []string{"maintenance"}})
+//	result, err := c.Execute(ctx, client.ExecuteRequest{TrackingID: "42", TaskData: map[string]interface{}{"name": "check-logs"}})
+//
+// Failed requests return an *Error carrying the problem code of the response, e.g. SCRIPT_NOT_FOUND.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error codes of the executor's problem responses
+const (
+	CodeInvalidRequest     = "INVALID_REQUEST"
+	CodeScriptNotFound     = "SCRIPT_NOT_FOUND"
+	CodeScriptDisabled     = "SCRIPT_DISABLED"
+	CodeParamMissing       = "PARAM_MISSING"
+	CodeParamInvalid       = "PARAM_INVALID"
+	CodePodNotFound        = "POD_NOT_FOUND"
+	CodeExecFailed         = "EXEC_FAILED"
+	CodeTrackingFailed     = "TRACKING_FAILED"
+	CodeDefinitionsInvalid = "DEFINITIONS_INVALID"
+	CodeUnauthenticated    = "UNAUTHENTICATED"
+	CodeForbidden          = "FORBIDDEN"
+	CodePolicyDenied       = "POLICY_DENIED"
+	CodeQuotaExceeded      = "QUOTA_EXCEEDED"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	CodeRequestTimeout     = "REQUEST_TIMEOUT"
+	CodeMaintenance        = "MAINTENANCE"
+	CodeShuttingDown       = "SHUTTING_DOWN"
+	CodeScriptLocked       = "SCRIPT_LOCKED"
+	CodeNotFound           = "NOT_FOUND"
+	CodeNotEnabled         = "NOT_ENABLED"
+	CodeInternal           = "INTERNAL_ERROR"
+)
+
+// Error is a failed API call: the problem response of the executor, or the bare status when the
+// response wasn't one.
+type Error struct {
+	StatusCode int    `json:"status"`
+	Code       string `json:"code"`
+	Detail     string `json:"detail"`
+	// Output of the script, for EXEC_FAILED
+	Output string `json:"output,omitempty"`
+	// Category of a failed execution, e.g. pod-unavailable or script-error: infrastructure failures
+	// may be worth retrying later, script errors rarely are
+	Category string `json:"category,omitempty"`
+	// RetryAfter is the delay the executor asked for (429 and 503 responses), if any
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("executor returned status %d: %s", e.StatusCode, e.Detail)
+	}
+	return fmt.Sprintf("executor returned %s (status %d): %s", e.Code, e.StatusCode, e.Detail)
+}
+
+// HasCode reports whether err is an *Error with the given problem code.
+func HasCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// Client calls one executor. It is safe for concurrent use.
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	apiKey      string
+	bearerToken string
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey authenticates with an API key (X-API-Key header).
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithBearerToken authenticates with a bearer token, e.g. a ServiceAccount token.
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.bearerToken = token }
+}
+
+// WithHTTPClient uses the given HTTP client, e.g. one with a client certificate for mTLS. It
+// should have no overall timeout shorter than the longest script, as Execute waits for it.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithRetries sets the attempts of retryable requests (at least 1) and the exponential backoff
+// between them, starting at base and capped at max. The default is 3 attempts from 500ms to 5s.
+func WithRetries(attempts int, base, max time.Duration) Option {
+	return func(c *Client) {
+		if attempts < 1 {
+			attempts = 1
+		}
+		c.maxAttempts, c.retryBase, c.retryMax = attempts, base, max
+	}
+}
+
+// New returns a client of the executor at baseURL, e.g. "http://script-executor:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		httpClient:  &http.Client{},
+		maxAttempts: 3,
+		retryBase:   500 * time.Millisecond,
+		retryMax:    5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// retryPolicy decides whether a failed attempt may be repeated
+type retryPolicy func(resp *http.Response, apiErr *Error, err error) bool
+
+// retryIdempotent repeats reads on network errors, 429 and 502-504 responses.
+func retryIdempotent(resp *http.Response, apiErr *Error, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryRefused repeats executions only when the executor refused them before running anything:
+// rate limited, or the replica was draining (the next attempt likely reaches another one).
+func retryRefused(resp *http.Response, apiErr *Error, err error) bool {
+	return err == nil && (apiErr.Code == CodeRateLimited || apiErr.Code == CodeShuttingDown)
+}
+
+// do sends a request, retrying per policy, and returns the successful response with its body
+// unread. Error responses are returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, retry retryPolicy) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		// Pin the field naming the types below decode, whatever JSON_NAMING_DEFAULT is
+		req.Header.Set("Accept", "application/json; profile=default")
+		if c.apiKey != "" {
+			req.Header.Set("X-API-Key", c.apiKey)
+		}
+		if c.bearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		var apiErr *Error
+		if err == nil {
+			if resp.StatusCode < 300 {
+				return resp, nil
+			}
+			apiErr = readError(resp)
+		}
+		if attempt >= c.maxAttempts || ctx.Err() != nil || !retry(resp, apiErr, err) {
+			if err != nil {
+				return nil, err
+			}
+			return nil, apiErr
+		}
+
+		delay := c.backoff(attempt)
+		if apiErr != nil && apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt, with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retryBase << (attempt - 1)
+	if delay > c.retryMax || delay <= 0 {
+		delay = c.retryMax
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// readError turns an error response into an *Error and closes its body.
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Detail == "" {
+		apiErr.Detail = strings.TrimSpace(string(data))
+	}
+	apiErr.StatusCode = resp.StatusCode
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// decode reads a successful JSON response into v and closes its body.
+func decode(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Parameter is an input parameter of a script
+type Parameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	Sensitive   bool   `json:"sensitive,omitempty"`
+}
+
+// Script is a script the caller may run, as listed by Options
+type Script struct {
+	Name       string      `json:"name"`
+	Parameters []Parameter `json:"parameters"`
+	Tags       []string    `json:"tags,omitempty"`
+	Version    string      `json:"version,omitempty"`
+	Deprecated bool        `json:"deprecated,omitempty"`
+	Warning    string      `json:"warning,omitempty"`
+}
+
+// OptionsFilter narrows the scripts listed by Options
+type OptionsFilter struct {
+	Tags   []string // Scripts must have every tag
+	Search string   // Matches name, ID, description or tags
+}
+
+// Options lists the scripts the caller may run (GET /v1/options).
+func (c *Client) Options(ctx context.Context, filter OptionsFilter) ([]Script, error) {
+	query := url.Values{}
+	for _, tag := range filter.Tags {
+		query.Add("tag", tag)
+	}
+	if filter.Search != "" {
+		query.Set("search", filter.Search)
+	}
+	path := "/v1/options"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := c.do(ctx, http.MethodGet, path, nil, retryIdempotent)
+	if err != nil {
+		return nil, err
+	}
+	var scripts []Script
+	return scripts, decode(resp, &scripts)
+}
+
+// ExecuteRequest is the body of an execution, in the Task Service format
+type ExecuteRequest struct {
+	TaskName    string                 `json:"taskName,omitempty"`
+	LastRunTime int64                  `json:"lastRunTime,omitempty"`
+	TrackingID  string                 `json:"trackingId"`
+	TaskData    map[string]interface{} `json:"taskData"` // "name" selects the script, the other entries are its parameters
+	Version     string                 `json:"version,omitempty"`
+	CallbackURL string                 `json:"callbackUrl,omitempty"`
+	Stage       string                 `json:"stage,omitempty"`
+}
+
+// ExecuteResult is a successful execution
+type ExecuteResult struct {
+	// ProcessID is the process tracking ID (X-ProcessId), 0 when the script isn't tracked. It also
+	// identifies the execution for Execution, WaitForCompletion and StreamLogs.
+	ProcessID int64
+	// ExecutionID is the execution's UUID (X-Execution-Id), which also identifies it.
+	ExecutionID string
+	// Status is WARNING or SKIPPED when the script exited with a code its definition maps to one,
+	// with that ExitCode; empty when it succeeded.
+	Status   string
+	ExitCode int
+}
+
+// Execute runs a script and waits for it to finish (POST /v1/execute). A failed script returns an
+// *Error with code EXEC_FAILED and the script output. Only requests the executor refused without
+// running anything (RATE_LIMITED, SHUTTING_DOWN) are retried, so a script never runs twice.
+func (c *Client) Execute(ctx context.Context, request ExecuteRequest) (*ExecuteResult, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/execute", request, retryRefused)
+	if err != nil {
+		return nil, err
+	}
+	return executeResult(resp), nil
+}
+
+// executeResult reads the execution IDs and mapped status of a successful execute response.
+func executeResult(resp *http.Response) *ExecuteResult {
+	defer resp.Body.Close()
+	var body struct {
+		Status   string `json:"status"`
+		ExitCode int    `json:"exitCode"`
+	}
+	// A plain success has no body
+	json.NewDecoder(resp.Body).Decode(&body)
+	io.Copy(io.Discard, resp.Body)
+	result := &ExecuteResult{Status: body.Status, ExitCode: body.ExitCode}
+	if header := resp.Header.Get("X-ProcessId"); header != "" {
+		result.ProcessID, _ = strconv.ParseInt(header, 10, 64)
+	}
+	result.ExecutionID = resp.Header.Get("X-Execution-Id")
+	return result
+}
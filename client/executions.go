@@ -0,0 +1,201 @@
+package client
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Execution statuses
+const (
+	StatusRunning    = "RUNNING"
+	StatusSuccessful = "SUCCESSFUL"
+	StatusFailed     = "FAILED"
+)
+
+// Execution is a recorded execution (requires the executor's history store, HISTORY_DB_DSN)
+type Execution struct {
+	ID            string            `json:"id"`
+	TrackingID    string            `json:"trackingId"`
+	ProcessID     int64             `json:"processId,omitempty"`
+	Script        string            `json:"script"`
+	ScriptVersion string            `json:"scriptVersion,omitempty"`
+	TaskName      string            `json:"taskName,omitempty"`
+	Caller        string            `json:"caller,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	RerunOf       string            `json:"rerunOf,omitempty"` // Execution this one re-ran
+	TargetPod     string            `json:"targetPod,omitempty"`
+	Status        string            `json:"status"`
+	Output        string            `json:"output,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Category      string            `json:"category,omitempty"`    // Failure category of a failed execution, e.g. script-error
+	Termination   string            `json:"termination,omitempty"` // SIGTERM, SIGKILL or POD_DELETED when the script was stopped
+	StartedAt     time.Time         `json:"startedAt"`
+	FinishedAt    *time.Time        `json:"finishedAt,omitempty"`
+	// Built-in process tracking state
+	TrackingStage     string     `json:"trackingStage,omitempty"`
+	TrackingStatus    string     `json:"trackingStatus,omitempty"`
+	TrackingMessage   string     `json:"trackingMessage,omitempty"`
+	TrackingUpdatedAt *time.Time `json:"trackingUpdatedAt,omitempty"`
+}
+
+// Finished reports whether the execution ended.
+func (e *Execution) Finished() bool {
+	return e.Status != StatusRunning
+}
+
+// Execution returns one execution with its output (GET /v1/executions/{id}). The id is an
+// execution ID or a process ID.
+func (c *Client) Execution(ctx context.Context, id string) (*Execution, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/v1/executions/"+url.PathEscape(id), nil, retryIdempotent)
+	if err != nil {
+		return nil, err
+	}
+	execution := &Execution{}
+	return execution, decode(resp, execution)
+}
+
+// RerunRequest overrides parts of the original request of a re-run; the zero value re-runs it as is
+type RerunRequest struct {
+	TaskData   map[string]interface{} `json:"taskData,omitempty"`   // Parameters replacing the original ones
+	TrackingID string                 `json:"trackingId,omitempty"` // The new execution ID when empty
+	Version    string                 `json:"version,omitempty"`
+}
+
+// Rerun runs a recorded execution again with the same request, apart from the overrides
+// (POST /v1/executions/{id}/rerun). Sensitive parameters aren't recorded and must be passed again.
+// Like Execute, it waits for the script to finish and retries only refused requests.
+func (c *Client) Rerun(ctx context.Context, id string, overrides RerunRequest) (*ExecuteResult, error) {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/executions/"+url.PathEscape(id)+"/rerun", overrides, retryRefused)
+	if err != nil {
+		return nil, err
+	}
+	return executeResult(resp), nil
+}
+
+// Cancel stops a running execution, given its execution ID or process ID
+// (POST /v1/executions/{id}/cancel). The call must reach the replica running the execution; its
+// Execute call then fails. Executions not running there fail with NOT_FOUND.
+func (c *Client) Cancel(ctx context.Context, id string) error {
+	resp, err := c.do(ctx, http.MethodPost, "/v1/executions/"+url.PathEscape(id)+"/cancel", nil, retryRefused)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// WaitForCompletion polls an execution every interval (at least a second) until it finished or
+// ctx is done, and returns its final record. Useful for executions started by triggers or
+// schedules, or when an Execute call was cut off before the script ended.
+func (c *Client) WaitForCompletion(ctx context.Context, id string, interval time.Duration) (*Execution, error) {
+	if interval < time.Second {
+		interval = time.Second
+	}
+	for {
+		execution, err := c.Execution(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if execution.Finished() {
+			return execution, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// StreamLogs writes the output of an execution to w (GET /v1/executions/{id}/output). The executor
+// records the output when the execution ends, so StreamLogs first waits for completion, polling
+// every interval, then decompresses the output as it is downloaded.
+func (c *Client) StreamLogs(ctx context.Context, id string, interval time.Duration, w io.Writer) error {
+	if _, err := c.WaitForCompletion(ctx, id, interval); err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/v1/executions/"+url.PathEscape(id)+"/output", nil, retryIdempotent)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	output, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, output)
+	return err
+}
+
+// ComparedExecution is one side of a Comparison
+type ComparedExecution struct {
+	ID        string `json:"id"`
+	TargetPod string `json:"targetPod,omitempty"`
+	Status    string `json:"status"`
+}
+
+// Comparison is the diff of the outputs of two executions of the same script
+type Comparison struct {
+	Script    string            `json:"script"`
+	A         ComparedExecution `json:"a"`
+	B         ComparedExecution `json:"b"`
+	Identical bool              `json:"identical"`
+	Diff      string            `json:"diff,omitempty"` // Unified diff from a's output to b's
+}
+
+// CompareExecutions diffs the outputs of two finished executions of the same script
+// (GET /v1/executions/compare). a and b are execution IDs or process IDs.
+func (c *Client) CompareExecutions(ctx context.Context, a, b string) (*Comparison, error) {
+	query := url.Values{"a": {a}, "b": {b}}
+	resp, err := c.do(ctx, http.MethodGet, "/v1/executions/compare?"+query.Encode(), nil, retryIdempotent)
+	if err != nil {
+		return nil, err
+	}
+	comparison := &Comparison{}
+	return comparison, decode(resp, comparison)
+}
+
+// ExecutionFilter narrows the executions listed by Executions
+type ExecutionFilter struct {
+	Script     string
+	TrackingID string
+	Status     string // RUNNING, SUCCESSFUL, WARNING, SKIPPED or FAILED
+	Category   string // Failure category, e.g. pod-unavailable
+	Limit      int    // Executor default (100) when 0
+}
+
+// Executions lists recorded executions, most recent first and without their output
+// (GET /v1/executions).
+func (c *Client) Executions(ctx context.Context, filter ExecutionFilter) ([]Execution, error) {
+	query := url.Values{}
+	if filter.Script != "" {
+		query.Set("script", filter.Script)
+	}
+	if filter.TrackingID != "" {
+		query.Set("trackingId", filter.TrackingID)
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Category != "" {
+		query.Set("category", filter.Category)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	path := "/v1/executions"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := c.do(ctx, http.MethodGet, path, nil, retryIdempotent)
+	if err != nil {
+		return nil, err
+	}
+	var executions []Execution
+	return executions, decode(resp, &executions)
+}
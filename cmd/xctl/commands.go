@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alvdevcl/k8s-script-executor/client"
+	"github.com/spf13/cobra"
+)
+
+// newScriptsCommand lists the scripts the caller may run.
+func newScriptsCommand(opts *globalOptions) *cobra.Command {
+	var filter client.OptionsFilter
+	cmd := &cobra.Command{
+		Use:   "scripts",
+		Short: "List the scripts you may run",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scripts, err := opts.newClient().Options(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+			if opts.json {
+				return printJSON(cmd.OutOrStdout(), scripts)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tVERSION\tPARAMETERS\tTAGS")
+			for _, script := range scripts {
+				params := make([]string, 0, len(script.Parameters))
+				for _, param := range script.Parameters {
+					if param.Optional {
+						params = append(params, "["+param.Name+"]")
+					} else {
+						params = append(params, param.Name)
+					}
+				}
+				name := script.Name
+				if script.Deprecated {
+					name += " (deprecated)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, script.Version, strings.Join(params, ","), strings.Join(script.Tags, ","))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringSliceVar(&filter.Tags, "tag", nil, "Only scripts with this tag (repeatable)")
+	cmd.Flags().StringVar(&filter.Search, "search", "", "Only scripts whose name, description or tags match")
+	return cmd
+}
+
+// newExecCommand runs a script and waits for it, printing its process ID.
+func newExecCommand(opts *globalOptions) *cobra.Command {
+	var (
+		params     []string
+		request    client.ExecuteRequest
+		showOutput bool
+	)
+	cmd := &cobra.Command{
+		Use:   "exec SCRIPT",
+		Short: "Run a script and wait for it to finish",
+		Example: `  xctl exec check-logs --param pod=web-0 --param lines=200
+  xctl exec rotate-certs --version 2.0.0 --tracking-id INC-1234 --output`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			request.TaskData = map[string]interface{}{"name": args[0]}
+			for _, param := range params {
+				name, value, found := strings.Cut(param, "=")
+				if !found || name == "" {
+					return fmt.Errorf("invalid --param '%s', expected NAME=VALUE", param)
+				}
+				request.TaskData[name] = value
+			}
+			c := opts.newClient()
+			result, err := c.Execute(cmd.Context(), request)
+			if err != nil {
+				return err
+			}
+			if opts.json {
+				return printJSON(cmd.OutOrStdout(), result)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Script '%s' %s (execution %s, process ID %d).\n", args[0], completedAs(result), result.ExecutionID, result.ProcessID)
+			if showOutput && result.ExecutionID != "" {
+				return c.StreamLogs(cmd.Context(), result.ExecutionID, time.Second, cmd.OutOrStdout())
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVarP(&params, "param", "p", nil, "Script parameter as NAME=VALUE (repeatable)")
+	cmd.Flags().StringVar(&request.TrackingID, "tracking-id", "", "Tracking ID of the execution (default: its execution ID)")
+	cmd.Flags().StringVar(&request.Version, "version", "", "Script version (default: the current one)")
+	cmd.Flags().StringVar(&request.TaskName, "task-name", "", "Task name shown in the history and process tracking")
+	cmd.Flags().BoolVar(&showOutput, "output", false, "Print the script output afterwards (needs the execution history)")
+	return cmd
+}
+
+// newRerunCommand re-runs a recorded execution and waits for it, printing the new execution's IDs.
+func newRerunCommand(opts *globalOptions) *cobra.Command {
+	var (
+		params     []string
+		overrides  client.RerunRequest
+		showOutput bool
+	)
+	cmd := &cobra.Command{
+		Use:   "rerun EXECUTION",
+		Short: "Run a recorded execution again with the same parameters",
+		Long: `Run a recorded execution again, given its execution ID or process ID, with the same script,
+version and parameters unless overridden. Sensitive parameters aren't recorded and must be passed again.`,
+		Example: `  xctl rerun 01901d6e-3c2a-7b4e-9f1d-5a2b8c7d9e0f
+  xctl rerun 3603915199274130959 --param lines=500 --output`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, param := range params {
+				name, value, found := strings.Cut(param, "=")
+				if !found || name == "" {
+					return fmt.Errorf("invalid --param '%s', expected NAME=VALUE", param)
+				}
+				if overrides.TaskData == nil {
+					overrides.TaskData = map[string]interface{}{}
+				}
+				overrides.TaskData[name] = value
+			}
+			c := opts.newClient()
+			result, err := c.Rerun(cmd.Context(), args[0], overrides)
+			if err != nil {
+				return err
+			}
+			if opts.json {
+				return printJSON(cmd.OutOrStdout(), result)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Re-run of '%s' %s (execution %s, process ID %d).\n", args[0], completedAs(result), result.ExecutionID, result.ProcessID)
+			if showOutput && result.ExecutionID != "" {
+				return c.StreamLogs(cmd.Context(), result.ExecutionID, time.Second, cmd.OutOrStdout())
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringArrayVarP(&params, "param", "p", nil, "Parameter override as NAME=VALUE (repeatable)")
+	cmd.Flags().StringVar(&overrides.TrackingID, "tracking-id", "", "Tracking ID of the re-run (default: its execution ID)")
+	cmd.Flags().StringVar(&overrides.Version, "version", "", "Script version (default: the original one)")
+	cmd.Flags().BoolVar(&showOutput, "output", false, "Print the script output afterwards")
+	return cmd
+}
+
+// completedAs describes how an execution completed: plainly, or with the status its exit code mapped to.
+func completedAs(result *client.ExecuteResult) string {
+	if result.Status == "" {
+		return "completed"
+	}
+	return fmt.Sprintf("completed with status %s (exit code %d)", result.Status, result.ExitCode)
+}
+
+// newCancelCommand stops a running execution.
+func newCancelCommand(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "cancel EXECUTION",
+		Short: "Stop a running execution",
+		Long: `Stop a running execution, given its execution ID or process ID: the script is terminated in its
+pod and the execution fails. The request must reach the replica running the execution.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.newClient().Cancel(cmd.Context(), args[0]); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Execution '%s' cancelled.\n", args[0])
+			return nil
+		},
+	}
+}
+
+// newLogsCommand prints the output of an execution, waiting for it to finish.
+func newLogsCommand(opts *globalOptions) *cobra.Command {
+	var interval time.Duration
+	cmd := &cobra.Command{
+		Use:   "logs EXECUTION",
+		Short: "Print the output of an execution, waiting until it finished",
+		Long: `Print the output of an execution, given its execution ID or process ID. The executor records
+the output when the execution ends, so a running execution is polled until then.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return opts.newClient().StreamLogs(cmd.Context(), args[0], interval, cmd.OutOrStdout())
+		},
+	}
+	cmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Polling interval while the execution runs")
+	return cmd
+}
+
+// newDiffCommand prints the diff of the outputs of two executions.
+func newDiffCommand(opts *globalOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff EXECUTION_A EXECUTION_B",
+		Short: "Compare the outputs of two executions of the same script",
+		Long: `Print a unified diff of the outputs of two finished executions of the same script, given their
+execution IDs or process IDs, e.g. to check that a re-run on another pod produced the same result.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			comparison, err := opts.newClient().CompareExecutions(cmd.Context(), args[0], args[1])
+			if err != nil {
+				return err
+			}
+			if opts.json {
+				return printJSON(cmd.OutOrStdout(), comparison)
+			}
+			if comparison.Identical {
+				fmt.Fprintf(cmd.OutOrStdout(), "Outputs of '%s' on %s and %s are identical.\n", comparison.Script, comparison.A.TargetPod, comparison.B.TargetPod)
+				return nil
+			}
+			_, err = fmt.Fprint(cmd.OutOrStdout(), comparison.Diff)
+			return err
+		},
+	}
+}
+
+// newHistoryCommand lists recorded executions.
+func newHistoryCommand(opts *globalOptions) *cobra.Command {
+	var filter client.ExecutionFilter
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recent executions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter.Status = strings.ToUpper(filter.Status)
+			executions, err := opts.newClient().Executions(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+			if opts.json {
+				return printJSON(cmd.OutOrStdout(), executions)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tPROCESS\tSCRIPT\tSTATUS\tCALLER\tSTARTED\tDURATION")
+			for _, execution := range executions {
+				duration := "-"
+				if execution.FinishedAt != nil {
+					duration = execution.FinishedAt.Sub(execution.StartedAt).Round(time.Second).String()
+				}
+				script := execution.Script
+				if execution.ScriptVersion != "" {
+					script += "@" + execution.ScriptVersion
+				}
+				status := execution.Status
+				if execution.Category != "" {
+					status += " (" + execution.Category + ")"
+				}
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", execution.ID, execution.ProcessID, script, status,
+					execution.Caller, execution.StartedAt.Local().Format(time.DateTime), duration)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&filter.Script, "script", "", "Only executions of this script")
+	cmd.Flags().StringVar(&filter.TrackingID, "tracking-id", "", "Only executions with this tracking ID")
+	cmd.Flags().StringVar(&filter.Status, "status", "", "Only executions with this status (running, successful, warning, skipped, failed)")
+	cmd.Flags().StringVar(&filter.Category, "category", "", "Only failed executions of this category (e.g. pod-unavailable, script-error)")
+	cmd.Flags().IntVar(&filter.Limit, "limit", 20, "Maximum number of executions")
+	return cmd
+}
@@ -0,0 +1,99 @@
+// Command xctl is the operator CLI of the k8s-script-executor: it lists scripts, runs them, shows
+// their output and the execution history through the executor API.
+//
+//	export XCTL_SERVER=https://script-executor.example.com XCTL_API_KEY=...
+//	xctl scripts --tag maintenance
+//	xctl exec check-logs --param pod=web-0 --param lines=200
+//	xctl logs 1234
+//	xctl history --script check-logs --status failed
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/alvdevcl/k8s-script-executor/client"
+	"github.com/spf13/cobra"
+)
+
+// globalOptions are the connection flags shared by every command
+type globalOptions struct {
+	server string
+	apiKey string
+	token  string
+	json   bool
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) && apiErr.Output != "" {
+			fmt.Fprintln(os.Stderr, strings.TrimRight(apiErr.Output, "\n"))
+		}
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// newRootCommand builds the xctl command tree. Connection flags default to the XCTL_SERVER,
+// XCTL_API_KEY and XCTL_TOKEN environment variables.
+func newRootCommand() *cobra.Command {
+	opts := &globalOptions{}
+	root := &cobra.Command{
+		Use:           "xctl",
+		Short:         "Operate the k8s-script-executor",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&opts.server, "server", envOrDefault("XCTL_SERVER", "http://localhost:8080"), "Executor base URL (XCTL_SERVER)")
+	root.PersistentFlags().StringVar(&opts.apiKey, "api-key", os.Getenv("XCTL_API_KEY"), "API key (XCTL_API_KEY)")
+	root.PersistentFlags().StringVar(&opts.token, "token", os.Getenv("XCTL_TOKEN"), "Bearer token, e.g. a ServiceAccount token (XCTL_TOKEN)")
+	root.PersistentFlags().BoolVar(&opts.json, "json", false, "Print JSON instead of tables")
+
+	root.AddCommand(
+		newScriptsCommand(opts),
+		newExecCommand(opts),
+		newRerunCommand(opts),
+		newCancelCommand(opts),
+		newLogsCommand(opts),
+		newDiffCommand(opts),
+		newHistoryCommand(opts),
+	)
+	return root
+}
+
+// newClient returns an API client for the connection flags.
+func (o *globalOptions) newClient() *client.Client {
+	var clientOpts []client.Option
+	if o.apiKey != "" {
+		clientOpts = append(clientOpts, client.WithAPIKey(o.apiKey))
+	}
+	if o.token != "" {
+		clientOpts = append(clientOpts, client.WithBearerToken(o.token))
+	}
+	return client.New(o.server, clientOpts...)
+}
+
+// envOrDefault returns the environment variable, or fallback when it is unset or empty.
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// printJSON writes v as indented JSON.
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configContextKey is the gin context key under which injectConfig stores the request's Config
+const configContextKey = "executor.config"
+
+// activeConfig is the validated configuration built at startup
+var activeConfig atomic.Pointer[Config]
+
+// currentConfig returns the active configuration, for code running outside a request (schedulers,
+// background loops). Before startup completes it falls back to reading the environment.
+func currentConfig() *Config {
+	if config := activeConfig.Load(); config != nil {
+		return config
+	}
+	return loadConfig()
+}
+
+// injectConfig is middleware making the active configuration available to handlers, so a request
+// sees one consistent Config from start to finish.
+func injectConfig(c *gin.Context) {
+	c.Set(configContextKey, currentConfig())
+	c.Next()
+}
+
+// configFromContext returns the configuration injected into the request.
+func configFromContext(c *gin.Context) *Config {
+	if value, exists := c.Get(configContextKey); exists {
+		if config, ok := value.(*Config); ok {
+			return config
+		}
+	}
+	return currentConfig()
+}
+
+// validateConfig checks a configuration for values the executor can't run with, reporting all of
+// them at once so a misconfigured deployment fails at startup rather than on the first request.
+func validateConfig(config *Config) error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if config.ProcessTrackingURL != "" {
+		parsed, err := url.Parse(config.ProcessTrackingURL)
+		check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "",
+			"PROCESS_TRACKING_SERVICE_URL '%s' is not an absolute http(s) URL", config.ProcessTrackingURL)
+	}
+	check(config.ProcessTrackingURL != "" || !config.ProcessTrackingRequired,
+		"PROCESS_TRACKING_SERVICE_URL is required when PROCESS_TRACKING_REQUIRED=true")
+	check(config.ProcessTrackingClientCert == "" || config.ProcessTrackingClientKey != "",
+		"PROCESS_TRACKING_CLIENT_CERT requires PROCESS_TRACKING_CLIENT_KEY")
+	check(config.ProcessTrackingMaxAttempts >= 1, "PROCESS_TRACKING_MAX_ATTEMPTS must be at least 1")
+	switch config.ProcessTrackingMetadata {
+	case trackingMetadataFields, trackingMetadataMessage, trackingMetadataOff:
+	default:
+		check(false, "unknown PROCESS_TRACKING_METADATA '%s' (supported: fields, message, off)", config.ProcessTrackingMetadata)
+	}
+	check(containsString(mappedTrackingStatuses, config.ProcessTrackingWarningStatus),
+		"unsupported PROCESS_TRACKING_WARNING_STATUS '%s' (supported: %v)", config.ProcessTrackingWarningStatus, mappedTrackingStatuses)
+	check(containsString(mappedTrackingStatuses, config.ProcessTrackingSkippedStatus),
+		"unsupported PROCESS_TRACKING_SKIPPED_STATUS '%s' (supported: %v)", config.ProcessTrackingSkippedStatus, mappedTrackingStatuses)
+	check(isTrackingOutputMode(config.TrackingOutputMode),
+		"unknown TRACKING_OUTPUT_MODE '%s' (supported: head, tail, errors, attachment)", config.TrackingOutputMode)
+	if _, err := regexp.Compile(config.TrackingOutputErrorPattern); err != nil {
+		check(false, "invalid TRACKING_OUTPUT_ERROR_PATTERN: %v", err)
+	}
+
+	check(containsString(supportedHistoryDBDrivers, config.HistoryDBDriver),
+		"unsupported HISTORY_DB_DRIVER '%s' (supported: %v)", config.HistoryDBDriver, supportedHistoryDBDrivers)
+	check(config.ExecTransientRetries >= 0, "EXEC_TRANSIENT_RETRIES must not be negative")
+	switch config.SchedulerMode {
+	case schedulerModeOff, schedulerModeCronJob, schedulerModeInternal:
+	default:
+		check(false, "unknown SCHEDULER_MODE '%s' (supported: off, cronjob, internal)", config.SchedulerMode)
+	}
+	switch config.ExecutorMode {
+	case executorModeKubernetes:
+	case executorModeLocal:
+		// Without a kubeconfig, local mode has no Kubernetes client
+		check(config.Kubeconfig != "" || (config.SchedulerMode != schedulerModeCronJob && !config.TokenReviewEnabled),
+			"EXECUTOR_MODE=local needs KUBECONFIG for SCHEDULER_MODE=cronjob and TOKEN_REVIEW_ENABLED")
+	default:
+		check(false, "unknown EXECUTOR_MODE '%s' (supported: kubernetes, local)", config.ExecutorMode)
+	}
+	check(!config.ImpersonateServiceAccounts || config.TokenReviewEnabled, "IMPERSONATE_SERVICE_ACCOUNTS requires TOKEN_REVIEW_ENABLED")
+	check(config.ExclusiveLeaseDuration >= 3*time.Second, "EXCLUSIVE_LEASE_DURATION_SECONDS must be at least 3")
+	check(config.SerializeMaxWait >= 0, "SERIALIZE_MAX_WAIT_SECONDS must not be negative")
+	check(config.ResourceTTL >= 0, "RESOURCE_TTL_SECONDS must not be negative")
+	check(config.ResourceGCInterval >= time.Second, "RESOURCE_GC_INTERVAL_SECONDS must be at least 1")
+	check(config.PermissionCheckInterval >= 0, "PERMISSION_CHECK_INTERVAL_SECONDS must not be negative")
+	check(config.ExecutionJournalPollInterval >= time.Second, "EXECUTION_JOURNAL_POLL_SECONDS must be at least 1")
+	check(config.TerminationGracePeriod >= 0, "TERMINATION_GRACE_SECONDS must not be negative")
+	check(config.PreflightInterval >= 0, "PREFLIGHT_INTERVAL_SECONDS must not be negative")
+	check(config.ParamFileThreshold >= 0, "PARAM_FILE_THRESHOLD_BYTES must not be negative")
+	check(config.Port >= 1 && config.Port <= 65535, "PORT must be between 1 and 65535")
+	check(config.ReadHeaderTimeout >= 0 && config.ReadTimeout >= 0 && config.WriteTimeout >= 0 && config.IdleTimeout >= 0,
+		"HTTP_*_TIMEOUT_SECONDS must not be negative")
+	check(config.MaxRequestBodyBytes >= 0 && config.RequestTimeout >= 0 && config.ExecuteRequestTimeout >= 0,
+		"MAX_REQUEST_BODY_BYTES, REQUEST_TIMEOUT_SECONDS and EXECUTE_REQUEST_TIMEOUT_SECONDS must not be negative")
+	check(!config.CORSAllowCredentials || !containsString(config.CORSAllowedOrigins, "*"),
+		"CORS_ALLOW_CREDENTIALS=true can't be combined with CORS_ALLOWED_ORIGINS=*")
+	check((config.AdminTLSCertFile == "") == (config.AdminTLSKeyFile == ""), "ADMIN_TLS_CERT_FILE and ADMIN_TLS_KEY_FILE must be set together")
+	check(config.AdminClientCAFile == "" || config.AdminTLSCertFile != "", "ADMIN_CLIENT_CA_FILE requires ADMIN_TLS_CERT_FILE")
+	check(config.AdminTLSCertFile == "" || config.AdminListenAddr != "", "ADMIN_TLS_CERT_FILE requires ADMIN_LISTEN_ADDR")
+	check((config.TLSCertFile == "") == (config.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(config.TLSClientAuth == "require" || config.TLSClientAuth == "optional",
+		"unknown TLS_CLIENT_AUTH '%s' (supported: require, optional)", config.TLSClientAuth)
+	if _, err := parseLogLevel(config.LogLevel); err != nil {
+		check(false, "LOG_LEVEL: %v", err)
+	}
+	return errors.Join(errs...)
+}
+
+// secretConfigFields are the Config fields GET /admin/config masks entirely
+var secretConfigFields = map[string]bool{
+	"ProcessTrackingToken":    true,
+	"ProcessTrackingPassword": true,
+	"ProcessTrackingCookie":   true,
+	"TriggerToken":            true,
+	"AdminToken":              true,
+	"CallbackSigningSecret":   true,
+	"SMTPPassword":            true,
+}
+
+// dsnPasswordPattern matches the password of a key=value connection string
+var dsnPasswordPattern = regexp.MustCompile(`(?i)(password=)('[^']*'|\S+)`)
+
+// maskConfigValue hides the secret parts of a string setting: passwords in URLs and connection
+// strings, and inline private keys. Paths and other values are returned as they are.
+func maskConfigValue(value string) string {
+	if strings.Contains(value, "PRIVATE KEY") {
+		return maskedValue
+	}
+	if parsed, err := url.Parse(value); err == nil && parsed.User != nil {
+		if _, hasPassword := parsed.User.Password(); hasPassword {
+			parsed.User = url.UserPassword(parsed.User.Username(), maskedValue)
+			return parsed.String()
+		}
+	}
+	return dsnPasswordPattern.ReplaceAllString(value, "${1}"+maskedValue)
+}
+
+// effectiveConfig returns the settings of a configuration by field name, secrets masked (empty
+// secrets stay empty, so it shows whether they are set) and durations formatted like "30s".
+func effectiveConfig(config *Config) map[string]interface{} {
+	settings := make(map[string]interface{})
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, field := value.Type().Field(i).Name, value.Field(i).Interface()
+		switch typed := field.(type) {
+		case time.Duration:
+			settings[name] = typed.String()
+		case string:
+			if secretConfigFields[name] && typed != "" {
+				typed = maskedValue
+			}
+			settings[name] = maskConfigValue(typed)
+		default:
+			settings[name] = field
+		}
+	}
+	return settings
+}
+
+// getConfig handles GET /admin/config, returning the effective configuration of this replica,
+// including reloaded settings, so operators can tell e.g. which selector and namespace it uses
+// without reading the pod's environment.
+func getConfig(c *gin.Context) {
+	writeJSON(c, http.StatusOK, effectiveConfig(configFromContext(c)))
+}
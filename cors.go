@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsExposedHeaders are the response headers browser clients may read
+var corsExposedHeaders = []string{"X-ProcessId", "X-Execution-Id", "Retry-After"}
+
+// corsOriginAllowed reports whether an Origin is in CORS_ALLOWED_ORIGINS, where "*" allows any
+// origin and "*.example.com" any subdomain of example.com.
+func corsOriginAllowed(config *Config, origin string) bool {
+	for _, allowed := range config.CORSAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if _, host, found := strings.Cut(origin, "://"); found && strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(suffix)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleCORS is middleware letting browser-based consoles on CORS_ALLOWED_ORIGINS call the API
+// directly. It answers preflight requests itself, before authentication, as browsers send them
+// without credentials. Requests from other origins get no CORS headers, so browsers block them.
+func handleCORS(c *gin.Context) {
+	config := configFromContext(c)
+	origin := c.GetHeader("Origin")
+	if origin == "" || len(config.CORSAllowedOrigins) == 0 || !corsOriginAllowed(config, origin) {
+		c.Next()
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	if config.CORSAllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.Request.Method != http.MethodOptions || c.GetHeader("Access-Control-Request-Method") == "" {
+		header.Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		c.Next()
+		return
+	}
+
+	// Preflight
+	header.Set("Access-Control-Allow-Methods", strings.Join(config.CORSAllowedMethods, ", "))
+	header.Set("Access-Control-Allow-Headers", strings.Join(config.CORSAllowedHeaders, ", "))
+	if config.CORSMaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(config.CORSMaxAge))
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Labels and annotations placed on the CronJobs of scheduled scripts and other created resources
+const (
+	managedByLabel        = "app.kubernetes.io/managed-by"
+	managedByValue        = "k8s-script-executor"
+	scriptIDLabel         = "executor.decloudz.io/script-id"
+	scriptNameAnnotation  = "executor.decloudz.io/script-name"
+	scheduledCronJobLimit = 52 // CronJob names are limited to 52 characters (11 are reserved for Job suffixes)
+)
+
+// Scheduler modes selected by SCHEDULER_MODE
+const (
+	schedulerModeOff     = "off"
+	schedulerModeCronJob = "cronjob"
+)
+
+var dnsLabelInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// scheduledCronJobName derives a DNS-1123 compliant CronJob name from a script ID.
+func scheduledCronJobName(scriptID string) string {
+	name := "script-" + strings.Trim(dnsLabelInvalidChars.ReplaceAllString(strings.ToLower(scriptID), "-"), "-")
+	if len(name) > scheduledCronJobLimit {
+		name = strings.TrimRight(name[:scheduledCronJobLimit], "-")
+	}
+	return name
+}
+
+// desiredCronJob builds the CronJob that triggers a scheduled script through /v1/trigger.
+func desiredCronJob(def *ScriptDefinition, config *Config) *batchv1.CronJob {
+	triggerURL := strings.TrimSuffix(config.SchedulerExecutorURL, "/") + "/v1/trigger/" + def.ID
+	successfulJobsHistoryLimit := int32(3)
+	failedJobsHistoryLimit := int32(3)
+	backoffLimit := int32(0)
+
+	return &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        scheduledCronJobName(def.ID),
+			Namespace:   config.SchedulerNamespace,
+			Labels:      scriptLabels(def),
+			Annotations: map[string]string{scriptNameAnnotation: def.Name},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:                   def.Schedule,
+			ConcurrencyPolicy:          batchv1.ForbidConcurrent,
+			SuccessfulJobsHistoryLimit: &successfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     &failedJobsHistoryLimit,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{managedByLabel: managedByValue}},
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyNever,
+							Containers: []corev1.Container{{
+								Name:  "trigger",
+								Image: config.SchedulerCronJobImage,
+								Env: []corev1.EnvVar{{
+									Name: "TRIGGER_TOKEN",
+									ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: config.SchedulerTokenSecret},
+										Key:                  "token",
+									}},
+								}},
+								Args: []string{"-fsS", "-X", "POST", "-H", "Authorization: Bearer $(TRIGGER_TOKEN)", triggerURL},
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// reconcileCronJobs makes the managed CronJobs match the scripts that declare a `schedule`:
+// missing ones are created, changed ones updated, and ones for removed/unscheduled scripts deleted.
+func reconcileCronJobs(ctx context.Context, clientset kubernetes.Interface, config *Config) error {
+	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load script definitions: %v", err)
+	}
+
+	definitions = latestScriptVersions(definitions)
+	desired := make(map[string]*batchv1.CronJob)
+	for i := range definitions {
+		if definitions[i].Schedule == "" || definitions[i].Disabled {
+			continue
+		}
+		if strings.HasPrefix(definitions[i].Schedule, "@every") {
+			logger.Info().Msgf("[Scheduler] Skipping script '%s': '%s' is only supported by SCHEDULER_MODE=internal.", definitions[i].Name, definitions[i].Schedule)
+			continue
+		}
+		cronJob := desiredCronJob(&definitions[i], config)
+		desired[cronJob.Name] = cronJob
+	}
+
+	cronJobs := clientset.BatchV1().CronJobs(config.SchedulerNamespace)
+	existing, err := cronJobs.List(ctx, metav1.ListOptions{LabelSelector: managedByLabel + "=" + managedByValue})
+	if err != nil {
+		return fmt.Errorf("failed to list managed CronJobs: %v", err)
+	}
+
+	for i := range existing.Items {
+		current := &existing.Items[i]
+		want, ok := desired[current.Name]
+		if !ok {
+			logger.Info().Msgf("[Scheduler] Deleting CronJob '%s': script no longer scheduled.", current.Name)
+			if err := cronJobs.Delete(ctx, current.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				logger.Error().Msgf("[Scheduler] Failed to delete CronJob '%s': %v", current.Name, err)
+			}
+			continue
+		}
+		delete(desired, current.Name)
+
+		currentContainer := current.Spec.JobTemplate.Spec.Template.Spec.Containers
+		wantContainer := want.Spec.JobTemplate.Spec.Template.Spec.Containers
+		if current.Spec.Schedule == want.Spec.Schedule && current.Labels[scriptLabel] == want.Labels[scriptLabel] && len(currentContainer) == 1 &&
+			currentContainer[0].Image == wantContainer[0].Image &&
+			strings.Join(currentContainer[0].Args, " ") == strings.Join(wantContainer[0].Args, " ") {
+			continue
+		}
+		logger.Info().Msgf("[Scheduler] Updating CronJob '%s' (schedule: %s).", current.Name, want.Spec.Schedule)
+		want.ResourceVersion = current.ResourceVersion
+		if _, err := cronJobs.Update(ctx, want, metav1.UpdateOptions{}); err != nil {
+			logger.Error().Msgf("[Scheduler] Failed to update CronJob '%s': %v", current.Name, err)
+		}
+	}
+
+	for name, want := range desired {
+		logger.Info().Msgf("[Scheduler] Creating CronJob '%s' for script '%s' (schedule: %s).", name, want.Annotations[scriptNameAnnotation], want.Spec.Schedule)
+		if _, err := cronJobs.Create(ctx, want, metav1.CreateOptions{}); err != nil {
+			logger.Error().Msgf("[Scheduler] Failed to create CronJob '%s': %v", name, err)
+		}
+	}
+	return nil
+}
+
+// startCronJobReconciler reconciles CronJobs immediately and then on every interval,
+// so schedule edits in the scripts ConfigMap are picked up without a restart.
+func startCronJobReconciler(clientset kubernetes.Interface, config *Config) {
+	if config.TriggerToken == "" || config.SchedulerTokenSecret == "" {
+		logger.Warn().Msg("[Scheduler] SCHEDULER_MODE=cronjob requires TRIGGER_TOKEN and SCHEDULER_TOKEN_SECRET; CronJob scheduling disabled.")
+		return
+	}
+	go func() {
+		for {
+			if err := reconcileCronJobs(context.Background(), clientset, currentConfig()); err != nil {
+				logger.Error().Msgf("[Scheduler] CronJob reconciliation failed: %v", err)
+			}
+			time.Sleep(config.SchedulerReconcileInterval)
+		}
+	}()
+	logger.Info().Msgf("[Scheduler] CronJob reconciler started (namespace: %s, interval: %s).", config.SchedulerNamespace, config.SchedulerReconcileInterval)
+}
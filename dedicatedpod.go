@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// kubeClient is the in-cluster Kubernetes client, set in main. Used by execution modes that
+// create resources (dedicated pods) rather than exec into an existing workload.
+var kubeClient kubernetes.Interface
+
+// ScriptResources are the container resource requests/limits of a dedicated script pod
+type ScriptResources struct {
+	Requests map[string]string `json:"requests,omitempty"` // e.g. {"cpu": "100m", "memory": "128Mi"}
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+// PodScheduling places a dedicated script pod, e.g. on a batch node pool away from the
+// latency-sensitive application nodes. The fields take the Kubernetes pod spec's format.
+type PodScheduling struct {
+	NodeSelector map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration `json:"tolerations,omitempty"`
+	Affinity     *corev1.Affinity    `json:"affinity,omitempty"`
+}
+
+// validateScheduling checks the "scheduling" field of a script definition. Only dedicated pods are
+// scheduled by the executor; node-targeted scripts pick their node with nodeName/nodeSelector.
+func validateScheduling(def *ScriptDefinition) error {
+	if def.Scheduling == nil {
+		return nil
+	}
+	if backend := inferBackend(def); backend != backendJob {
+		return fmt.Errorf("'scheduling' is only supported by backend 'job', not '%s'", backend)
+	}
+	for i, toleration := range def.Scheduling.Tolerations {
+		switch toleration.Operator {
+		case "", corev1.TolerationOpEqual:
+		case corev1.TolerationOpExists:
+			if toleration.Value != "" {
+				return fmt.Errorf("toleration %d has operator 'Exists' and a value", i)
+			}
+		default:
+			return fmt.Errorf("toleration %d has unknown operator '%s' (supported: Equal, Exists)", i, toleration.Operator)
+		}
+		switch toleration.Effect {
+		case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("toleration %d has unknown effect '%s' (supported: NoSchedule, PreferNoSchedule, NoExecute)", i, toleration.Effect)
+		}
+	}
+	return nil
+}
+
+// dedicatedPodPollInterval is how often a dedicated pod's phase is checked
+const dedicatedPodPollInterval = 2 * time.Second
+
+// toResourceList converts a name -> quantity map into a Kubernetes ResourceList.
+func toResourceList(quantities map[string]string) (corev1.ResourceList, error) {
+	if len(quantities) == 0 {
+		return nil, nil
+	}
+	list := make(corev1.ResourceList, len(quantities))
+	for name, value := range quantities {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quantity '%s' for resource '%s': %v", value, name, err)
+		}
+		list[corev1.ResourceName(name)] = quantity
+	}
+	return list, nil
+}
+
+// toResourceRequirements converts a script's resources into container resource requirements.
+func toResourceRequirements(resources *ScriptResources) (corev1.ResourceRequirements, error) {
+	var requirements corev1.ResourceRequirements
+	if resources == nil {
+		return requirements, nil
+	}
+	var err error
+	if requirements.Requests, err = toResourceList(resources.Requests); err != nil {
+		return requirements, err
+	}
+	if requirements.Limits, err = toResourceList(resources.Limits); err != nil {
+		return requirements, err
+	}
+	return requirements, nil
+}
+
+// dedicatedPodName builds a unique, DNS-1123 compliant pod name for one execution.
+func dedicatedPodName(scriptID, executionID string) string {
+	base := strings.Trim(dnsLabelInvalidChars.ReplaceAllString(strings.ToLower(scriptID), "-"), "-")
+	suffix := executionID
+	if len(suffix) > 10 {
+		suffix = suffix[len(suffix)-10:]
+	}
+	if maxBase := 63 - len("script-run--") - len(suffix); len(base) > maxBase {
+		base = strings.TrimRight(base[:maxBase], "-")
+	}
+	return "script-run-" + base + "-" + suffix
+}
+
+// runInDedicatedPod runs the command in a short-lived pod built from the script's image, streams its
+// logs, and deletes the pod afterwards. This frees scripts from needing their tooling preinstalled in
+// the target workload's image. It returns the pod name and combined output; a non-zero exit code is
+// reported as an error.
+func runInDedicatedPod(ctx context.Context, run *executorRun) (string, string, error) {
+	config, def := run.Config, run.Definition
+	if kubeClient == nil {
+		return "", "", fmt.Errorf("kubernetes client not initialized")
+	}
+	requirements, err := toResourceRequirements(def.Resources)
+	if err != nil {
+		return "", "", err
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dedicatedPodName(def.ID, run.ExecutionID),
+			Namespace:   config.Namespace,
+			Labels:      ownerLabels(run),
+			Annotations: ownerAnnotations(run),
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{{
+				Name:            "script",
+				Image:           def.Image,
+				Command:         []string{"/bin/sh", "-c", run.Command},
+				Resources:       requirements,
+				SecurityContext: runAsUserSecurityContext(def),
+			}},
+		},
+	}
+	if scheduling := def.Scheduling; scheduling != nil {
+		pod.Spec.NodeSelector = scheduling.NodeSelector
+		pod.Spec.Tolerations = scheduling.Tolerations
+		pod.Spec.Affinity = scheduling.Affinity
+	}
+	return runPodToCompletion(ctx, run, pod)
+}
+
+// runPodToCompletion creates the pod, follows its logs until the "script" container exits,
+// and always deletes the pod afterwards, which also stops the script when ctx is done first: it
+// then gets TERMINATION_GRACE_SECONDS between SIGTERM and SIGKILL. Shared by the execution modes
+// that create their own pod.
+func runPodToCompletion(ctx context.Context, run *executorRun, pod *corev1.Pod) (string, string, error) {
+	config, scriptName := run.Config, run.Definition.Name
+	ctx, cancel := context.WithTimeout(ctx, config.DedicatedPodTimeout)
+	defer cancel()
+	pods := kubeClient.CoreV1().Pods(pod.Namespace)
+
+	created, err := pods.Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create pod for script '%s': %v", scriptName, err)
+	}
+	podName := created.Name
+	logger.Info().Msgf("Created pod '%s' (namespace: %s) for script '%s'.", podName, pod.Namespace, scriptName)
+	defer func() {
+		var options metav1.DeleteOptions
+		if ctx.Err() != nil {
+			grace := int64(config.TerminationGracePeriod.Seconds())
+			options.GracePeriodSeconds = &grace
+			run.Termination = terminationPodDeleted
+		}
+		// Delete with a fresh context so cleanup still happens after a timeout
+		if err := pods.Delete(context.Background(), podName, options); err != nil {
+			logger.Warn().Msgf("Failed to delete pod '%s' for script '%s': %v", podName, scriptName, err)
+		} else {
+			logger.Info().Msgf("Deleted pod '%s' for script '%s'.", podName, scriptName)
+		}
+	}()
+
+	output, err := followContainer(ctx, pods, podName, "script", false)
+	return podName, output, err
+}
+
+// containerStatus returns the status of the named (regular or ephemeral) container of a pod.
+func containerStatus(pod *corev1.Pod, containerName string, ephemeral bool) *corev1.ContainerStatus {
+	statuses := pod.Status.ContainerStatuses
+	if ephemeral {
+		statuses = pod.Status.EphemeralContainerStatuses
+	}
+	for i := range statuses {
+		if statuses[i].Name == containerName {
+			return &statuses[i]
+		}
+	}
+	return nil
+}
+
+// followContainer waits for a container to start, streams its logs until it exits and returns
+// the combined output. A non-zero exit code is reported as an error.
+func followContainer(ctx context.Context, pods typedcorev1.PodInterface, podName, containerName string, ephemeral bool) (string, error) {
+	// Wait for the container to start (or finish) before attaching to its logs
+	for {
+		current, err := pods.Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get pod '%s': %v", podName, err)
+		}
+		status := containerStatus(current, containerName, ephemeral)
+		if status != nil && (status.State.Running != nil || status.State.Terminated != nil) {
+			break
+		}
+		if status != nil {
+			if waiting := status.State.Waiting; waiting != nil && (waiting.Reason == "ErrImagePull" || waiting.Reason == "ImagePullBackOff" || waiting.Reason == "InvalidImageName") {
+				return "", fmt.Errorf("container '%s' in pod '%s' cannot start: %s: %s", containerName, podName, waiting.Reason, waiting.Message)
+			}
+		}
+		if current.Status.Phase == corev1.PodFailed || current.Status.Phase == corev1.PodSucceeded {
+			if status == nil || status.State.Terminated == nil {
+				return "", fmt.Errorf("pod '%s' finished (phase %s) before container '%s' started", podName, current.Status.Phase, containerName)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for container '%s' in pod '%s' to start: %v", containerName, podName, ctx.Err())
+		case <-time.After(dedicatedPodPollInterval):
+		}
+	}
+
+	// Stream logs until the container exits
+	var output strings.Builder
+	stream, err := pods.GetLogs(podName, &corev1.PodLogOptions{Container: containerName, Follow: true}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to stream logs of container '%s' in pod '%s': %v", containerName, podName, err)
+	}
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		logger.Info().Msgf("[%s/%s] %s", podName, containerName, line)
+		output.WriteString(line + "\n")
+	}
+	stream.Close()
+
+	// Determine the exit code once the container has terminated
+	for {
+		current, err := pods.Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return output.String(), fmt.Errorf("failed to get pod '%s': %v", podName, err)
+		}
+		if status := containerStatus(current, containerName, ephemeral); status != nil && status.State.Terminated != nil {
+			if exitCode := status.State.Terminated.ExitCode; exitCode != 0 {
+				return output.String(), fmt.Errorf("exit status %d", exitCode)
+			}
+			return output.String(), nil
+		}
+		select {
+		case <-ctx.Done():
+			return output.String(), fmt.Errorf("timed out waiting for container '%s' in pod '%s' to finish: %v", containerName, podName, ctx.Err())
+		case <-time.After(dedicatedPodPollInterval):
+		}
+	}
+}
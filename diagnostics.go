@@ -0,0 +1,35 @@
+package main
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+func init() {
+	expvar.Publish("goroutines", expvar.Func(func() interface{} { return runtime.NumGoroutine() }))
+}
+
+// startDiagnosticsServer serves net/http/pprof under /debug/pprof/ and expvar under /debug/vars on a
+// separate listener (DIAGNOSTICS_ADDR), so heap and goroutine profiles can be taken in production
+// without exposing them on the API port. Bind it to localhost and use `kubectl port-forward`, or
+// keep the port out of the Service.
+func startDiagnosticsServer(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error().Msgf("Diagnostics server on %s failed: %v", addr, err)
+		}
+	}()
+	logger.Info().Msgf("- Diagnostics: pprof and expvar on %s", addr)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maskedValue replaces the values of sensitive parameters in dry-run responses
+const maskedValue = "****"
+
+// dryRunScript handles POST /v1/execute/dry-run. It takes the same body as /v1/execute and runs the
+// whole execution flow (script resolution, parameter validation, command rendering and target pod
+// selection) but stops before anything is executed: no process tracking record or history entry is
+// created and the rendered command and environment are returned instead.
+func dryRunScript(c *gin.Context) {
+	config := configFromContext(c)
+	var request TaskServiceRequest
+	if err := bindJSONWithNaming(c, &request); err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, "Invalid request body: "+err.Error()))
+		return
+	}
+	writeOutcome(c, runTask(c.Request.Context(), config, request, executionOptions{DryRun: true, Caller: callerFromContext(c), ClientIP: c.ClientIP()}))
+}
+
+// executionModeOf names how a script definition is executed, as reported by dry runs.
+func executionModeOf(def *ScriptDefinition) string {
+	switch {
+	case len(def.Pipeline) > 0:
+		return "pipeline"
+	case def.TektonPipeline != "":
+		return "tekton"
+	case isNodeTargeted(def):
+		return "node"
+	case def.DebugImage != "":
+		return "ephemeralContainer"
+	case def.Image != "":
+		return "dedicatedPod"
+	case len(def.Steps) > 0:
+		return "steps"
+	default:
+		return "exec"
+	}
+}
+
+// maskSensitiveValues replaces every occurrence of the given values in a rendered command, both in
+// the double-quoted form used for environment variables and verbatim (placeholders).
+func maskSensitiveValues(command string, values []string) string {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		command = strings.ReplaceAll(command, fmt.Sprintf("%q", value), maskedValue)
+		command = strings.ReplaceAll(command, value, maskedValue)
+	}
+	return command
+}
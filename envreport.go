@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+// injectedEnvVar describes one environment variable passed to a script
+type injectedEnvVar struct {
+	Name      string `json:"name"`
+	Parameter string `json:"parameter,omitempty"` // Empty for task context and static env variables
+	Value     string `json:"value,omitempty"`     // Only recorded when DEBUG_ENV_VALUES=true and the parameter isn't sensitive
+}
+
+// envReport records exactly what a script's environment looked like, so reports like
+// "my script didn't see $START_DATE" can be resolved from the execution record alone.
+// It is only collected in debug mode (DEBUG=true).
+type envReport struct {
+	Injected               []injectedEnvVar `json:"injected"`
+	SkippedOptional        []string         `json:"skippedOptional"`        // Declared optional parameters absent from taskData
+	UnresolvedPlaceholders []string         `json:"unresolvedPlaceholders"` // ${VAR} placeholders in the command with no matching parameter
+}
+
+// newEnvReport returns an empty report, or nil when debug mode is off (all methods are nil-safe).
+func newEnvReport(config *Config) *envReport {
+	if !config.Debug {
+		return nil
+	}
+	return &envReport{Injected: []injectedEnvVar{}, SkippedOptional: []string{}, UnresolvedPlaceholders: []string{}}
+}
+
+// addInjected records an injected env var; the value is kept only if allowed and the parameter isn't sensitive.
+func (r *envReport) addInjected(param InputParameterDef, envName, value string, recordValues bool) {
+	if r == nil {
+		return
+	}
+	entry := injectedEnvVar{Name: envName, Parameter: param.Name}
+	if recordValues && !param.Sensitive {
+		entry.Value = value
+	}
+	r.Injected = append(r.Injected, entry)
+}
+
+// addBaseEnv records an injected variable not coming from a parameter: the task context
+// (TASK_NAME, TRACKING_ID, ...) or the script's static "env".
+func (r *envReport) addBaseEnv(envName, value string, recordValues bool) {
+	if r == nil {
+		return
+	}
+	entry := injectedEnvVar{Name: envName}
+	if recordValues {
+		entry.Value = value
+	}
+	r.Injected = append(r.Injected, entry)
+}
+
+// addSkippedOptional records a declared optional parameter that was not supplied.
+func (r *envReport) addSkippedOptional(paramName string) {
+	if r == nil {
+		return
+	}
+	r.SkippedOptional = append(r.SkippedOptional, paramName)
+}
+
+// addUnresolvedPlaceholder records a ${VAR} placeholder left unexpanded in the command.
+func (r *envReport) addUnresolvedPlaceholder(placeholder string) {
+	if r == nil {
+		return
+	}
+	r.UnresolvedPlaceholders = append(r.UnresolvedPlaceholders, placeholder)
+}
+
+// record logs the report and stores it on the execution record.
+func (r *envReport) record(executionID, trackingID string) {
+	if r == nil {
+		return
+	}
+	reportJSON, err := json.Marshal(r)
+	if err != nil {
+		logger.Error().Str("trackingId", trackingID).Msgf("Failed to marshal environment report for execution %s: %v", executionID, err)
+		return
+	}
+	logger.Debug().Str("trackingId", trackingID).Msgf("Environment report for execution %s: %s", executionID, string(reportJSON))
+	executionStore.RecordEnvReport(executionID, string(reportJSON))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// runInEphemeralContainer runs the command in an ephemeral debug container attached to the target
+// pod (the `kubectl debug` equivalent). This works for distroless targets where exec'ing into the
+// main container has no shell. With debugTargetContainer set, the debug container shares that
+// container's process namespace.
+//
+// Ephemeral containers cannot be removed once added; they remain in the pod spec as terminated
+// containers until the pod is replaced.
+func runInEphemeralContainer(config *Config, def *ScriptDefinition, podName, executionID, fullCommand string) (string, error) {
+	if kubeClient == nil {
+		return "", fmt.Errorf("kubernetes client not initialized")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.DedicatedPodTimeout)
+	defer cancel()
+	pods := kubeClient.CoreV1().Pods(config.Namespace)
+
+	pod, err := pods.Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get pod '%s': %v", podName, err)
+	}
+
+	containerName := "script-" + executionID
+	if len(containerName) > 63 {
+		containerName = containerName[:63]
+	}
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:            containerName,
+			Image:           def.DebugImage,
+			Command:         []string{"/bin/sh", "-c", fullCommand},
+			SecurityContext: runAsUserSecurityContext(def),
+		},
+		TargetContainerName: def.DebugTargetContainer,
+	})
+	if _, err := pods.UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{}); err != nil {
+		return "", fmt.Errorf("failed to attach ephemeral container to pod '%s': %v", podName, err)
+	}
+	logger.Info().Msgf("Attached ephemeral container '%s' (image: %s) to pod '%s' for script '%s'.", containerName, def.DebugImage, podName, def.Name)
+
+	return followContainer(ctx, pods, podName, containerName, true)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// Event publishers (EVENTS_SINK)
+const (
+	eventsSinkOff   = "off"
+	eventsSinkKafka = "kafka" // Messages on EVENTS_TOPIC of the EVENTS_BROKERS cluster, keyed by script name
+	eventsSinkNATS  = "nats"  // Messages on EVENTS_TOPIC (a subject) of the NATS server at EVENTS_BROKERS
+)
+
+// CloudEvents 1.0 attributes of execution events. The types are a stable contract with consumers:
+// new information is added to the data, never by renaming types.
+const (
+	cloudEventsSpecVersion     = "1.0"
+	cloudEventsContentType     = "application/cloudevents+json" // Structured content mode
+	executionStartedEventType  = "io.decloudz.executor.execution.started"
+	executionFinishedEventType = "io.decloudz.executor.execution.finished"
+)
+
+// eventPublishTimeout bounds publishing one event
+const eventPublishTimeout = 10 * time.Second
+
+// cloudEvent is a CloudEvents 1.0 event in structured JSON format. Every transport (callbacks, Kafka,
+// NATS) sends it as is, so consumers can use any CloudEvents SDK.
+type cloudEvent struct {
+	SpecVersion     string             `json:"specversion"`
+	ID              string             `json:"id"`
+	Source          string             `json:"source"`
+	Type            string             `json:"type"`
+	Subject         string             `json:"subject,omitempty"` // Script name
+	Time            time.Time          `json:"time"`
+	DataContentType string             `json:"datacontenttype"`
+	Data            ExecutionEventData `json:"data"`
+}
+
+// ExecutionEventData is the data of execution events
+type ExecutionEventData struct {
+	ExecutionID string     `json:"executionId,omitempty"`
+	TrackingID  string     `json:"trackingId"`
+	ProcessID   int64      `json:"processId,omitempty"`
+	Script      string     `json:"script"`
+	Version     string     `json:"version,omitempty"`
+	Status      string     `json:"status,omitempty"` // SUCCESSFUL or FAILED; set on finished events
+	Caller      string     `json:"caller,omitempty"`
+	Pod         string     `json:"pod,omitempty"`
+	Output      string     `json:"output,omitempty"` // Only in callbacks; broker events omit it to stay within message size limits
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	DurationMs  int64      `json:"durationMs,omitempty"`
+}
+
+// newExecutionCloudEvent builds an execution event of eventType from the state of an execution.
+func newExecutionCloudEvent(config *Config, eventType string, completion completionEvent, includeOutput bool) cloudEvent {
+	now := time.Now()
+	data := ExecutionEventData{
+		ExecutionID: completion.ExecutionID,
+		TrackingID:  completion.TrackingID,
+		ProcessID:   completion.ProcessID,
+		Script:      completion.Script,
+		Version:     completion.Version,
+		Caller:      completion.Caller,
+		Pod:         completion.Pod,
+		StartedAt:   completion.Started.UTC(),
+	}
+	if eventType == executionFinishedEventType {
+		finished := completion.Started.Add(completion.Duration).UTC()
+		data.Status = completion.Status
+		data.Error = completion.Error
+		data.FinishedAt = &finished
+		data.DurationMs = completion.Duration.Milliseconds()
+		if includeOutput {
+			data.Output = completion.Output
+		}
+	}
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              newRecordID(),
+		Source:          config.EventsSource,
+		Type:            eventType,
+		Subject:         completion.Script,
+		Time:            now.UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// eventPublisher delivers execution events to a message broker
+type eventPublisher interface {
+	Publish(ctx context.Context, event cloudEvent) error
+	Close() error
+}
+
+// eventBus is the configured publisher, nil when publishing is off
+var eventBus eventPublisher
+
+// kafkaEventPublisher writes events to a Kafka topic
+type kafkaEventPublisher struct {
+	writer *kafka.Writer
+}
+
+// Publish writes the event in structured mode, keyed by script so events of one script stay
+// ordered within a partition.
+func (p *kafkaEventPublisher) Publish(ctx context.Context, event cloudEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(event.Subject),
+		Value:   value,
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte(cloudEventsContentType)}},
+	})
+}
+
+// Close flushes pending writes and closes the connections.
+func (p *kafkaEventPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// natsEventPublisher publishes events on a NATS subject
+type natsEventPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// Publish sends the event on the subject in structured mode.
+func (p *natsEventPublisher) Publish(ctx context.Context, event cloudEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.conn.Publish(p.subject, value)
+}
+
+// Close flushes buffered messages and closes the connection.
+func (p *natsEventPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// newEventPublisher creates the configured event publisher.
+func newEventPublisher(config *Config) (eventPublisher, error) {
+	switch config.EventsSink {
+	case eventsSinkOff, "":
+		return nil, nil
+	case eventsSinkKafka:
+		if len(config.EventsBrokers) == 0 {
+			return nil, fmt.Errorf("EVENTS_SINK=kafka requires EVENTS_BROKERS")
+		}
+		return &kafkaEventPublisher{writer: &kafka.Writer{
+			Addr:         kafka.TCP(config.EventsBrokers...),
+			Topic:        config.EventsTopic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 50 * time.Millisecond, // Events are rare; don't wait the default second for a batch
+			RequiredAcks: kafka.RequireAll,
+		}}, nil
+	case eventsSinkNATS:
+		if len(config.EventsBrokers) == 0 {
+			return nil, fmt.Errorf("EVENTS_SINK=nats requires EVENTS_BROKERS")
+		}
+		conn, err := nats.Connect(config.EventsBrokers[0], nats.Name("k8s-script-executor"), nats.MaxReconnects(-1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to NATS at '%s': %v", config.EventsBrokers[0], err)
+		}
+		return &natsEventPublisher{conn: conn, subject: config.EventsTopic}, nil
+	default:
+		return nil, fmt.Errorf("unknown EVENTS_SINK '%s' (supported: off, kafka, nats)", config.EventsSink)
+	}
+}
+
+// publishExecutionEvent publishes an execution event in the background, so a slow or unavailable
+// broker never delays an execution. Failures are logged.
+func publishExecutionEvent(config *Config, eventType string, completion completionEvent) {
+	if eventBus == nil {
+		return
+	}
+	event := newExecutionCloudEvent(config, eventType, completion, false)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), eventPublishTimeout)
+		defer cancel()
+		if err := eventBus.Publish(ctx, event); err != nil {
+			logger.Error().Str("trackingId", completion.TrackingID).Msgf("Failed to publish %s event for script '%s': %v", event.Type, completion.Script, err)
+		}
+	}()
+}
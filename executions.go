@@ -0,0 +1,193 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Limits of GET /v1/executions
+const (
+	executionsDefaultLimit = 100
+	executionsMaxLimit     = 1000
+)
+
+// listExecutions handles GET /v1/executions?script=&trackingId=&status=&category=&limit=, listing recorded
+// executions most recent first. Outputs are left out; fetch a single execution for its output.
+func listExecutions(c *gin.Context) {
+	if executionStore == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotEnabled, "Execution history is not enabled (HISTORY_DB_DSN)"))
+		return
+	}
+	query := executionQuery{
+		Script:     c.Query("script"),
+		TrackingID: c.Query("trackingId"),
+		Status:     strings.ToUpper(c.Query("status")),
+		Category:   c.Query("category"),
+		Limit:      executionsDefaultLimit,
+	}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 {
+			writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("invalid 'limit' '%s'", limit)))
+			return
+		}
+		query.Limit = parsed
+	}
+	if query.Limit > executionsMaxLimit {
+		query.Limit = executionsMaxLimit
+	}
+	visible, err := visibleScripts(configFromContext(c), callerFromContext(c))
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	query.Scripts = visible
+
+	records, err := executionStore.ListExecutions(query)
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	writeJSON(c, http.StatusOK, records)
+}
+
+// visibleExecution returns the recorded execution with the id if the caller's tag restriction admits
+// its script. Otherwise, or if there is no such execution, it returns nil: hidden executions are
+// answered like missing ones, so their existence doesn't leak.
+func visibleExecution(c *gin.Context, id string) (*ExecutionRecord, error) {
+	record, err := executionStore.GetExecution(id)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	visible, err := visibleScripts(configFromContext(c), callerFromContext(c))
+	if err != nil {
+		return nil, err
+	}
+	if visible != nil && !containsString(visible, record.Script) {
+		return nil, nil
+	}
+	return record, nil
+}
+
+// getExecution handles GET /v1/executions/:id, returning one execution including its output. The
+// id is an execution ID or a numeric process ID (the X-ProcessId of the execute response).
+func getExecution(c *gin.Context) {
+	if executionStore == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotEnabled, "Execution history is not enabled (HISTORY_DB_DSN)"))
+		return
+	}
+	record, err := visibleExecution(c, c.Param("id"))
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	if record == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotFound, fmt.Sprintf("Execution '%s' not found", c.Param("id"))))
+		return
+	}
+	writeJSON(c, http.StatusOK, record)
+}
+
+// getExecutionOutput handles GET /v1/executions/:id/output, serving the full output of an execution
+// as a gzip-compressed attachment. Tracking messages link here in the "attachment" output mode.
+func getExecutionOutput(c *gin.Context) {
+	if executionStore == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotEnabled, "Execution history is not enabled (HISTORY_DB_DSN)"))
+		return
+	}
+	record, err := visibleExecution(c, c.Param("id"))
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	if record == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotFound, fmt.Sprintf("Execution '%s' not found", c.Param("id"))))
+		return
+	}
+
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=output-%s.txt.gz", record.ID))
+	c.Status(http.StatusOK)
+	writer := gzip.NewWriter(c.Writer)
+	writer.Write([]byte(record.Output))
+	writer.Close()
+}
+
+// executionSide is one of the executions compared by GET /v1/executions/compare
+type executionSide struct {
+	ID        string `json:"id"`
+	TargetPod string `json:"targetPod,omitempty"`
+	Status    string `json:"status"`
+}
+
+// executionComparison is the response of GET /v1/executions/compare
+type executionComparison struct {
+	Script    string        `json:"script"`
+	A         executionSide `json:"a"`
+	B         executionSide `json:"b"`
+	Identical bool          `json:"identical"`
+	Diff      string        `json:"diff,omitempty"` // Unified diff from a's output to b's, empty when identical
+}
+
+// compareExecutions handles GET /v1/executions/compare?a=&b=, returning a unified diff of the
+// recorded outputs of two finished executions of the same script, e.g. to check that a re-run on
+// another pod produced the same result. a and b are execution IDs or numeric process IDs.
+func compareExecutions(c *gin.Context) {
+	if executionStore == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotEnabled, "Execution history is not enabled (HISTORY_DB_DSN)"))
+		return
+	}
+	var records [2]*ExecutionRecord
+	for i, param := range []string{"a", "b"} {
+		id := c.Query(param)
+		if id == "" {
+			writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("missing query parameter '%s'", param)))
+			return
+		}
+		record, err := visibleExecution(c, id)
+		if err != nil {
+			writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+			return
+		}
+		if record == nil {
+			writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotFound, fmt.Sprintf("Execution '%s' not found", id)))
+			return
+		}
+		if record.Status == executionStatusRunning {
+			writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Execution '%s' is still running", id)))
+			return
+		}
+		records[i] = record
+	}
+	a, b := records[0], records[1]
+	if a.Script != b.Script {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest,
+			fmt.Sprintf("Executions of different scripts can't be compared ('%s' and '%s')", a.Script, b.Script)))
+		return
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a.Output),
+		B:        difflib.SplitLines(b.Output),
+		FromFile: "a/" + a.ID,
+		ToFile:   "b/" + b.ID,
+		Context:  3,
+	})
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, fmt.Sprintf("Failed to diff outputs: %v", err)))
+		return
+	}
+	writeJSON(c, http.StatusOK, executionComparison{
+		Script:    a.Script,
+		A:         executionSide{ID: a.ID, TargetPod: a.TargetPod, Status: a.Status},
+		B:         executionSide{ID: b.ID, TargetPod: b.TargetPod, Status: b.Status},
+		Identical: a.Output == b.Output,
+		Diff:      diff,
+	})
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Executor backends, selected per script with "backend" or inferred from its fields
+const (
+	backendPodExec            = "podExec"            // kubectl exec into a selected workload pod (default)
+	backendJob                = "job"                // Short-lived pod built from the script's image
+	backendEphemeralContainer = "ephemeralContainer" // Debug container attached to a selected workload pod
+	backendNode               = "node"               // Privileged helper pod in the host namespaces of a node
+	backendTekton             = "tekton"             // PipelineRun of a Tekton Pipeline
+	backendLocal              = "local"              // The executor's own host (EXECUTOR_MODE=local)
+)
+
+// Executor runs scripts on one kind of target. Backends only run the command: target selection,
+// environment, tracking, history and the HTTP response stay with runTask, so a new kind of target
+// is added by implementing Executor and registering it in executors.
+type Executor interface {
+	// UsesWorkloadPod reports whether the backend runs in (or attaches to) a workload pod selected
+	// by the script's pod selectors, which runTask then selects before running it.
+	UsesWorkloadPod() bool
+	// Run executes the script and returns where it ran (a pod, helper pod or host) and the combined
+	// output. A non-zero exit is reported as an error.
+	Run(ctx context.Context, run *executorRun) (target, output string, err error)
+}
+
+// executorRun is one execution handed to an Executor
+type executorRun struct {
+	Config      *Config
+	Definition  *ScriptDefinition
+	ExecutionID string
+	TrackingID  string
+	Caller      string            // Name of the caller the execution runs on behalf of
+	Impersonate *Caller           // Identity pod execs impersonate; nil runs them as the executor
+	ProcessID   int64             // Process tracking ID, 0 when untracked
+	TargetPod   string            // Selected workload pod, for backends using one
+	Command     string            // Full shell command: environment prefix and expanded placeholders
+	Steps       []string          // Full command of each step, for multi-step scripts
+	Params      map[string]string // Declared parameter name -> value, for backends taking parameters directly
+	Env         map[string]string // Environment variable name -> value
+	Files       []paramFile       // Parameter values delivered as files, for backends in fileDeliveryBackends
+	Termination string            // How the backend stopped the script when ctx ended first, see termination*
+}
+
+// executors are the registered backends by name
+var executors = map[string]Executor{
+	backendPodExec:            podExecExecutor{},
+	backendJob:                jobExecutor{},
+	backendEphemeralContainer: ephemeralContainerExecutor{},
+	backendNode:               nodeExecutor{},
+	backendTekton:             tektonExecutor{},
+	backendLocal:              localExecutor{},
+	backendSSH:                sshExecutor{},
+}
+
+// inferBackend returns the backend a script's fields imply, for definitions without "backend".
+func inferBackend(def *ScriptDefinition) string {
+	switch {
+	case def.SSH != nil:
+		return backendSSH
+	case def.TektonPipeline != "":
+		return backendTekton
+	case isNodeTargeted(def):
+		return backendNode
+	case def.DebugImage != "":
+		return backendEphemeralContainer
+	case def.Image != "":
+		return backendJob
+	default:
+		return backendPodExec
+	}
+}
+
+// backendOf returns the backend running a script. In local mode, scripts exec'd into a workload
+// pod run on the executor's host instead.
+func backendOf(config *Config, def *ScriptDefinition) string {
+	backend := def.Backend
+	if backend == "" {
+		backend = inferBackend(def)
+	}
+	if config.ExecutorMode == executorModeLocal && backend == backendPodExec {
+		return backendLocal
+	}
+	return backend
+}
+
+// validateBackend checks the "backend" of a script definition: it must be registered and match the
+// fields the backend needs. Pipelines run other scripts and have no backend of their own.
+func validateBackend(def *ScriptDefinition) error {
+	if def.SSH != nil {
+		if err := validateSSHTarget(def); err != nil {
+			return err
+		}
+	}
+	if def.Backend == "" {
+		return nil
+	}
+	if len(def.Pipeline) > 0 {
+		return fmt.Errorf("pipelines run their nodes' backends and can't set one")
+	}
+	if _, ok := executors[def.Backend]; !ok {
+		return fmt.Errorf("unknown backend '%s'", def.Backend)
+	}
+	inferred := inferBackend(def)
+	if def.Backend == inferred || (def.Backend == backendLocal && inferred == backendPodExec) {
+		return nil
+	}
+	return fmt.Errorf("backend '%s' doesn't match the script's fields, which select '%s'", def.Backend, inferred)
+}
+
+// checkBackend rejects executions the configured executor mode can't run: local mode runs only
+// scripts exec'd into a workload pod, and the local backend only runs in local mode.
+func checkBackend(config *Config, def *ScriptDefinition) error {
+	if len(def.Pipeline) > 0 {
+		return nil
+	}
+	backend := backendOf(config, def)
+	if config.ExecutorMode == executorModeLocal && backend != backendLocal {
+		return fmt.Errorf("script '%s' runs on backend '%s', which EXECUTOR_MODE=local doesn't support", def.Name, backend)
+	}
+	if config.ExecutorMode != executorModeLocal && backend == backendLocal {
+		return fmt.Errorf("script '%s' uses backend 'local', which requires EXECUTOR_MODE=local", def.Name)
+	}
+	return nil
+}
+
+// podExecExecutor execs the command, or each step, into the selected workload pod.
+type podExecExecutor struct{}
+
+func (podExecExecutor) UsesWorkloadPod() bool { return true }
+
+func (podExecExecutor) Run(ctx context.Context, run *executorRun) (string, string, error) {
+	execIn := func(ctx context.Context) func(command string) (string, error) {
+		return func(command string) (string, error) {
+			return execInPodAs(ctx, run.Config.Namespace, run.TargetPod, command, run.Impersonate)
+		}
+	}
+	exec := execIn(ctx)
+	// Files are written on every run, so a retry on a fresh pod gets them too. They are removed
+	// even when the execution is cancelled.
+	cleanup, err := deliverParamFiles(ctx, run, func(file paramFile) error {
+		return writeFileInPod(run.Config.Namespace, run.TargetPod, run.Definition, file, run.Impersonate)
+	}, execIn(context.WithoutCancel(ctx)))
+	if err != nil {
+		return run.TargetPod, "", err
+	}
+	defer cleanup()
+	var output string
+	if len(run.Steps) > 0 {
+		executionLog(ctx).Info().Msgf("Executing %d steps of script '%s' in pod '%s'...", len(run.Steps), run.Definition.Name, run.TargetPod)
+		output, err = runSteps(ctx, run, exec)
+	} else {
+		if run.Impersonate != nil {
+			executionLog(ctx).Info().Msgf("Executing command for script '%s' in pod '%s' as '%s'...", run.Definition.Name, run.TargetPod, run.Impersonate.Name)
+		} else {
+			executionLog(ctx).Info().Msgf("Executing command for script '%s' in pod '%s'...", run.Definition.Name, run.TargetPod)
+		}
+		output, err = exec(run.Command)
+	}
+	if err != nil && ctx.Err() != nil {
+		// Killing kubectl leaves the script running in the pod
+		run.Termination = terminateInPod(ctx, run)
+	}
+	return run.TargetPod, output, err
+}
+
+// jobExecutor runs the command in a short-lived pod built from the script's image.
+type jobExecutor struct{}
+
+func (jobExecutor) UsesWorkloadPod() bool { return false }
+
+func (jobExecutor) Run(ctx context.Context, run *executorRun) (string, string, error) {
+	executionLog(ctx).Info().Msgf("Executing command for script '%s' in a dedicated pod (image: %s)...", run.Definition.Name, run.Definition.Image)
+	return runInDedicatedPod(ctx, run)
+}
+
+// ephemeralContainerExecutor runs the command in a debug container attached to the selected pod.
+type ephemeralContainerExecutor struct{}
+
+func (ephemeralContainerExecutor) UsesWorkloadPod() bool { return true }
+
+func (ephemeralContainerExecutor) Run(ctx context.Context, run *executorRun) (string, string, error) {
+	executionLog(ctx).Info().Msgf("Executing command for script '%s' in an ephemeral container (image: %s) of pod '%s'...", run.Definition.Name, run.Definition.DebugImage, run.TargetPod)
+	output, err := runInEphemeralContainer(run.Config, run.Definition, run.TargetPod, run.ExecutionID, run.Command)
+	return run.TargetPod, output, err
+}
+
+// nodeExecutor runs the command on a node through a privileged helper pod.
+type nodeExecutor struct{}
+
+func (nodeExecutor) UsesWorkloadPod() bool { return false }
+
+func (nodeExecutor) Run(ctx context.Context, run *executorRun) (string, string, error) {
+	nodeName, err := resolveNodeName(run.Definition.NodeName, run.Env)
+	if err != nil {
+		return "", "", err
+	}
+	executionLog(ctx).Info().Msgf("Executing command for script '%s' on node '%s' (selector: %v) via helper pod...", run.Definition.Name, nodeName, run.Definition.NodeSelector)
+	return runOnNode(ctx, run, nodeName)
+}
+
+// tektonExecutor runs the script as a PipelineRun, passing the parameters directly.
+type tektonExecutor struct{}
+
+func (tektonExecutor) UsesWorkloadPod() bool { return false }
+
+func (tektonExecutor) Run(ctx context.Context, run *executorRun) (string, string, error) {
+	executionLog(ctx).Info().Msgf("Executing script '%s' as Tekton Pipeline '%s'...", run.Definition.Name, run.Definition.TektonPipeline)
+	return runTektonPipeline(run)
+}
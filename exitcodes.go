@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// validateExitCodes checks the "exitCodes" field of a script definition: non-zero exit codes
+// mapped to WARNING or SKIPPED. Pipelines and Tekton PipelineRuns have no exit code to map.
+func validateExitCodes(def *ScriptDefinition) error {
+	if len(def.ExitCodes) == 0 {
+		return nil
+	}
+	if len(def.Pipeline) > 0 || def.TektonPipeline != "" {
+		return fmt.Errorf("'exitCodes' can't be combined with 'pipeline' or 'tektonPipeline'")
+	}
+	for code, status := range def.ExitCodes {
+		if code < 1 || code > 255 {
+			return fmt.Errorf("exit code %d is not between 1 and 255", code)
+		}
+		if status != executionStatusWarning && status != executionStatusSkipped {
+			return fmt.Errorf("exit code %d maps to unsupported status '%s' (supported: WARNING, SKIPPED)", code, status)
+		}
+	}
+	return nil
+}
+
+// mappedTrackingStatuses are the process tracking statuses PROCESS_TRACKING_WARNING_STATUS and
+// PROCESS_TRACKING_SKIPPED_STATUS may name. Services that don't know WARNING or SKIPPED get
+// SUCCESSFUL by default.
+var mappedTrackingStatuses = []string{executionStatusSuccessful, executionStatusFailed, executionStatusWarning, executionStatusSkipped}
+
+// mappedTrackingStatus returns the status process tracking gets for an execution whose exit code
+// mapped to WARNING or SKIPPED.
+func mappedTrackingStatus(config *Config, mappedStatus string) string {
+	if mappedStatus == executionStatusSkipped {
+		return config.ProcessTrackingSkippedStatus
+	}
+	return config.ProcessTrackingWarningStatus
+}
+
+// mappedExitStatus returns the status a script maps the exit code of a failed execution to, with
+// the exit code, or "" if the script didn't exit with a mapped code.
+func mappedExitStatus(def *ScriptDefinition, err error) (string, int) {
+	if err == nil || len(def.ExitCodes) == 0 {
+		return "", 0
+	}
+	code, convErr := strconv.Atoi(exitCodeOf(err.Error()))
+	if convErr != nil {
+		return "", 0
+	}
+	return def.ExitCodes[code], code
+}
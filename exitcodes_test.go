@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMappedExitStatus(t *testing.T) {
+	def := &ScriptDefinition{Name: "purge-exports", ExitCodes: map[int]string{3: executionStatusSkipped, 4: executionStatusWarning}}
+	tests := []struct {
+		name     string
+		def      *ScriptDefinition
+		err      error
+		wantCode int
+		want     string
+	}{
+		{"success", def, nil, 0, ""},
+		{"mapped to SKIPPED", def, errors.New("exit status 3"), 3, executionStatusSkipped},
+		{"mapped to WARNING", def, errors.New("exit status 4"), 4, executionStatusWarning},
+		{"unmapped code", def, errors.New("exit status 1"), 1, ""},
+		{"no exit code", def, errors.New("error dialing backend: connection refused"), 0, ""},
+		{"no mapping", &ScriptDefinition{Name: "restore"}, errors.New("exit status 3"), 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := mappedExitStatus(tt.def, tt.err)
+			if status != tt.want || (status != "" && code != tt.wantCode) {
+				t.Errorf("mappedExitStatus(%v) = %q, %d; want %q, %d", tt.err, status, code, tt.want, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateExitCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		def     ScriptDefinition
+		wantErr bool
+	}{
+		{"none", ScriptDefinition{}, false},
+		{"valid", ScriptDefinition{ExitCodes: map[int]string{3: "SKIPPED", 255: "WARNING"}}, false},
+		{"zero", ScriptDefinition{ExitCodes: map[int]string{0: "WARNING"}}, true},
+		{"above 255", ScriptDefinition{ExitCodes: map[int]string{256: "WARNING"}}, true},
+		{"unsupported status", ScriptDefinition{ExitCodes: map[int]string{3: "SUCCESSFUL"}}, true},
+		{"lowercase status", ScriptDefinition{ExitCodes: map[int]string{3: "skipped"}}, true},
+		{"pipeline", ScriptDefinition{ExitCodes: map[int]string{3: "SKIPPED"}, Pipeline: []PipelineNode{{Script: "a"}}}, true},
+		{"tekton", ScriptDefinition{ExitCodes: map[int]string{3: "SKIPPED"}, TektonPipeline: "build"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateExitCodes(&tt.def); (err != nil) != tt.wantErr {
+				t.Errorf("validateExitCodes() = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMappedTrackingStatus(t *testing.T) {
+	defaults := &Config{ProcessTrackingWarningStatus: executionStatusSuccessful, ProcessTrackingSkippedStatus: executionStatusSuccessful}
+	native := &Config{ProcessTrackingWarningStatus: executionStatusWarning, ProcessTrackingSkippedStatus: executionStatusSkipped}
+	tests := []struct {
+		config *Config
+		mapped string
+		want   string
+	}{
+		{defaults, executionStatusWarning, executionStatusSuccessful},
+		{defaults, executionStatusSkipped, executionStatusSuccessful},
+		{native, executionStatusWarning, executionStatusWarning},
+		{native, executionStatusSkipped, executionStatusSkipped},
+	}
+	for _, tt := range tests {
+		if got := mappedTrackingStatus(tt.config, tt.mapped); got != tt.want {
+			t.Errorf("mappedTrackingStatus(%+v, %s) = %s, want %s", tt.config, tt.mapped, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Categories of failed executions, recorded on the execution, returned in the problem response and
+// counted by script_executor_failures_total, so retries and alerts can tell transient
+// infrastructure errors from genuine script failures
+const (
+	failurePodUnavailable = "pod-unavailable" // No target pod (node, VM), or it couldn't be reached, created or started
+	failurePermission     = "permission"      // The executor, or the caller it impersonates, isn't allowed to run the script
+	failureTimeout        = "timeout"         // The execution outlasted its request or pod timeout
+	failureScriptError    = "script-error"    // The script ran and exited non-zero
+	failureTrackingError  = "tracking-error"  // The process tracking record couldn't be created
+	failureCancelled      = "cancelled"       // Cancelled, abandoned by its client or stopped by a shutdown
+)
+
+// permissionErrorPatterns are (lowercase) signs of a failure the Kubernetes API or kubectl refused
+var permissionErrorPatterns = []string{
+	"forbidden",
+	"unauthorized",
+	"cannot create resource",
+	"cannot get resource",
+	"cannot list resource",
+}
+
+// timeoutErrorPatterns are (lowercase) signs of a backend giving up waiting, e.g. for a dedicated pod
+var timeoutErrorPatterns = []string{
+	"timed out",
+	"deadline exceeded",
+}
+
+// kubectlExitPattern matches the line kubectl exec ends with when the remote script exited non-zero
+var kubectlExitPattern = regexp.MustCompile(`(?m)^command terminated with exit code \d+\s*$`)
+
+// kubectlErrorPattern matches the lines kubectl writes about its own failures, as opposed to the
+// output of the script it runs: "error: ...", "Error from server (...)", "Unable to connect to the
+// server" and klog error/warning lines such as "E1015 10:00:00.123456 ...".
+var kubectlErrorPattern = regexp.MustCompile(`(?m)^(error: |Error from server|Unable to connect to the server|[EW]\d{4} \d\d:\d\d:\d\d\.\d+ ).*$`)
+
+// containsAny reports whether text contains one of the patterns.
+func containsAny(text string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(text, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyFailure returns the category of a failed execution from the error and output of its
+// backend. A script stopped with its context is a timeout when the request's deadline passed and
+// cancelled otherwise. The script's exit status is checked first: kubectl reports the remote
+// script's exit as "command terminated with exit code", other backends as "exit status N" (kubectl's
+// own exit status means nothing about the script). Only then are the error and, for pod execs,
+// kubectl's own error lines matched against infrastructure failures, never the script's output,
+// which may mention "forbidden" or "timed out" on its own.
+func classifyFailure(ctx context.Context, backend, output string, err error) string {
+	if ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return failureTimeout
+		}
+		return failureCancelled
+	}
+	infrastructure := err.Error()
+	if backend == backendPodExec {
+		if kubectlExitPattern.MatchString(output) {
+			return failureScriptError
+		}
+		infrastructure += "\n" + strings.Join(kubectlErrorPattern.FindAllString(output, -1), "\n")
+	} else if exitCodeOf(err.Error()) != "unknown" {
+		return failureScriptError
+	}
+	infrastructure = strings.ToLower(infrastructure)
+	switch {
+	case containsAny(infrastructure, permissionErrorPatterns):
+		return failurePermission
+	case containsAny(infrastructure, transientExecErrorPatterns) || podNotFoundPattern.MatchString(infrastructure):
+		// Lost contact with the pod, including API timeouts, rather than a backend giving up waiting
+		return failurePodUnavailable
+	case containsAny(infrastructure, timeoutErrorPatterns):
+		return failureTimeout
+	default:
+		return failurePodUnavailable
+	}
+}
+
+// classifyPodSelectionFailure returns the category of an execution that found no target pod.
+func classifyPodSelectionFailure(err error) string {
+	if containsAny(strings.ToLower(err.Error()), permissionErrorPatterns) {
+		return failurePermission
+	}
+	return failurePodUnavailable
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		output  string
+		err     error
+		want    string
+	}{
+		{"pod exec script exit", backendPodExec, "restoring...\ncommand terminated with exit code 2\n", errors.New("exit status 2"), failureScriptError},
+		{"script printing forbidden", backendPodExec, "Error: forbidden table\ncommand terminated with exit code 1\n", errors.New("exit status 1"), failureScriptError},
+		{"script printing timed out", backendPodExec, "query timed out after 30s\ncommand terminated with exit code 1\n", errors.New("exit status 1"), failureScriptError},
+		{"kubectl forbidden", backendPodExec, `Error from server (Forbidden): pods "query-server-abc" is forbidden: User "system:serviceaccount:default:executor" cannot create resource "pods/exec"` + "\n", errors.New("exit status 1"), failurePermission},
+		{"pod gone", backendPodExec, `Error from server (NotFound): pods "query-server-abc" not found` + "\n", errors.New("exit status 1"), failurePodUnavailable},
+		{"connection refused", backendPodExec, "error: unable to upgrade connection: dial tcp 10.0.0.7:10250: connect: connection refused\n", errors.New("exit status 1"), failurePodUnavailable},
+		{"api timeout is transient", backendPodExec, "Unable to connect to the server: net/http: TLS handshake timeout\n", errors.New("exit status 1"), failurePodUnavailable},
+		{"forbidden in script output only", backendPodExec, "access forbidden\n", errors.New("exit status 1"), failurePodUnavailable},
+		{"job script exit", backendJob, "permission denied: forbidden\n", errors.New("exit status 3"), failureScriptError},
+		{"job pod timed out", backendJob, "", errors.New("timed out waiting for pod script-abc to start"), failureTimeout},
+		{"job forbidden", backendJob, "", errors.New(`pods is forbidden: User "executor" cannot create resource "pods"`), failurePermission},
+		{"job output ignored", backendJob, "request timed out", errors.New("failed to create pod: connection refused"), failurePodUnavailable},
+		{"ssh exit", backendSSH, "", errors.New("exit status 255"), failureScriptError},
+		{"ssh dial", backendSSH, "", errors.New("dial tcp 10.0.0.9:22: connect: connection refused"), failurePodUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyFailure(context.Background(), tt.backend, tt.output, tt.err); got != tt.want {
+				t.Errorf("classifyFailure(%s, %q, %v) = %s, want %s", tt.backend, tt.output, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyFailureStoppedContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	// A stopped execution is cancelled or timed out whatever the script printed
+	output := "command terminated with exit code 137\n"
+	if got := classifyFailure(cancelled, backendPodExec, output, errors.New("signal: killed")); got != failureCancelled {
+		t.Errorf("cancelled context: got %s, want %s", got, failureCancelled)
+	}
+	if got := classifyFailure(expired, backendPodExec, output, errors.New("signal: killed")); got != failureTimeout {
+		t.Errorf("expired context: got %s, want %s", got, failureTimeout)
+	}
+}
+
+func TestClassifyPodSelectionFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{errors.New(`pods is forbidden: User "executor" cannot list resource "pods"`), failurePermission},
+		{errors.New("no Ready pod matches selector app=query-server"), failurePodUnavailable},
+	}
+	for _, tt := range tests {
+		if got := classifyPodSelectionFailure(tt.err); got != tt.want {
+			t.Errorf("classifyPodSelectionFailure(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
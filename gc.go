@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// startResourceReaper periodically deletes resources the executor created and failed to clean up,
+// e.g. pods left behind by a crash or a failed delete, once they are older than RESOURCE_TTL_SECONDS.
+// Every replica runs it; deletes of already deleted resources are ignored.
+func startResourceReaper(config *Config) {
+	if kubeClient == nil || config.ResourceTTL <= 0 {
+		return
+	}
+	go func() {
+		for {
+			reapResources(context.Background(), currentConfig())
+			time.Sleep(config.ResourceGCInterval)
+		}
+	}()
+	logger.Info().Msgf("[GC] Resource reaper started (namespace: %s, TTL: %s, interval: %s).", config.Namespace, config.ResourceTTL, config.ResourceGCInterval)
+}
+
+// reapResources deletes the expired pods, PipelineRuns and released Leases the executor created.
+func reapResources(ctx context.Context, config *Config) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	deleted := 0
+	for _, reap := range []func(context.Context, *Config) (int, error){reapPods, reapPipelineRuns, reapLeases} {
+		count, err := reap(ctx, config)
+		deleted += count
+		if err != nil {
+			logger.Warn().Msgf("[GC] %v", err)
+		}
+	}
+	if deleted > 0 {
+		logger.Info().Msgf("[GC] Deleted %d expired resource(s).", deleted)
+	}
+}
+
+// reapPods deletes dedicated and node helper pods that finished more than the TTL ago, and those
+// still running past their timeout plus the TTL. Pods owned by another object (e.g. the Jobs of
+// scheduling CronJobs, which keep a bounded history) are left to their owner.
+func reapPods(ctx context.Context, config *Config) (int, error) {
+	pods := kubeClient.CoreV1().Pods(config.Namespace)
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: managedSelector})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pods: %v", err)
+	}
+	deleted := 0
+	for _, pod := range list.Items {
+		if len(pod.OwnerReferences) > 0 || pod.DeletionTimestamp != nil {
+			continue
+		}
+		age := time.Since(pod.CreationTimestamp.Time)
+		finished := pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+		if age < config.ResourceTTL || (!finished && age < config.DedicatedPodTimeout+config.ResourceTTL) {
+			continue
+		}
+		if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			logger.Warn().Msgf("[GC] Failed to delete pod '%s': %v", pod.Name, err)
+			continue
+		}
+		logger.Info().Msgf("[GC] Deleted pod '%s' (phase: %s, age: %s).", pod.Name, pod.Status.Phase, age.Round(time.Second))
+		deleted++
+	}
+	return deleted, nil
+}
+
+// reapPipelineRuns deletes finished PipelineRuns older than the TTL, in NAMESPACE and the
+// tektonNamespace of every script. Clusters without Tekton are skipped.
+func reapPipelineRuns(ctx context.Context, config *Config) (int, error) {
+	if kubeDynamicClient == nil {
+		return 0, nil
+	}
+	namespaces := []string{config.Namespace}
+	if registry := lastValidScripts.Load(); registry != nil {
+		for _, def := range registry.definitions {
+			if def.TektonNamespace != "" && !containsString(namespaces, def.TektonNamespace) {
+				namespaces = append(namespaces, def.TektonNamespace)
+			}
+		}
+	}
+	deleted := 0
+	for _, namespace := range namespaces {
+		pipelineRuns := kubeDynamicClient.Resource(pipelineRunResource).Namespace(namespace)
+		list, err := pipelineRuns.List(ctx, metav1.ListOptions{LabelSelector: managedSelector})
+		if apierrors.IsNotFound(err) {
+			return deleted, nil // Tekton isn't installed
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to list PipelineRuns in namespace '%s': %v", namespace, err)
+		}
+		for _, run := range list.Items {
+			completion, _, _ := unstructured.NestedString(run.Object, "status", "completionTime")
+			completed, err := time.Parse(time.RFC3339, completion)
+			if err != nil || time.Since(completed) < config.ResourceTTL {
+				continue
+			}
+			if err := pipelineRuns.Delete(ctx, run.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				logger.Warn().Msgf("[GC] Failed to delete PipelineRun '%s/%s': %v", namespace, run.GetName(), err)
+				continue
+			}
+			logger.Info().Msgf("[GC] Deleted PipelineRun '%s/%s' (completed: %s).", namespace, run.GetName(), completion)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// reapLeases deletes the Leases of exclusive scripts that have been released, or whose holder
+// stopped renewing them, for longer than the TTL.
+func reapLeases(ctx context.Context, config *Config) (int, error) {
+	leases := kubeClient.CoordinationV1().Leases(config.Namespace)
+	list, err := leases.List(ctx, metav1.ListOptions{LabelSelector: managedSelector})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list Leases: %v", err)
+	}
+	deleted := 0
+	for i := range list.Items {
+		lease := &list.Items[i]
+		if leaseHolder(lease) != "" && !leaseExpired(lease) {
+			continue
+		}
+		lastUsed := lease.CreationTimestamp.Time
+		if lease.Spec.RenewTime != nil {
+			lastUsed = lease.Spec.RenewTime.Time
+		}
+		if time.Since(lastUsed) < config.ResourceTTL {
+			continue
+		}
+		// Deleting only this version of the Lease, so a replica taking it meanwhile keeps it
+		preconditions := metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &lease.ResourceVersion}}
+		err := leases.Delete(ctx, lease.Name, preconditions)
+		if apierrors.IsConflict(err) {
+			continue
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			logger.Warn().Msgf("[GC] Failed to delete Lease '%s': %v", lease.Name, err)
+			continue
+		}
+		logger.Info().Msgf("[GC] Deleted Lease '%s' (last renewed: %s).", lease.Name, lastUsed.UTC().Format(time.RFC3339))
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessCheckTimeout bounds each dependency check of the readiness probe
+const readinessCheckTimeout = 3 * time.Second
+
+// livezHandler handles the /livez endpoint: the process is up and serving requests. It checks no
+// dependency, so a broken dependency makes the replica unready instead of restarting it.
+func livezHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyzHandler handles the /readyz endpoint. The replica is ready when its script definitions
+// can be read, the Kubernetes API is reachable and the required permissions are still granted in
+// every namespace scripts use, and, with READINESS_CHECK_TRACKING=true, the process tracking
+// service answers. Each check is reported with
+// "ok" or its error; any failure answers 503 so Kubernetes stops routing to the replica.
+func readyzHandler(c *gin.Context) {
+	config := configFromContext(c)
+	ctx := c.Request.Context()
+	checks := gin.H{}
+	ready := true
+	record := func(name string, err error) {
+		if err != nil {
+			checks[name] = err.Error()
+			ready = false
+			return
+		}
+		checks[name] = "ok"
+	}
+
+	// A missing or unreadable file fails the check; invalid definitions only when no earlier
+	// valid set is there to fall back to
+	_, _, invalid, err := readScriptDefinitions(config.ScriptsPath)
+	if err == nil && len(invalid) > 0 {
+		_, err = loadScriptDefinitions(config.ScriptsPath)
+	}
+	record("definitions", err)
+	if config.ExecutorMode == executorModeLocal && kubeClient == nil {
+		// Local mode without a cluster: scripts run on this host
+		checks["kubernetes"] = "skipped (EXECUTOR_MODE=local)"
+	} else {
+		record("kubernetes", checkKubernetesAPI(ctx))
+	}
+	if checks["kubernetes"] == "ok" && !config.SkipPermissionCheck {
+		// "permissions" covers NAMESPACE, "permissions/<namespace>" the other namespaces scripts use
+		for _, result := range currentPermissions(ctx, config) {
+			name := "permissions"
+			if result.Namespace != config.Namespace {
+				name += "/" + result.Namespace
+			}
+			record(name, result.Err)
+		}
+	}
+	if sharedState != nil {
+		record("redis", checkSharedState(ctx))
+	}
+	if config.ReadinessCheckTracking && config.ProcessTrackingURL != "" {
+		record("processTracking", checkProcessTrackingService(ctx, config))
+	}
+
+	if !ready {
+		logger.Warn().Interface("checks", checks).Msg("Readiness check failed.")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": checks})
+}
+
+// withTimeout runs check with a context bounded by readinessCheckTimeout.
+func withTimeout(ctx context.Context, check func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+	return check(ctx)
+}
+
+// checkKubernetesAPI verifies the API server answers its own readiness endpoint.
+func checkKubernetesAPI(ctx context.Context) error {
+	if kubeClient == nil {
+		return fmt.Errorf("kubernetes client not initialized")
+	}
+	return withTimeout(ctx, func(ctx context.Context) error {
+		return kubeClient.Discovery().RESTClient().Get().AbsPath("/readyz").Do(ctx).Error()
+	})
+}
+
+// checkProcessTrackingService verifies the process tracking service answers; any response below
+// 500 counts, as the service URL itself may not support GET.
+func checkProcessTrackingService(ctx context.Context, config *Config) error {
+	return withTimeout(ctx, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.ProcessTrackingURL, nil)
+		if err != nil {
+			return err
+		}
+		setProcessTrackingAuth(req, config)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("process tracking service returned status %d", resp.StatusCode)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// journalEntry is an execution of this executor that started and hasn't finished yet
+type journalEntry struct {
+	ExecutionID string    `json:"executionId"` // Also the EXECUTION_ID the script's processes carry
+	ProcessID   int64     `json:"processId,omitempty"`
+	Script      string    `json:"script"`
+	Backend     string    `json:"backend"`
+	Namespace   string    `json:"namespace"`
+	Pod         string    `json:"pod,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+}
+
+// executionJournal journals the executions in flight, so that after a crash or kill the next start
+// of the executor can reconcile them instead of leaving their tracking records in PROGRESS. Like the
+// tracking outbox, it is a JSON file rewritten atomically on every change.
+type executionJournal struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]journalEntry // By execution ID
+}
+
+// inFlightJournal is the journal of executions in flight, nil when EXECUTION_JOURNAL_PATH is not set
+var inFlightJournal *executionJournal
+
+// openExecutionJournal loads the journal at path, starting empty if it doesn't exist yet.
+func openExecutionJournal(path string) (*executionJournal, error) {
+	journal := &executionJournal{path: path, entries: make(map[string]journalEntry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return journal, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution journal '%s': %v", path, err)
+	}
+	if len(data) > 0 {
+		var entries []journalEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse execution journal '%s': %v", path, err)
+		}
+		for _, entry := range entries {
+			journal.entries[entry.ExecutionID] = entry
+		}
+	}
+	return journal, nil
+}
+
+// record journals a starting execution.
+func (j *executionJournal) record(entry journalEntry) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries[entry.ExecutionID] = entry
+	j.saveOrLog()
+}
+
+// setPod records the pod an execution moved to, e.g. when retried on a fresh pod.
+func (j *executionJournal) setPod(executionID, pod string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if entry, ok := j.entries[executionID]; ok && entry.Pod != pod {
+		entry.Pod = pod
+		j.entries[executionID] = entry
+		j.saveOrLog()
+	}
+}
+
+// remove drops a finished execution from the journal.
+func (j *executionJournal) remove(executionID string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.entries[executionID]; ok {
+		delete(j.entries, executionID)
+		j.saveOrLog()
+	}
+}
+
+// list returns the journaled executions, oldest first.
+func (j *executionJournal) list() []journalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries := make([]journalEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].StartedAt.Before(entries[b].StartedAt) })
+	return entries
+}
+
+// saveOrLog rewrites the journal, logging failures: an execution mustn't fail because it couldn't
+// be journaled. The caller holds mu.
+func (j *executionJournal) saveOrLog() {
+	entries := make([]journalEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		entries = append(entries, entry)
+	}
+	data, err := json.Marshal(entries)
+	if err == nil {
+		err = writeFileAtomically(j.path, data)
+	}
+	if err != nil {
+		logger.Error().Msgf("[Journal] Failed to write execution journal '%s': %v", j.path, err)
+	}
+}
+
+// startExecutionJournal opens the journal at EXECUTION_JOURNAL_PATH and reconciles the executions
+// a previous run of the executor left in it. It does nothing when the path is not set.
+func startExecutionJournal(config *Config) error {
+	if config.ExecutionJournalPath == "" {
+		return nil
+	}
+	journal, err := openExecutionJournal(config.ExecutionJournalPath)
+	if err != nil {
+		return err
+	}
+	inFlightJournal = journal
+	orphans := journal.list()
+	logger.Info().Msgf("[Journal] Execution journal at '%s' holds %d execution(s) of a previous run.", journal.path, len(orphans))
+	for _, entry := range orphans {
+		go recoverExecution(config, entry)
+	}
+	return nil
+}
+
+// markedProcessesCommand lists the PIDs of the processes of a pod whose environment or command line
+// carries EXECUTION_ID=<executionID>. The bracket keeps the command from finding itself.
+func markedProcessesCommand(executionID string) string {
+	return fmt.Sprintf(`grep -ls "EXECUTION_I[D]=[\"']\?%s" /proc/[0-9]*/environ /proc/[0-9]*/cmdline 2>/dev/null | cut -d/ -f3 | sort -u`, executionID)
+}
+
+// isExecutionRunning reports whether a process of the execution still runs in its pod.
+func isExecutionRunning(entry journalEntry) (bool, error) {
+	output, err := execInPod(entry.Namespace, entry.Pod, markedProcessesCommand(entry.ExecutionID))
+	if err != nil {
+		return false, fmt.Errorf("%v: %s", err, strings.TrimSpace(output))
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// recoverExecution reconciles an execution left in the journal by a previous run. A pod exec
+// whose process still runs in its pod is re-attached: its tracking record stays in PROGRESS until
+// the process is gone. Every other execution is recorded as FAILED with a restart notice; its
+// output and exit code are lost with the previous run.
+func recoverExecution(config *Config, entry journalEntry) {
+	elog := logger.With().Str("executionId", entry.ExecutionID).Int64("processId", entry.ProcessID).Str("script", entry.Script).Logger()
+	finish := func(reason string) {
+		elog.Warn().Msgf("[Journal] %s", reason)
+		executionStore.RecordFinish(entry.ExecutionID, entry.Pod, executionStatusFailed, "", reason)
+		notifyProcessTrackingUpdate(context.Background(), config, entry.ProcessID, ProcessTrackingUpdatePayload{
+			Status:  "FAILED",
+			Message: reason,
+		})
+		inFlightJournal.remove(entry.ExecutionID)
+	}
+
+	if entry.Backend != backendPodExec || entry.Pod == "" {
+		finish(fmt.Sprintf("The executor restarted before script '%s' finished; its outcome is unknown", entry.Script))
+		return
+	}
+	running, err := isExecutionRunning(entry)
+	if err != nil || !running {
+		if err != nil {
+			elog.Warn().Msgf("[Journal] Can't check pod '%s' for the execution: %v", entry.Pod, err)
+		}
+		finish(fmt.Sprintf("The executor restarted before script '%s' finished; it no longer runs in pod '%s'", entry.Script, entry.Pod))
+		return
+	}
+
+	elog.Info().Msgf("[Journal] Script '%s' still runs in pod '%s' after the executor restarted; re-attaching.", entry.Script, entry.Pod)
+	notifyProcessTrackingUpdate(context.Background(), config, entry.ProcessID, ProcessTrackingUpdatePayload{
+		Status:  "PROGRESS",
+		Message: fmt.Sprintf("The executor restarted; script still running in pod %s, watching it", entry.Pod),
+	})
+	for {
+		time.Sleep(config.ExecutionJournalPollInterval)
+		running, err := isExecutionRunning(entry)
+		if err == nil && running {
+			continue
+		}
+		if err != nil {
+			elog.Warn().Msgf("[Journal] Can't check pod '%s' for the execution: %v", entry.Pod, err)
+		}
+		finish(fmt.Sprintf("Script '%s' ended in pod '%s' after the executor restarted; its output and exit code are unknown", entry.Script, entry.Pod))
+		return
+	}
+}
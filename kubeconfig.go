@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// applyServerFlags applies the command-line flags of the server, which take precedence over the
+// environment. They are settings that only matter at startup, so a configuration reload keeps them.
+func applyServerFlags(config *Config, args []string) error {
+	flags := flag.NewFlagSet("k8s-script-executor", flag.ContinueOnError)
+	flags.StringVar(&config.Kubeconfig, "kubeconfig", config.Kubeconfig,
+		"Path of a kubeconfig to run outside the cluster (default: $KUBECONFIG, else the in-cluster config)")
+	flags.BoolVar(&config.SkipPermissionCheck, "skip-permission-check", config.SkipPermissionCheck,
+		"Skip the RBAC permission checks at startup and in /readyz (SKIP_PERMISSION_CHECK)")
+	return flags.Parse(args)
+}
+
+// kubernetesRESTConfig returns the configuration of the Kubernetes clients: the kubeconfig of
+// KUBECONFIG/--kubeconfig (with its current context) when set, the in-cluster ServiceAccount
+// otherwise. kubectl, which executes the scripts, is pointed at the same kubeconfig.
+func kubernetesRESTConfig(config *Config) (*rest.Config, error) {
+	if config.Kubeconfig == "" {
+		k8sConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get in-cluster Kubernetes config (set KUBECONFIG or --kubeconfig to run outside a cluster): %v", err)
+		}
+		return k8sConfig, nil
+	}
+
+	k8sConfig, err := clientcmd.BuildConfigFromFlags("", config.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig '%s': %v", config.Kubeconfig, err)
+	}
+	// kubectl reads KUBECONFIG itself; this covers --kubeconfig
+	if err := os.Setenv("KUBECONFIG", config.Kubeconfig); err != nil {
+		return nil, err
+	}
+	logger.Warn().Msgf("Using kubeconfig '%s' (API server %s) instead of the in-cluster config.", config.Kubeconfig, k8sConfig.Host)
+	return k8sConfig, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Labels and annotations attributing created resources and execution records to the script,
+// execution and caller behind them, for cost tooling and kubectl selectors. Label values are
+// sanitized; the annotations of the same keys keep the full values.
+const (
+	scriptLabel      = "executor.decloudz.io/script"
+	executionIDLabel = "executor.decloudz.io/execution-id"
+	trackingIDLabel  = "executor.decloudz.io/tracking-id"
+	requestedByLabel = "executor.decloudz.io/requested-by"
+)
+
+// managedSelector selects the resources the executor created
+const managedSelector = managedByLabel + "=" + managedByValue
+
+// labelValueInvalidChars matches the characters Kubernetes label values can't hold
+var labelValueInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// labelValue turns a value into a valid label value: invalid characters become "_", and it is cut
+// to 63 characters and trimmed to start and end with an alphanumeric character.
+func labelValue(value string) string {
+	value = labelValueInvalidChars.ReplaceAllString(value, "_")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "_.-")
+}
+
+// scriptLabels are the labels of resources belonging to a script rather than one execution,
+// such as the CronJob scheduling it or the Lease guarding it.
+func scriptLabels(def *ScriptDefinition) map[string]string {
+	labels := map[string]string{
+		managedByLabel: managedByValue,
+		scriptIDLabel:  dnsLabelInvalidChars.ReplaceAllString(strings.ToLower(def.ID), "-"),
+	}
+	if script := labelValue(def.Name); script != "" {
+		labels[scriptLabel] = script
+	}
+	return labels
+}
+
+// executionLabels are the labels of an execution: its script's, plus its execution ID, tracking
+// ID and the caller it runs on behalf of. Empty values are left out.
+func executionLabels(def *ScriptDefinition, executionID, trackingID, caller string) map[string]string {
+	labels := scriptLabels(def)
+	for key, value := range map[string]string{executionIDLabel: executionID, trackingIDLabel: trackingID, requestedByLabel: caller} {
+		if value = labelValue(value); value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// executionAnnotations hold the unsanitized script name, tracking ID and caller of an execution.
+func executionAnnotations(def *ScriptDefinition, trackingID, caller string) map[string]string {
+	annotations := map[string]string{scriptNameAnnotation: def.Name}
+	if trackingID != "" {
+		annotations[trackingIDLabel] = trackingID
+	}
+	if caller != "" {
+		annotations[requestedByLabel] = caller
+	}
+	return annotations
+}
+
+// ownerLabels are the labels of every resource an execution creates, by which the reaper finds them.
+func ownerLabels(run *executorRun) map[string]string {
+	return executionLabels(run.Definition, run.ExecutionID, run.TrackingID, run.Caller)
+}
+
+// ownerAnnotations are the annotations of every resource an execution creates.
+func ownerAnnotations(run *executorRun) map[string]string {
+	return executionAnnotations(run.Definition, run.TrackingID, run.Caller)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// scriptLockedError reports an exclusive script already running elsewhere
+type scriptLockedError struct {
+	Script string
+	Holder string
+}
+
+func (e *scriptLockedError) Error() string {
+	return fmt.Sprintf("script '%s' is exclusive and already running (lock held by '%s')", e.Script, e.Holder)
+}
+
+// localScriptLocks stand in for Leases when there is no Kubernetes client (local mode)
+var localScriptLocks sync.Map
+
+// scriptLeaseName returns the name of the Lease guarding an exclusive script. All versions of a
+// script share it.
+func scriptLeaseName(scriptName string) string {
+	name := strings.Trim(dnsLabelInvalidChars.ReplaceAllString(strings.ToLower(scriptName), "-"), "-")
+	if len(name) > 63-len("script-lock-") {
+		name = strings.TrimRight(name[:63-len("script-lock-")], "-")
+	}
+	return "script-lock-" + name
+}
+
+// acquireScriptLock takes the Lease of an exclusive script in NAMESPACE, so only one instance of
+// it runs cluster-wide, across replicas and other tooling honoring the Lease. A Lease whose holder
+// stopped renewing it for EXCLUSIVE_LEASE_DURATION_SECONDS is taken over. While the script runs,
+// the Lease is renewed; the returned function releases it. A held Lease fails with a
+// *scriptLockedError.
+func acquireScriptLock(ctx context.Context, config *Config, def *ScriptDefinition, executionID string) (func(), error) {
+	hostname, _ := os.Hostname()
+	holder := hostname + "/" + executionID
+	if kubeClient == nil {
+		if current, loaded := localScriptLocks.LoadOrStore(def.Name, holder); loaded {
+			return nil, &scriptLockedError{Script: def.Name, Holder: current.(string)}
+		}
+		return func() { localScriptLocks.Delete(def.Name) }, nil
+	}
+
+	leases := kubeClient.CoordinationV1().Leases(config.Namespace)
+	name := scriptLeaseName(def.Name)
+	durationSeconds := int32(config.ExclusiveLeaseDuration / time.Second)
+	now := metav1.NewMicroTime(time.Now())
+	spec := coordinationv1.LeaseSpec{
+		HolderIdentity:       &holder,
+		LeaseDurationSeconds: &durationSeconds,
+		AcquireTime:          &now,
+		RenewTime:            &now,
+	}
+
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		lease, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: scriptLabels(def)},
+			Spec:       spec,
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return nil, &scriptLockedError{Script: def.Name, Holder: "another replica"}
+		}
+	case err == nil:
+		if current := leaseHolder(lease); current != "" && !leaseExpired(lease) {
+			return nil, &scriptLockedError{Script: def.Name, Holder: current}
+		}
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions += *lease.Spec.LeaseTransitions
+		}
+		spec.LeaseTransitions = &transitions
+		lease.Spec = spec
+		lease, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			return nil, &scriptLockedError{Script: def.Name, Holder: "another replica"}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire Lease '%s': %v", name, err)
+	}
+
+	renewCtx, stopRenewing := context.WithCancel(context.WithoutCancel(ctx))
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		renewScriptLock(renewCtx, config, lease.Name, holder)
+	}()
+	return func() {
+		stopRenewing()
+		<-renewed
+		releaseScriptLock(config, name, holder)
+	}, nil
+}
+
+// leaseHolder returns the holder of a Lease, "" when released.
+func leaseHolder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// leaseExpired reports whether the holder of a Lease stopped renewing it.
+func leaseExpired(lease *coordinationv1.Lease) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return time.Now().After(lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second))
+}
+
+// renewScriptLock renews a held Lease every third of its duration until ctx is done.
+func renewScriptLock(ctx context.Context, config *Config, name, holder string) {
+	ticker := time.NewTicker(config.ExclusiveLeaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := updateHeldLease(ctx, config, name, holder, func(lease *coordinationv1.Lease) {
+			now := metav1.NewMicroTime(time.Now())
+			lease.Spec.RenewTime = &now
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error().Msgf("Failed to renew Lease '%s' of %s; another instance may start once it expires: %v", name, holder, err)
+		}
+	}
+}
+
+// releaseScriptLock clears the holder of a Lease still held by holder.
+func releaseScriptLock(config *Config, name, holder string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := updateHeldLease(ctx, config, name, holder, func(lease *coordinationv1.Lease) {
+		lease.Spec.HolderIdentity = nil
+		lease.Spec.AcquireTime = nil
+		lease.Spec.RenewTime = nil
+	})
+	if err != nil {
+		logger.Warn().Msgf("Failed to release Lease '%s' of %s; it expires on its own: %v", name, holder, err)
+	}
+}
+
+// updateHeldLease modifies a Lease if holder still holds it.
+func updateHeldLease(ctx context.Context, config *Config, name, holder string, modify func(*coordinationv1.Lease)) error {
+	leases := kubeClient.CoordinationV1().Leases(config.Namespace)
+	lease, err := leases.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if leaseHolder(lease) != holder {
+		return fmt.Errorf("lease is now held by '%s'", leaseHolder(lease))
+	}
+	modify(lease)
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// executionPaths are the routes that answer only when a script finished; they get
+// EXECUTE_REQUEST_TIMEOUT_SECONDS instead of REQUEST_TIMEOUT_SECONDS. Re-runs
+// (/v1/executions/:id/rerun) are matched by isExecutionPath.
+var executionPaths = []string{"/v1/execute", "/v1/trigger/"}
+
+// isExecutionPath reports whether a request path runs a script synchronously. Dry runs execute
+// nothing and keep the regular timeout.
+func isExecutionPath(path string) bool {
+	if path == "/v1/execute/dry-run" {
+		return false
+	}
+	for _, prefix := range executionPaths {
+		if path == prefix || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix)) {
+			return true
+		}
+	}
+	if id, found := strings.CutPrefix(path, "/v1/executions/"); found {
+		id, found = strings.CutSuffix(id, "/rerun")
+		return found && id != "" && !strings.Contains(id, "/")
+	}
+	return false
+}
+
+// withRequestLimits protects a handler from oversized and never-ending requests: bodies above
+// MAX_REQUEST_BODY_BYTES are refused with 413 (or fail to decode when their size isn't declared),
+// and a request not answered within its timeout gets a 503 problem response while its context is
+// cancelled. Slow clients trickling the request itself are cut off by HTTP_READ_TIMEOUT_SECONDS.
+func withRequestLimits(config *Config, next http.Handler) http.Handler {
+	tooLarge, _ := json.Marshal(problem(http.StatusRequestEntityTooLarge, codePayloadTooLarge,
+		fmt.Sprintf("Request body exceeds %d bytes (MAX_REQUEST_BODY_BYTES)", config.MaxRequestBodyBytes)))
+	timeoutHandlers := make(map[time.Duration]http.Handler)
+	for _, timeout := range []time.Duration{config.RequestTimeout, config.ExecuteRequestTimeout} {
+		if timeout > 0 {
+			timedOut, _ := json.Marshal(problem(http.StatusServiceUnavailable, codeRequestTimeout,
+				fmt.Sprintf("Request not completed within %s", timeout)))
+			timeoutHandlers[timeout] = http.TimeoutHandler(next, timeout, string(timedOut))
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if config.MaxRequestBodyBytes > 0 {
+			if req.ContentLength > config.MaxRequestBodyBytes {
+				w.Header().Set("Content-Type", problemContentType)
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				w.Write(tooLarge)
+				return
+			}
+			req.Body = http.MaxBytesReader(w, req.Body, config.MaxRequestBodyBytes)
+		}
+
+		timeout := config.RequestTimeout
+		if isExecutionPath(req.URL.Path) {
+			timeout = config.ExecuteRequestTimeout
+		}
+		if handler, ok := timeoutHandlers[timeout]; ok {
+			handler.ServeHTTP(problemTimeoutWriter{w}, req)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// problemTimeoutWriter labels the timeout response of http.TimeoutHandler, which carries no
+// Content-Type, as a problem response. Responses of the handler itself keep their own.
+type problemTimeoutWriter struct {
+	http.ResponseWriter
+}
+
+func (w problemTimeoutWriter) WriteHeader(statusCode int) {
+	if statusCode == http.StatusServiceUnavailable && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", problemContentType)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestIsExecutionPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/v1/execute", true},
+		{"/v1/execute/dry-run", false},
+		{"/v1/trigger/nightly-restore", true},
+		{"/v1/executions/0b6f3c1e/rerun", true},
+		{"/v1/executions/0b6f3c1e", false},
+		{"/v1/executions/0b6f3c1e/cancel", false},
+		{"/v1/executions//rerun", false},
+		{"/v1/executions/a/b/rerun", false},
+		{"/v1/executions", false},
+		{"/v1/options", false},
+	}
+	for _, tt := range tests {
+		if got := isExecutionPath(tt.path); got != tt.want {
+			t.Errorf("isExecutionPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// Executor modes (EXECUTOR_MODE)
+const (
+	executorModeKubernetes = "kubernetes" // Scripts run in the cluster (default)
+	executorModeLocal      = "local"      // Scripts run on the executor's own host/container, for testing
+)
+
+// localTarget is the target reported for executions in local mode
+const localTarget = "local"
+
+// localWaitDelay bounds the wait for the output of a command killed without grace period, which
+// processes it started may still hold open
+const localWaitDelay = 5 * time.Second
+
+// localGroupPollInterval is how often the process group of a stopped command is checked
+const localGroupPollInterval = 100 * time.Millisecond
+
+// execLocally runs the command on the executor's host with /bin/bash, like execInPod runs it in a
+// pod, and returns the combined output. The executor's environment is inherited. The command runs
+// in its own process group: when ctx is done, the group gets SIGTERM, and SIGKILL if any of it still
+// runs after TERMINATION_GRACE_SECONDS; which one ended it is recorded in run.Termination.
+func execLocally(ctx context.Context, run *executorRun, fullCommand string) (string, error) {
+	logger.Debug().Msgf("Running command locally: %s", fullCommand)
+	grace := run.Config.TerminationGracePeriod
+	cmd := exec.CommandContext(ctx, "/bin/bash", "-c", fullCommand)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	var stoppedAt time.Time
+	cmd.Cancel = func() error {
+		stoppedAt = time.Now()
+		if grace <= 0 {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	// Past the grace period, Go kills the shell and stops waiting for the output
+	cmd.WaitDelay = grace
+	if grace <= 0 {
+		cmd.WaitDelay = localWaitDelay
+	}
+	output, err := cmd.CombinedOutput()
+	if !stoppedAt.IsZero() {
+		run.Termination = killLocalGroup(cmd, stoppedAt.Add(grace))
+	}
+	return string(output), err
+}
+
+// killLocalGroup waits until the process group of a stopped command is gone, killing it with
+// SIGKILL if it isn't by deadline, and returns how the command was terminated.
+func killLocalGroup(cmd *exec.Cmd, deadline time.Time) string {
+	termination := terminationSIGTERM
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() && status.Signal() == syscall.SIGKILL {
+		termination = terminationSIGKILL
+	}
+	for syscall.Kill(-cmd.Process.Pid, 0) == nil {
+		if time.Now().After(deadline) {
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			return terminationSIGKILL
+		}
+		time.Sleep(localGroupPollInterval)
+	}
+	return termination
+}
+
+// localExecutor runs the command, or each step, on the executor's host.
+type localExecutor struct{}
+
+func (localExecutor) UsesWorkloadPod() bool { return false }
+
+func (localExecutor) Run(ctx context.Context, run *executorRun) (string, string, error) {
+	exec := func(command string) (string, error) { return execLocally(ctx, run, command) }
+	cleanup, err := deliverParamFiles(ctx, run, func(file paramFile) error {
+		return writeFileLocally(run.Definition, file)
+	}, func(command string) (string, error) { return execLocally(context.WithoutCancel(ctx), run, command) })
+	if err != nil {
+		return localTarget, "", err
+	}
+	defer cleanup()
+	var output string
+	if len(run.Steps) > 0 {
+		executionLog(ctx).Info().Msgf("Executing %d steps of script '%s' locally...", len(run.Steps), run.Definition.Name)
+		output, err = runSteps(ctx, run, exec)
+	} else {
+		executionLog(ctx).Info().Msgf("Executing command for script '%s' locally...", run.Definition.Name)
+		output, err = exec(run.Command)
+	}
+	return localTarget, output, err
+}
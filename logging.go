@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// logger writes one JSON object per line to stderr, so the log pipeline can index fields instead
+// of parsing free text. Output of the standard library logger (used by dependencies) is routed
+// through it as well.
+var logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
+
+func init() {
+	zerolog.TimeFieldFormat = time.RFC3339Nano
+	log.SetFlags(0)
+	log.SetOutput(logger)
+}
+
+// executionLogger logs entries of one execution with its correlation fields. The fields are filled
+// in as the execute flow learns them (the ProcessID after the tracking record is created, the pod
+// once one is selected); every entry carries all four, empty or zero while still unknown.
+type executionLogger struct {
+	TrackingID string
+	ProcessID  int64
+	Script     string
+	Pod        string
+}
+
+func (l *executionLogger) Debug() *zerolog.Event { return l.fields(logger.Debug()) }
+func (l *executionLogger) Info() *zerolog.Event  { return l.fields(logger.Info()) }
+func (l *executionLogger) Warn() *zerolog.Event  { return l.fields(logger.Warn()) }
+func (l *executionLogger) Error() *zerolog.Event { return l.fields(logger.Error()) }
+
+// fields adds the correlation fields to a log event
+func (l *executionLogger) fields(e *zerolog.Event) *zerolog.Event {
+	return e.Str("trackingId", l.TrackingID).Int64("processId", l.ProcessID).Str("script", l.Script).Str("pod", l.Pod)
+}
+
+type executionLoggerKey struct{}
+
+// withExecutionLogger returns a context carrying l, for helpers called from the execute flow.
+func withExecutionLogger(ctx context.Context, l *executionLogger) context.Context {
+	return context.WithValue(ctx, executionLoggerKey{}, l)
+}
+
+// executionLog returns the execution logger of ctx, or one without correlation values if ctx has none.
+func executionLog(ctx context.Context) *executionLogger {
+	if l, ok := ctx.Value(executionLoggerKey{}).(*executionLogger); ok {
+		return l
+	}
+	return &executionLogger{}
+}
+
+// logRequests is middleware writing one JSON access log entry per request, replacing gin's text logger.
+func logRequests(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	event := logger.Info()
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		event = logger.Error()
+	}
+	event.Str("method", c.Request.Method).
+		Str("path", c.Request.URL.Path).
+		Int("status", c.Writer.Status()).
+		Dur("latency", time.Since(start)).
+		Str("clientIp", c.ClientIP()).
+		Str("caller", callerFromContext(c).Name).
+		Msg("request")
+}
+
+// parseLogLevel parses one of the supported log level names.
+func parseLogLevel(level string) (zerolog.Level, error) {
+	parsed, err := zerolog.ParseLevel(strings.ToLower(strings.TrimSpace(level)))
+	if err != nil || parsed == zerolog.NoLevel || parsed > zerolog.ErrorLevel {
+		return zerolog.NoLevel, fmt.Errorf("invalid log level '%s' (supported: trace, debug, info, warn, error)", level)
+	}
+	return parsed, nil
+}
+
+// setLogLevel changes the level of all subsequent log entries. It is safe to call concurrently.
+func setLogLevel(level string) error {
+	parsed, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	zerolog.SetGlobalLevel(parsed)
+	return nil
+}
+
+// LogLevelRequest is the body of PUT /admin/loglevel
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// getLogLevel handles GET /admin/loglevel and returns the current log level.
+func getLogLevel(c *gin.Context) {
+	writeJSON(c, http.StatusOK, gin.H{"level": zerolog.GlobalLevel().String()})
+}
+
+// putLogLevel handles PUT /admin/loglevel with {"level": "debug"}, so verbosity can be raised while
+// investigating e.g. parameter mapping and lowered again, without a redeploy. The change lasts until
+// the next restart, which falls back to LOG_LEVEL.
+func putLogLevel(c *gin.Context) {
+	caller := callerFromContext(c)
+	var request LogLevelRequest
+	if err := bindJSONWithNaming(c, &request); err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+	previous := zerolog.GlobalLevel().String()
+	if err := setLogLevel(request.Level); err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, err.Error()))
+		return
+	}
+	current := zerolog.GlobalLevel().String()
+	// Logged without a level, so the change is visible whatever the new level is
+	logger.Log().Msgf("Log level changed from '%s' to '%s' by caller '%s'.", previous, current, caller.Name)
+	writeJSON(c, http.StatusOK, gin.H{"level": current, "previous": previous})
+}
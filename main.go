@@ -1,12 +1,12 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -16,12 +16,16 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	// Kubernetes imports
 	authv1 "k8s.io/api/authorization/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
 const (
@@ -29,6 +33,12 @@ const (
 	maxProcessTrackingMessageLength = 1000
 )
 
+// placeholderPattern matches ${VAR_NAME} placeholders expanded from request parameters
+var placeholderPattern = regexp.MustCompile(`\${([A-Za-z0-9_]+)}`)
+
+// version is the application version, set at build time via -ldflags "-X main.version=..."
+var version = "dev"
+
 // ParameterOption defines the structure for options within a script's *top-level* parameter definition
 // (Used if the script itself is presented like a parameter with predefined choices, like in the previous Go structure)
 // This might become less relevant with the new response structure but keep for loading compatibility for now.
@@ -44,22 +54,104 @@ type InputParameterDef struct {
 	Type        string `json:"type,omitempty"` // Required (Defaults to string if omitted? TBC)
 	Description string `json:"description,omitempty"`
 	Optional    bool   `json:"optional,omitempty"`
+	Sensitive   bool   `json:"sensitive,omitempty"` // Value is never recorded in debug environment reports
+	EnvName     string `json:"envName,omitempty"`   // Env var the value is passed in; defaults to the sanitized name
+	DeliverAs   string `json:"deliverAs,omitempty"` // "env" (default) or "file": the value is written to a file whose path is in <ENV>_FILE
 	// Add other fields seen in Java example if needed (e.g., dataset_id?)
 }
 
 // ScriptDefinition holds the combined definition loaded from scripts.json
 type ScriptDefinition struct {
 	// Fields for identifying the script and its command
-	ID      string `json:"id,omitempty"` // Optional - will be auto-generated from name if not provided
-	Name    string `json:"name"`         // Required (This will be the top-level "name" in the response)
-	Command string `json:"command"`      // Required
+	ID      string `json:"id,omitempty"`      // Optional - will be auto-generated from name if not provided
+	Name    string `json:"name"`              // Required (This will be the top-level "name" in the response)
+	Command string `json:"command"`           // Required unless 'steps' or 'tektonPipeline' is set
+	Version string `json:"version,omitempty"` // Several definitions may share a name with different versions; the latest is the default
+
+	// Pipelines: a DAG of other scripts linked by dependsOn, run by a single execute call
+	Pipeline []PipelineNode `json:"pipeline,omitempty"`
+	// Rollout strategy of a pipeline, e.g. a canary node that must succeed before the others start
+	Rollout *PipelineRollout `json:"rollout,omitempty"`
+
+	// Multi-step scripts: ordered commands run one after another in the same pod, aborting on the first failure
+	Steps []ScriptStep `json:"steps,omitempty"`
 
 	// Input parameters the script accepts
 	Parameters []InputParameterDef `json:"parameters,omitempty"`
+	// Environment variables of every execution, under the request's parameters (e.g. DB_HOST)
+	Env map[string]string `json:"env,omitempty"`
 
 	// Process tracking fields
 	Stage          string `json:"stage,omitempty"`          // Process tracking stage for this script
-	MonitorProcess bool   `json:"monitorProcess,omitempty"` // Whether to monitor this script with process tracking
+	MonitorProcess *bool  `json:"monitorProcess,omitempty"` // Whether to monitor this script with process tracking; unset follows MONITOR_PROCESS_DEFAULT
+
+	// Pod selection fields
+	PodSelectors           []string `json:"podSelectors,omitempty"`           // Label selectors tried in order (e.g. primary, then standby); defaults to POD_LABEL_SELECTOR
+	WaitForPodReadySeconds int      `json:"waitForPodReadySeconds,omitempty"` // Wait up to this long for a Ready pod instead of failing immediately
+
+	// Executor backend (podExec, job, ephemeralContainer, node, tekton, ssh, local); inferred from the fields below when unset
+	Backend string `json:"backend,omitempty"`
+
+	// User the command runs as: a user name, switched to with runuser/su, or a numeric UID for backends creating their container
+	RunAsUser string `json:"runAsUser,omitempty"`
+
+	// Only one instance runs cluster-wide at a time, guarded by a Lease
+	Exclusive bool `json:"exclusive,omitempty"`
+	// Executions wait for the running one instead of running concurrently
+	Serialize bool `json:"serialize,omitempty"`
+	// Identical requests within this many seconds reuse the output of the last successful execution
+	CacheTTLSeconds int `json:"cacheTtlSeconds,omitempty"`
+	// Executions are skipped while the last successful one finished less than this many seconds ago
+	MinIntervalSeconds int `json:"minIntervalSeconds,omitempty"`
+
+	// SSH mode: run on a VM, with the connection and credentials from a Secret
+	SSH *SSHTarget `json:"ssh,omitempty"`
+
+	// Dedicated pod mode: run in a short-lived pod built from this image instead of exec'ing into the workload
+	Image      string           `json:"image,omitempty"`
+	Resources  *ScriptResources `json:"resources,omitempty"`
+	Scheduling *PodScheduling   `json:"scheduling,omitempty"` // Node selector, tolerations and affinity of the pod
+
+	// Ephemeral container mode: run in a debug container attached to the selected target pod
+	DebugImage           string `json:"debugImage,omitempty"`
+	DebugTargetContainer string `json:"debugTargetContainer,omitempty"` // Share this container's process namespace
+
+	// Tekton mode: executing the script creates a PipelineRun of this Pipeline instead of running a command
+	TektonPipeline  string `json:"tektonPipeline,omitempty"`
+	TektonNamespace string `json:"tektonNamespace,omitempty"` // Defaults to NAMESPACE
+
+	// Node-targeted mode: run in the host namespaces of a node via a privileged helper pod
+	NodeName     string            `json:"nodeName,omitempty"` // May reference a parameter, e.g. "${NODE}"
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Scheduling
+	Schedule string `json:"schedule,omitempty"` // Cron expression; the script runs on this schedule without a Task Service trigger
+
+	// Lifecycle: disabled scripts are hidden and refuse execution; deprecated scripts still run but warn
+	Disabled           bool   `json:"disabled,omitempty"`
+	Deprecated         bool   `json:"deprecated,omitempty"`
+	DeprecationMessage string `json:"deprecationMessage,omitempty"` // E.g. which script to use instead
+
+	// Quota: maximum executions of this script per rolling hour/day, across all callers
+	Quota *ExecutionQuota `json:"quota,omitempty"`
+
+	// Caller authorization: when either list is set, only matching authenticated callers may run the script
+	AllowedCallers []string `json:"allowedCallers,omitempty"`
+	AllowedGroups  []string `json:"allowedGroups,omitempty"`
+
+	// Tags group scripts in catalogs and can be filtered on with /v1/options?tag=...
+	Tags []string `json:"tags,omitempty"`
+
+	// Chat channels notified when an execution of the script finishes
+	Notifications []NotificationChannel `json:"notifications,omitempty"`
+	// URL receiving a signed JSON payload with the result of every execution
+	CallbackURL string `json:"callbackUrl,omitempty"`
+	// Templates of the process record name and status messages
+	TrackingMessages *TrackingMessages `json:"trackingMessages,omitempty"`
+	// How the output is fitted into tracking messages
+	TrackingOutput *TrackingOutput `json:"trackingOutput,omitempty"`
+	// Non-zero exit codes meaning WARNING or SKIPPED rather than FAILED, e.g. {"3": "SKIPPED"}
+	ExitCodes map[int]string `json:"exitCodes,omitempty"`
 
 	// Optional descriptive fields (Not directly used in new response structure but maybe useful internally)
 	Description string            `json:"description,omitempty"`
@@ -73,6 +165,10 @@ type ScriptDefinition struct {
 type ScriptResponse struct {
 	Name       string              `json:"name"`
 	Parameters []InputParameterDef `json:"parameters"`
+	Tags       []string            `json:"tags,omitempty"`
+	Version    string              `json:"version,omitempty"` // Latest version of the script
+	Deprecated bool                `json:"deprecated,omitempty"`
+	Warning    string              `json:"warning,omitempty"` // Deprecation notice for deprecated scripts
 }
 
 // TaskServiceRequest defines the structure expected from the calling Task Service
@@ -80,7 +176,10 @@ type TaskServiceRequest struct {
 	TaskName    string                 `json:"taskName"`
 	LastRunTime int64                  `json:"lastRunTime"` // Changed type to int64 to accept number
 	TrackingID  string                 `json:"trackingId"`
-	TaskData    map[string]interface{} `json:"taskData"` // Use interface{} for flexible value types
+	TaskData    map[string]interface{} `json:"taskData"`              // Use interface{} for flexible value types
+	Version     string                 `json:"version,omitempty"`     // Pin a script version; the latest version runs when empty
+	CallbackURL string                 `json:"callbackUrl,omitempty"` // Receives a signed JSON payload when the execution finishes
+	Stage       string                 `json:"stage,omitempty"`       // Process tracking stage, overriding the script's and PROCESS_TRACKING_STAGE
 }
 
 // ProcessTrackingCreatePayload sent to initially create a process tracking record
@@ -102,98 +201,576 @@ type ProcessTrackingUpdatePayload struct {
 	Message      string `json:"message,omitempty"`
 	MessageLevel string `json:"messageLevel"` // Added: INFO or ERROR based on Status
 	// TrackingID removed
+	// Outcome of finishing updates, unless PROCESS_TRACKING_METADATA moves it into the message
+	ExitCode        *int     `json:"exitCode,omitempty"` // Unset when the script never ran
+	DurationSeconds *float64 `json:"durationSeconds,omitempty"`
+	Pod             string   `json:"pod,omitempty"`
 }
 
 // Config holds application configuration
 type Config struct {
-	ScriptsPath      string
-	PodLabelSelector string
-	Namespace        string
+	ScriptsPath string
+	// Definitions signing: when a public key is set, definitions without a valid detached signature are refused
+	ScriptSigningPubKey string // PEM public key, or path to a PEM file
+	ScriptSignaturePath string // Defaults to <SCRIPTS_PATH>.sig
+	CommandPolicyPath   string // Server-side allowlist of commands; definitions outside it are rejected
+	PodLabelSelector    string
+	Namespace           string
 	// Process Tracking Config
 	ProcessTrackingURL   string
 	ProcessTrackingStage string
 	ProcessTrackingGroup string
+	// Refuse to start without PROCESS_TRACKING_SERVICE_URL instead of falling back to built-in tracking
+	ProcessTrackingRequired bool
+	// Whether scripts without monitorProcess are reported to process tracking
+	MonitorProcessDefault bool
+	// Credentials of process tracking calls, typically from a Secret: bearer token or basic auth, and/or a Cookie header
+	ProcessTrackingToken    string
+	ProcessTrackingUsername string
+	ProcessTrackingPassword string
+	ProcessTrackingCookie   string
+	// TLS of process tracking calls: extra trusted CA bundle, and client certificate for mTLS
+	ProcessTrackingCACert     string
+	ProcessTrackingClientCert string
+	ProcessTrackingClientKey  string
+	// Rate limit of PROGRESS updates per execution; excess updates are coalesced (0 = unlimited)
+	ProcessTrackingMaxUpdatesPerMinute int
+	// How finishing updates carry exit code, duration and pod: "fields", "message" or "off"
+	ProcessTrackingMetadata string
+	// Process tracking statuses reported for exit codes mapped to WARNING or SKIPPED
+	ProcessTrackingWarningStatus string
+	ProcessTrackingSkippedStatus string
+	// How output is fitted into tracking messages: mode, error line pattern, and full-output link template
+	TrackingOutputMode          string
+	TrackingOutputErrorPattern  string
+	TrackingOutputAttachmentURL string
+	// Retries of process tracking calls: total attempts, and exponential backoff base and cap
+	ProcessTrackingMaxAttempts int
+	ProcessTrackingRetryBase   time.Duration
+	ProcessTrackingRetryMax    time.Duration
+	// Journal of process tracking updates that failed transiently, retried in the background (empty = off)
+	TrackingOutboxPath          string
+	TrackingOutboxRetryInterval time.Duration
+	// Journal of the executions in flight, reconciled after a restart, and how often a re-attached
+	// execution is checked
+	ExecutionJournalPath         string
+	ExecutionJournalPollInterval time.Duration
+	// Execution history persistence
+	HistoryDBDriver      string
+	HistoryDBDSN         string
+	HistoryDBAutoMigrate bool
+	// Exec retry config
+	ExecTransientRetries int
+	// Shared secret for the /v1/trigger endpoint (endpoint disabled when empty)
+	TriggerToken string
+	// Debug mode
+	Debug          bool
+	DebugEnvValues bool // Record values (not just names) of non-sensitive env vars in debug reports
+	// Pin executions sharing a TrackingID to the same pod
+	StickyPodAffinity bool
+	StickyPodTTL      time.Duration
+	// Catalog export
+	CatalogOwner       string
+	CatalogCategory    string
+	CatalogExecutorURL string
+	// Scheduled scripts
+	SchedulerMode              string
+	SchedulerNamespace         string
+	SchedulerExecutorURL       string
+	SchedulerCronJobImage      string
+	SchedulerTokenSecret       string
+	SchedulerReconcileInterval time.Duration
+	SchedulerMaxConcurrent     int
+	// Dedicated pod mode
+	DedicatedPodTimeout time.Duration
+	NodeHelperImage     string // Image of the privileged helper pod for node-targeted scripts (must provide nsenter)
+	// Tekton mode
+	TektonTimeout time.Duration
+	// Caller authentication through headers set by a trusted proxy
+	TrustedCallerHeader string
+	TrustedGroupsHeader string
+	// Where scripts run: "kubernetes" or "local" (the executor's own host, for testing)
+	ExecutorMode string
+	// How long the Lease of an exclusive script outlives its last renewal
+	ExclusiveLeaseDuration time.Duration
+	// How long a serialized script's execution waits for its turn (0 = as long as the request lasts)
+	SerializeMaxWait time.Duration
+	// Created resources left behind are deleted once this old (0 = never), checked every ResourceGCInterval
+	ResourceTTL        time.Duration
+	ResourceGCInterval time.Duration
+	// Parameter values larger than this are delivered as files where the backend supports it (0 = never)
+	ParamFileThreshold int
+	// Redis holding the state replicas share (rate limits, pod pins); in memory per replica when unset
+	RedisURL       string
+	RedisKeyPrefix string
+	// known_hosts file verifying SSH targets whose Secret has no knownHosts
+	SSHKnownHostsPath string
+	// Out-of-cluster mode: kubeconfig used instead of the in-cluster config, and skipping the RBAC
+	// checks for local development (also settable with --kubeconfig and --skip-permission-check)
+	Kubeconfig          string
+	SkipPermissionCheck bool
+	// Pod execs of callers authenticated by a ServiceAccount token impersonate it, so cluster RBAC
+	// governs what each caller may exec into
+	ImpersonateServiceAccounts bool
+	// Permissions are re-checked this often, failing readiness and executions once revoked (0 = startup only)
+	PermissionCheckInterval time.Duration
+	// Pre-flight check that script binaries exist in their target pods: at startup, then every
+	// PreflightInterval (0 = startup only)
+	PreflightCheck    bool
+	PreflightInterval time.Duration
+	// HTTP server: listen address and timeouts (0 disables a timeout)
+	ListenAddr        string
+	Port              int
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration // Off by default: synchronous executions keep the response open
+	IdleTimeout       time.Duration
+	// Request limits: body size, and handler deadlines of regular and synchronous execution requests
+	MaxRequestBodyBytes   int64
+	RequestTimeout        time.Duration
+	ExecuteRequestTimeout time.Duration
+	// Maintenance mode: initial state and default message of paused executions
+	MaintenanceMode    bool
+	MaintenanceMessage string
+	// Admin endpoints: own listener (shared with the API when empty), token and TLS/mTLS
+	AdminListenAddr   string
+	AdminToken        string
+	AdminTLSCertFile  string
+	AdminTLSKeyFile   string
+	AdminClientCAFile string
+	// CORS for browser-based consoles (disabled without allowed origins)
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           int // Seconds browsers may cache a preflight response
+	// Readiness probe: optional reachability check of the process tracking service
+	ReadinessCheckTracking bool
+	// Time in-flight executions get to finish on SIGTERM
+	ShutdownGracePeriod time.Duration
+	// Time a stopped script gets to exit after SIGTERM before it is killed with SIGKILL
+	TerminationGracePeriod time.Duration
+	// HTTPS: server certificate, and optional client CA for mTLS
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+	TLSClientAuth   string // "require" (default) or "optional"
+	// ServiceAccount token authentication through the TokenReview API
+	TokenReviewEnabled   bool
+	TokenReviewAudiences []string
+	TokenReviewCacheTTL  time.Duration
+	// API key authentication with scopes
+	APIKeysPath string
+	// Per-client rate limiting of the execute endpoints (0 disables)
+	RateLimitExecutePerMinute int
+	RateLimitExecuteBurst     int
+	// Per-caller execution quotas (JSON file of caller name -> quota)
+	CallerQuotasPath string
+	// Audit log of every execute attempt
+	AuditLogSink string
+	AuditLogPath string
+	AuditReaders []string // Caller names/groups allowed to export the audit log; empty allows any caller with access
+	// Logging and operator endpoints
+	LogLevel     string   // Initial log level (trace, debug, info, warn, error); adjustable at runtime via /admin/loglevel
+	AdminCallers []string // Caller names/groups allowed to use /admin endpoints; empty allows none, unless the admin listener requires mTLS
+	// Completion callbacks: HMAC secret signing them, and hosts a request's callbackUrl may point to
+	CallbackSigningSecret string
+	CallbackAllowedHosts  []string
+	// Execution event publishing: off, kafka or nats; brokers (Kafka bootstrap servers or NATS URL) and topic/subject
+	EventsSink    string
+	EventsBrokers []string
+	EventsTopic   string
+	EventsSource  string // CloudEvents 'source' of execution events and callbacks
+	// SMTP server of email notifications
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	// Link to an execution record in notifications, with {processId}, {trackingId} and {executionId} placeholders
+	ExecutionLinkTemplate string
+	// Listen address of the pprof/expvar diagnostics server; empty disables it
+	DiagnosticsAddr string
+	// Policy hook: external OPA decision endpoint consulted before every execution
+	OPAURL     string
+	OPATimeout time.Duration
+	// JSON field naming for integrations
+	JSONNamingDefault   namingStrategy
+	JSONNamingEndpoints map[string]namingStrategy
 }
 
 // Load configuration from environment variables with fallbacks
 func loadConfig() *Config {
+	jsonNamingDefault, err := parseNamingStrategy(lookupEnv("JSON_NAMING_DEFAULT"))
+	if err != nil {
+		logger.Warn().Msgf("%v; using struct-defined field names", err)
+	}
+
 	return &Config{
-		ScriptsPath:          getEnvOrDefault("SCRIPTS_PATH", "/config/scripts.json"),
-		PodLabelSelector:     getEnvOrDefault("POD_LABEL_SELECTOR", "app=query-server"),
-		Namespace:            getEnvOrDefault("NAMESPACE", "default"),
-		ProcessTrackingURL:   os.Getenv("PROCESS_TRACKING_SERVICE_URL"),                    // Mandatory? Add check if so.
-		ProcessTrackingStage: getEnvOrDefault("PROCESS_TRACKING_STAGE", "EXECUTION"),       // Example default
-		ProcessTrackingGroup: getEnvOrDefault("PROCESS_TRACKING_GROUP", "ScriptExecution"), // Example default
+		ScriptsPath:                        getEnvOrDefault("SCRIPTS_PATH", "/config/scripts.json"),
+		ScriptSigningPubKey:                getEnvOrDefault("SCRIPT_SIGNING_PUBKEY", ""),
+		ScriptSignaturePath:                getEnvOrDefault("SCRIPT_SIGNATURE_PATH", ""),
+		CommandPolicyPath:                  getEnvOrDefault("COMMAND_POLICY_PATH", ""),
+		PodLabelSelector:                   getEnvOrDefault("POD_LABEL_SELECTOR", "app=query-server"),
+		Namespace:                          getEnvOrDefault("NAMESPACE", "default"),
+		ProcessTrackingURL:                 lookupEnv("PROCESS_TRACKING_SERVICE_URL"),                    // Built-in tracking when empty, unless PROCESS_TRACKING_REQUIRED
+		ProcessTrackingStage:               getEnvOrDefault("PROCESS_TRACKING_STAGE", "EXECUTION"),       // Example default
+		ProcessTrackingGroup:               getEnvOrDefault("PROCESS_TRACKING_GROUP", "ScriptExecution"), // Example default
+		ProcessTrackingRequired:            getEnvOrDefault("PROCESS_TRACKING_REQUIRED", "false") == "true",
+		ProcessTrackingToken:               lookupEnv("PROCESS_TRACKING_TOKEN"),
+		ProcessTrackingUsername:            lookupEnv("PROCESS_TRACKING_USERNAME"),
+		ProcessTrackingPassword:            lookupEnv("PROCESS_TRACKING_PASSWORD"),
+		ProcessTrackingCookie:              lookupEnv("PROCESS_TRACKING_COOKIE"),
+		ProcessTrackingCACert:              getEnvOrDefault("PROCESS_TRACKING_CA_CERT", ""),
+		ProcessTrackingClientCert:          getEnvOrDefault("PROCESS_TRACKING_CLIENT_CERT", ""),
+		ProcessTrackingClientKey:           getEnvOrDefault("PROCESS_TRACKING_CLIENT_KEY", ""),
+		MonitorProcessDefault:              getEnvOrDefault("MONITOR_PROCESS_DEFAULT", "true") == "true",
+		ProcessTrackingMaxUpdatesPerMinute: getEnvIntOrDefault("PROCESS_TRACKING_MAX_UPDATES_PER_MINUTE", 30),
+		ProcessTrackingMetadata:            getEnvOrDefault("PROCESS_TRACKING_METADATA", trackingMetadataFields),
+		ProcessTrackingWarningStatus:       strings.ToUpper(getEnvOrDefault("PROCESS_TRACKING_WARNING_STATUS", executionStatusSuccessful)),
+		ProcessTrackingSkippedStatus:       strings.ToUpper(getEnvOrDefault("PROCESS_TRACKING_SKIPPED_STATUS", executionStatusSuccessful)),
+		TrackingOutputMode:                 getEnvOrDefault("TRACKING_OUTPUT_MODE", trackingOutputHead),
+		TrackingOutputErrorPattern:         getEnvOrDefault("TRACKING_OUTPUT_ERROR_PATTERN", `(?i)\b(error|exception|fatal|fail(ed|ure)?)\b`),
+		TrackingOutputAttachmentURL:        getEnvOrDefault("TRACKING_OUTPUT_ATTACHMENT_URL", ""),
+		ProcessTrackingMaxAttempts:         getEnvIntOrDefault("PROCESS_TRACKING_MAX_ATTEMPTS", 3),
+		ProcessTrackingRetryBase:           time.Duration(getEnvIntOrDefault("PROCESS_TRACKING_RETRY_BASE_MS", 500)) * time.Millisecond,
+		ProcessTrackingRetryMax:            time.Duration(getEnvIntOrDefault("PROCESS_TRACKING_RETRY_MAX_MS", 5000)) * time.Millisecond,
+		TrackingOutboxPath:                 getEnvOrDefault("TRACKING_OUTBOX_PATH", ""),
+		TrackingOutboxRetryInterval:        time.Duration(getEnvIntOrDefault("TRACKING_OUTBOX_RETRY_SECONDS", 30)) * time.Second,
+		ExecutionJournalPath:               getEnvOrDefault("EXECUTION_JOURNAL_PATH", ""),
+		ExecutionJournalPollInterval:       time.Duration(getEnvIntOrDefault("EXECUTION_JOURNAL_POLL_SECONDS", 15)) * time.Second,
+		HistoryDBDriver:                    getEnvOrDefault("HISTORY_DB_DRIVER", "sqlite"),
+		HistoryDBDSN:                       lookupEnv("HISTORY_DB_DSN"), // History persistence is disabled when empty
+		HistoryDBAutoMigrate:               getEnvOrDefault("HISTORY_DB_AUTO_MIGRATE", "true") == "true",
+		ExecTransientRetries:               getEnvIntOrDefault("EXEC_TRANSIENT_RETRIES", 2),
+		TriggerToken:                       lookupEnv("TRIGGER_TOKEN"),
+		Debug:                              getEnvOrDefault("DEBUG", "false") == "true",
+		DebugEnvValues:                     getEnvOrDefault("DEBUG_ENV_VALUES", "false") == "true",
+		StickyPodAffinity:                  getEnvOrDefault("STICKY_POD_AFFINITY", "false") == "true",
+		StickyPodTTL:                       time.Duration(getEnvIntOrDefault("STICKY_POD_TTL_SECONDS", 3600)) * time.Second,
+		CatalogOwner:                       getEnvOrDefault("CATALOG_OWNER", "group:default/platform"),
+		CatalogCategory:                    getEnvOrDefault("CATALOG_CATEGORY", "Script Execution"),
+		CatalogExecutorURL:                 getEnvOrDefault("CATALOG_EXECUTOR_URL", "http://k8s-script-executor"),
+		SchedulerMode:                      getEnvOrDefault("SCHEDULER_MODE", schedulerModeOff),
+		SchedulerNamespace:                 getEnvOrDefault("SCHEDULER_NAMESPACE", getEnvOrDefault("POD_NAMESPACE", "default")),
+		SchedulerExecutorURL:               getEnvOrDefault("SCHEDULER_EXECUTOR_URL", "http://k8s-script-executor"),
+		SchedulerCronJobImage:              getEnvOrDefault("SCHEDULER_CRONJOB_IMAGE", "curlimages/curl:8.10.1"),
+		SchedulerTokenSecret:               lookupEnv("SCHEDULER_TOKEN_SECRET"),
+		SchedulerReconcileInterval:         time.Duration(getEnvIntOrDefault("SCHEDULER_RECONCILE_INTERVAL_SECONDS", 60)) * time.Second,
+		SchedulerMaxConcurrent:             getEnvIntOrDefault("SCHEDULER_MAX_CONCURRENT", 4),
+		DedicatedPodTimeout:                time.Duration(getEnvIntOrDefault("DEDICATED_POD_TIMEOUT_SECONDS", 3600)) * time.Second,
+		NodeHelperImage:                    getEnvOrDefault("NODE_HELPER_IMAGE", "alpine:3.20"),
+		TrustedCallerHeader:                getEnvOrDefault("TRUSTED_CALLER_HEADER", ""),
+		TrustedGroupsHeader:                getEnvOrDefault("TRUSTED_GROUPS_HEADER", ""),
+		ExecutorMode:                       getEnvOrDefault("EXECUTOR_MODE", executorModeKubernetes),
+		SSHKnownHostsPath:                  getEnvOrDefault("SSH_KNOWN_HOSTS_PATH", ""),
+		ExclusiveLeaseDuration:             time.Duration(getEnvIntOrDefault("EXCLUSIVE_LEASE_DURATION_SECONDS", 60)) * time.Second,
+		SerializeMaxWait:                   time.Duration(getEnvIntOrDefault("SERIALIZE_MAX_WAIT_SECONDS", 600)) * time.Second,
+		ParamFileThreshold:                 getEnvIntOrDefault("PARAM_FILE_THRESHOLD_BYTES", 32768),
+		ResourceTTL:                        time.Duration(getEnvIntOrDefault("RESOURCE_TTL_SECONDS", 3600)) * time.Second,
+		ResourceGCInterval:                 time.Duration(getEnvIntOrDefault("RESOURCE_GC_INTERVAL_SECONDS", 300)) * time.Second,
+		RedisURL:                           getEnvOrDefault("REDIS_URL", ""),
+		RedisKeyPrefix:                     getEnvOrDefault("REDIS_KEY_PREFIX", "k8s-script-executor:"),
+		Kubeconfig:                         getEnvOrDefault("KUBECONFIG", ""),
+		SkipPermissionCheck:                getEnvOrDefault("SKIP_PERMISSION_CHECK", "false") == "true",
+		ImpersonateServiceAccounts:         getEnvOrDefault("IMPERSONATE_SERVICE_ACCOUNTS", "false") == "true",
+		PermissionCheckInterval:            time.Duration(getEnvIntOrDefault("PERMISSION_CHECK_INTERVAL_SECONDS", 300)) * time.Second,
+		PreflightCheck:                     getEnvOrDefault("PREFLIGHT_CHECK", "false") == "true",
+		PreflightInterval:                  time.Duration(getEnvIntOrDefault("PREFLIGHT_INTERVAL_SECONDS", 0)) * time.Second,
+		ListenAddr:                         getEnvOrDefault("LISTEN_ADDR", "0.0.0.0"),
+		Port:                               getEnvIntOrDefault("PORT", 8080),
+		ReadHeaderTimeout:                  time.Duration(getEnvIntOrDefault("HTTP_READ_HEADER_TIMEOUT_SECONDS", 10)) * time.Second,
+		ReadTimeout:                        time.Duration(getEnvIntOrDefault("HTTP_READ_TIMEOUT_SECONDS", 60)) * time.Second,
+		WriteTimeout:                       time.Duration(getEnvIntOrDefault("HTTP_WRITE_TIMEOUT_SECONDS", 0)) * time.Second,
+		IdleTimeout:                        time.Duration(getEnvIntOrDefault("HTTP_IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+		MaintenanceMode:                    getEnvOrDefault("MAINTENANCE_MODE", "false") == "true",
+		MaintenanceMessage:                 getEnvOrDefault("MAINTENANCE_MESSAGE", "Script execution is paused for maintenance, please retry later"),
+		AdminListenAddr:                    getEnvOrDefault("ADMIN_LISTEN_ADDR", ""),
+		AdminToken:                         lookupEnv("ADMIN_TOKEN"),
+		AdminTLSCertFile:                   getEnvOrDefault("ADMIN_TLS_CERT_FILE", ""),
+		AdminTLSKeyFile:                    getEnvOrDefault("ADMIN_TLS_KEY_FILE", ""),
+		AdminClientCAFile:                  getEnvOrDefault("ADMIN_CLIENT_CA_FILE", ""),
+		CORSAllowedOrigins:                 getEnvListOrDefault("CORS_ALLOWED_ORIGINS", nil),
+		CORSAllowedMethods:                 getEnvListOrDefault("CORS_ALLOWED_METHODS", []string{"GET", "POST"}),
+		CORSAllowedHeaders:                 getEnvListOrDefault("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", apiKeyHeader}),
+		CORSAllowCredentials:               getEnvOrDefault("CORS_ALLOW_CREDENTIALS", "false") == "true",
+		CORSMaxAge:                         getEnvIntOrDefault("CORS_MAX_AGE_SECONDS", 600),
+		ReadinessCheckTracking:             getEnvOrDefault("READINESS_CHECK_TRACKING", "false") == "true",
+		MaxRequestBodyBytes:                int64(getEnvIntOrDefault("MAX_REQUEST_BODY_BYTES", 1<<20)),
+		RequestTimeout:                     time.Duration(getEnvIntOrDefault("REQUEST_TIMEOUT_SECONDS", 60)) * time.Second,
+		ExecuteRequestTimeout:              time.Duration(getEnvIntOrDefault("EXECUTE_REQUEST_TIMEOUT_SECONDS", 0)) * time.Second,
+		ShutdownGracePeriod:                time.Duration(getEnvIntOrDefault("SHUTDOWN_GRACE_SECONDS", 25)) * time.Second,
+		TerminationGracePeriod:             time.Duration(getEnvIntOrDefault("TERMINATION_GRACE_SECONDS", 10)) * time.Second,
+		TLSCertFile:                        getEnvOrDefault("TLS_CERT_FILE", ""),
+		TLSKeyFile:                         getEnvOrDefault("TLS_KEY_FILE", ""),
+		TLSClientCAFile:                    getEnvOrDefault("TLS_CLIENT_CA_FILE", ""),
+		TLSClientAuth:                      getEnvOrDefault("TLS_CLIENT_AUTH", "require"),
+		TokenReviewEnabled:                 getEnvOrDefault("TOKEN_REVIEW_ENABLED", "false") == "true",
+		TokenReviewAudiences:               getEnvListOrDefault("TOKEN_REVIEW_AUDIENCES", nil),
+		TokenReviewCacheTTL:                time.Duration(getEnvIntOrDefault("TOKEN_REVIEW_CACHE_SECONDS", 60)) * time.Second,
+		APIKeysPath:                        getEnvOrDefault("API_KEYS_PATH", ""),
+		RateLimitExecutePerMinute:          getEnvIntOrDefault("RATE_LIMIT_EXECUTE_PER_MINUTE", 0),
+		RateLimitExecuteBurst:              getEnvIntOrDefault("RATE_LIMIT_EXECUTE_BURST", 5),
+		CallerQuotasPath:                   getEnvOrDefault("CALLER_QUOTAS_PATH", ""),
+		AuditLogSink:                       getEnvOrDefault("AUDIT_LOG_SINK", auditSinkOff),
+		AuditLogPath:                       getEnvOrDefault("AUDIT_LOG_PATH", "/var/log/executor/audit.log"),
+		AuditReaders:                       getEnvListOrDefault("AUDIT_READERS", nil),
+		LogLevel:                           getEnvOrDefault("LOG_LEVEL", "info"),
+		AdminCallers:                       getEnvListOrDefault("ADMIN_CALLERS", nil),
+		DiagnosticsAddr:                    getEnvOrDefault("DIAGNOSTICS_ADDR", ""),
+		ExecutionLinkTemplate:              getEnvOrDefault("EXECUTION_LINK_TEMPLATE", ""),
+		CallbackSigningSecret:              getEnvOrDefault("CALLBACK_SIGNING_SECRET", ""),
+		EventsSink:                         getEnvOrDefault("EVENTS_SINK", eventsSinkOff),
+		EventsBrokers:                      getEnvListOrDefault("EVENTS_BROKERS", nil),
+		EventsTopic:                        getEnvOrDefault("EVENTS_TOPIC", "script-executions"),
+		EventsSource:                       getEnvOrDefault("EVENTS_SOURCE", "/k8s-script-executor"),
+		SMTPHost:                           getEnvOrDefault("SMTP_HOST", ""),
+		SMTPPort:                           getEnvIntOrDefault("SMTP_PORT", 587),
+		SMTPUsername:                       getEnvOrDefault("SMTP_USERNAME", ""),
+		SMTPPassword:                       getEnvOrDefault("SMTP_PASSWORD", ""),
+		SMTPFrom:                           getEnvOrDefault("SMTP_FROM", ""),
+		CallbackAllowedHosts:               getEnvListOrDefault("CALLBACK_ALLOWED_HOSTS", nil),
+		OPAURL:                             getEnvOrDefault("OPA_URL", ""),
+		OPATimeout:                         time.Duration(getEnvIntOrDefault("OPA_TIMEOUT_SECONDS", 5)) * time.Second,
+		TektonTimeout:                      time.Duration(getEnvIntOrDefault("TEKTON_TIMEOUT_SECONDS", 3600)) * time.Second,
+		JSONNamingDefault:                  jsonNamingDefault,
+		JSONNamingEndpoints:                parseEndpointNaming(lookupEnv("JSON_NAMING_ENDPOINTS")),
 	}
 }
 
 // Get environment variable with fallback
 func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-// loadScriptDefinitions reads, parses, and validates the scripts definition file.
-func loadScriptDefinitions(filePath string) ([]ScriptDefinition, error) {
+// getEnvListOrDefault parses a comma-separated environment variable, ignoring empty entries.
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// Get integer environment variable with fallback (invalid values are logged and ignored)
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warn().Msgf("Ignoring invalid integer value '%s' for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// DefinitionError is a validation error of one script definition
+type DefinitionError struct {
+	Index int    `json:"index"` // Position in the definitions file
+	ID    string `json:"id,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Error string `json:"error"`
+
+	rejectedByPolicy bool // Never fall back to an earlier set for these, see loadScriptDefinitions
+}
+
+// readScriptDefinitions reads, parses, and validates the scripts definition file, and loads the
+// command policy the definitions are checked against. File-level problems (unreadable, bad
+// signature, invalid JSON, unreadable policy) are returned as err; otherwise every definition is
+// validated and the errors of all invalid ones are returned.
+func readScriptDefinitions(filePath string) (definitions []ScriptDefinition, policy *CommandPolicy, invalid []DefinitionError, err error) {
 	file, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read script definitions file '%s': %v", filePath, err)
+		return nil, nil, nil, fmt.Errorf("failed to read script definitions file '%s': %v", filePath, err)
+	}
+	config := currentConfig()
+	if err := verifyDefinitionsSignature(config, filePath, file); err != nil {
+		logger.Error().Msgf("Refusing to load script definitions: %v", err)
+		return nil, nil, nil, err
+	}
+	// Fail closed: an unreadable policy must not silently allow every command
+	policy, err = loadCommandPolicy(config.CommandPolicyPath)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	definitions, invalid, err = parseScriptDefinitions(file, filePath, policy)
+	return definitions, policy, invalid, err
+}
 
-	var definitions []ScriptDefinition
+// parseScriptDefinitions parses definitions JSON and validates every definition, including against
+// the command policy. filePath names the source in errors.
+func parseScriptDefinitions(file []byte, filePath string, policy *CommandPolicy) (definitions []ScriptDefinition, invalid []DefinitionError, err error) {
 	err = json.Unmarshal(file, &definitions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse script definitions JSON from '%s': %v", filePath, err)
+		return nil, nil, fmt.Errorf("failed to parse script definitions JSON from '%s': %v", filePath, err)
 	}
 
 	// Validate definitions
+	versions := make(map[string]bool) // "name@version" of every definition, to reject duplicates
 	for i := range definitions {
-		// Validate top-level required fields and set default ID if needed
-		if definitions[i].ID == "" {
-			// Generate an ID based on name if not provided
-			definitions[i].ID = strings.ToLower(strings.ReplaceAll(definitions[i].Name, " ", "-"))
-			log.Printf("Auto-generated ID '%s' for script definition with name '%s'", definitions[i].ID, definitions[i].Name)
+		if err := validateScriptDefinition(definitions, i, versions, filePath); err != nil {
+			invalid = append(invalid, DefinitionError{Index: i, ID: definitions[i].ID, Name: definitions[i].Name, Error: err.Error()})
+			continue
+		}
+		if err := policy.checkDefinition(&definitions[i]); err != nil {
+			logger.Error().Msgf("Script definition '%s' rejected by command policy: %v", definitions[i].ID, err)
+			invalid = append(invalid, DefinitionError{Index: i, ID: definitions[i].ID, Name: definitions[i].Name, rejectedByPolicy: true,
+				Error: fmt.Sprintf("script definition '%s' in '%s' is rejected by the command policy: %v", definitions[i].ID, filePath, err)})
 		}
+	}
+	return definitions, invalid, nil
+}
+
+// validateScriptDefinition validates definitions[i], defaulting its ID and parameter types.
+// versions collects the "name@version" of the definitions validated so far, to reject duplicates.
+func validateScriptDefinition(definitions []ScriptDefinition, i int, versions map[string]bool, filePath string) error {
+	// Validate top-level required fields and set default ID if needed
+	if definitions[i].ID == "" {
+		// Generate an ID based on name if not provided
+		definitions[i].ID = strings.ToLower(strings.ReplaceAll(definitions[i].Name, " ", "-"))
+		logger.Info().Msgf("Auto-generated ID '%s' for script definition with name '%s'", definitions[i].ID, definitions[i].Name)
+	}
 
-		if definitions[i].Name == "" {
-			return nil, fmt.Errorf("script definition %d (id: %s) in '%s' is missing required 'name' field", i, definitions[i].ID, filePath)
+	if definitions[i].Name == "" {
+		return fmt.Errorf("script definition %d (id: %s) in '%s' is missing required 'name' field", i, definitions[i].ID, filePath)
+	}
+	nameVersion := definitions[i].Name + "@" + definitions[i].Version
+	if versions[nameVersion] {
+		return fmt.Errorf("script definition %d in '%s' duplicates script '%s' version '%s'", i, filePath, definitions[i].Name, definitions[i].Version)
+	}
+	versions[nameVersion] = true
+	if definitions[i].Command == "" && definitions[i].TektonPipeline == "" && len(definitions[i].Steps) == 0 && len(definitions[i].Pipeline) == 0 {
+		return fmt.Errorf("script definition %d (id: %s) in '%s' is missing required 'command' field", i, definitions[i].ID, filePath)
+	}
+
+	// Validate nested Parameters
+	envNames := make(map[string]string) // Env var name -> parameter name, to catch parameters sanitized to the same name
+	for j, param := range definitions[i].Parameters {
+		if param.Name == "" {
+			return fmt.Errorf("input parameter %d for script '%s' in '%s' is missing required 'name' field", j, definitions[i].ID, filePath)
+		}
+		if param.EnvName != "" && !isValidEnvVarName(param.EnvName) {
+			return fmt.Errorf("input parameter '%s' for script '%s' in '%s' has an invalid 'envName' '%s'", param.Name, definitions[i].ID, filePath, param.EnvName)
+		}
+		if other, taken := envNames[param.envVarName()]; taken {
+			return fmt.Errorf("input parameters '%s' and '%s' for script '%s' in '%s' both map to environment variable '%s'; set 'envName' on one of them",
+				other, param.Name, definitions[i].ID, filePath, param.envVarName())
 		}
-		if definitions[i].Command == "" {
-			return nil, fmt.Errorf("script definition %d (id: %s) in '%s' is missing required 'command' field", i, definitions[i].ID, filePath)
+		envNames[param.envVarName()] = param.Name
+		// Optional: Validate or default param.Type if needed
+		if param.Type == "" {
+			// Decide: either error out or default it
+			definitions[i].Parameters[j].Type = "string" // Example: Defaulting to string
+			// return fmt.Errorf("input parameter '%s' for script '%s' in '%s' is missing required 'type' field", param.Name, definitions[i].ID, filePath)
 		}
+	}
 
-		// Validate nested Parameters
-		for j, param := range definitions[i].Parameters {
-			if param.Name == "" {
-				return nil, fmt.Errorf("input parameter %d for script '%s' in '%s' is missing required 'name' field", j, definitions[i].ID, filePath)
-			}
-			// Optional: Validate or default param.Type if needed
-			if param.Type == "" {
-				// Decide: either error out or default it
-				definitions[i].Parameters[j].Type = "string" // Example: Defaulting to string
-				// return nil, fmt.Errorf("input parameter '%s' for script '%s' in '%s' is missing required 'type' field", param.Name, definitions[i].ID, filePath)
-			}
+	for j, selector := range definitions[i].PodSelectors {
+		if strings.TrimSpace(selector) == "" {
+			return fmt.Errorf("pod selector %d for script '%s' in '%s' is empty", j, definitions[i].ID, filePath)
+		}
+		if _, err := labels.Parse(selector); err != nil {
+			return fmt.Errorf("pod selector %d for script '%s' in '%s' is not a valid label selector: %v", j, definitions[i].ID, filePath, err)
+		}
+	}
+	if definitions[i].Schedule != "" {
+		if err := validateSchedule(definitions[i].Schedule); err != nil {
+			return fmt.Errorf("script definition '%s' in '%s' has an invalid 'schedule': %v", definitions[i].ID, filePath, err)
 		}
+	}
+	if _, err := toResourceRequirements(definitions[i].Resources); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has invalid 'resources': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateBackend(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has an invalid 'backend': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateScheduling(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has invalid 'scheduling': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateRunAsUser(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has an invalid 'runAsUser': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateParameterDelivery(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has invalid 'parameters': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateStaticEnv(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has an invalid 'env': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateSteps(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has invalid 'steps': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validatePipeline(&definitions[i], definitions); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has an invalid 'pipeline': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validatePipelineRollout(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has an invalid 'rollout': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateNotifications(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has invalid 'notifications': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateCallbackURL(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has an invalid 'callbackUrl': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateTrackingMessages(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has invalid 'trackingMessages': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateTrackingOutput(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has an invalid 'trackingOutput': %v", definitions[i].ID, filePath, err)
+	}
+	if err := validateExitCodes(&definitions[i]); err != nil {
+		return fmt.Errorf("script definition '%s' in '%s' has invalid 'exitCodes': %v", definitions[i].ID, filePath, err)
+	}
+	if definitions[i].WaitForPodReadySeconds < 0 {
+		return fmt.Errorf("script definition '%s' in '%s' has a negative 'waitForPodReadySeconds'", definitions[i].ID, filePath)
+	}
+	if definitions[i].CacheTTLSeconds < 0 {
+		return fmt.Errorf("script definition '%s' in '%s' has a negative 'cacheTtlSeconds'", definitions[i].ID, filePath)
+	}
+	if definitions[i].MinIntervalSeconds < 0 {
+		return fmt.Errorf("script definition '%s' in '%s' has a negative 'minIntervalSeconds'", definitions[i].ID, filePath)
+	}
 
-		// Retain validation for top-level options if they are still used/defined
-		for j, option := range definitions[i].Options {
-			if option.ID == "" {
-				return nil, fmt.Errorf("top-level option %d for script definition '%s' in '%s' is missing required 'id' field", j, definitions[i].ID, filePath)
-			}
-			if option.Name == "" {
-				return nil, fmt.Errorf("top-level option %d (id: %s) for script definition '%s' in '%s' is missing required 'name' field", j, option.ID, definitions[i].ID, filePath)
-			}
+	// Retain validation for top-level options if they are still used/defined
+	for j, option := range definitions[i].Options {
+		if option.ID == "" {
+			return fmt.Errorf("top-level option %d for script definition '%s' in '%s' is missing required 'id' field", j, definitions[i].ID, filePath)
+		}
+		if option.Name == "" {
+			return fmt.Errorf("top-level option %d (id: %s) for script definition '%s' in '%s' is missing required 'name' field", j, option.ID, definitions[i].ID, filePath)
 		}
 	}
 
-	return definitions, nil
+	return nil
+}
+
+// loadScriptDefinitions returns the script definitions of the file, from the registry of its
+// current version (see currentScripts). When the file can't be read or holds an invalid definition,
+// the error is logged and the last valid set is used instead, so a broken edit of the ConfigMap
+// doesn't stop every script; without one the error is returned. A definition rejected by the
+// command policy fails closed instead: the earlier set may hold the very command the policy now
+// forbids. POST /admin/scripts/reload reports every invalid definition.
+func loadScriptDefinitions(filePath string) ([]ScriptDefinition, error) {
+	registry, err := currentScripts(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return registry.definitions, nil
 }
 
 // Get the first pod matching the label selector (used by executeScript)
 func getTargetPod(namespace, labelSelector string) (string, error) {
-	cmd := exec.Command("sh", "-c", fmt.Sprintf("kubectl get pods -n %s -l %s -o jsonpath='{.items[0].metadata.name}'", namespace, labelSelector))
+	cmd := exec.Command("kubectl", "get", "pods", "-n", namespace, "-l", labelSelector, "-o", "jsonpath={.items[0].metadata.name}")
 	out, err := cmd.Output()
 	if err != nil {
 		// Improve error logging
@@ -210,17 +787,147 @@ func getTargetPod(namespace, labelSelector string) (string, error) {
 	return podName, nil
 }
 
+// podReadyPollInterval is how often waitForReadyPod re-checks the pod list
+const podReadyPollInterval = 2 * time.Second
+
+// getReadyPod returns the first pod matching the label selector whose Ready condition is True.
+func getReadyPod(namespace, labelSelector string) (string, error) {
+	jsonPath := `{range .items[*]}{.metadata.name}{" "}{.status.conditions[?(@.type=="Ready")].status}{"\n"}{end}`
+	cmd := exec.Command("kubectl", "get", "pods", "-n", namespace, "-l", labelSelector, "-o", "jsonpath="+jsonPath)
+	out, err := cmd.Output()
+	if err != nil {
+		stderr := ""
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			stderr = string(exitErr.Stderr)
+		}
+		return "", fmt.Errorf("failed to list pods (namespace: %s, selector: %s): %v, stderr: %s", namespace, labelSelector, err, stderr)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "True" {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no ready pod found matching label selector: %s in namespace %s", labelSelector, namespace)
+}
+
+// podSelectorsFor returns the ordered label selectors for a script, falling back to the global selector.
+func podSelectorsFor(def *ScriptDefinition, config *Config) []string {
+	if len(def.PodSelectors) > 0 {
+		return def.PodSelectors
+	}
+	return []string{config.PodLabelSelector}
+}
+
+// findPod tries each label selector in order and returns the first matching pod together with
+// the selector that matched, so a script still runs against a standby workload when the primary
+// is scaled to zero. When requireReady is set only pods with a True Ready condition qualify.
+func findPod(namespace string, selectors []string, requireReady bool) (string, string, error) {
+	var errs []string
+	for _, selector := range selectors {
+		var podName string
+		var err error
+		if requireReady {
+			podName, err = getReadyPod(namespace, selector)
+		} else {
+			podName, err = getTargetPod(namespace, selector)
+		}
+		if err == nil {
+			return podName, selector, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return "", "", fmt.Errorf("no pod found for any selector: %s", strings.Join(errs, "; "))
+}
+
+// waitForReadyPod polls for a Ready pod matching any of the selectors until one appears or the timeout elapses.
+// Used right after rollouts, when the target workload may briefly have no ready replicas.
+func waitForReadyPod(namespace string, selectors []string, timeout time.Duration) (string, string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		podName, selector, err := findPod(namespace, selectors, true)
+		if err == nil {
+			return podName, selector, nil
+		}
+		if time.Now().Add(podReadyPollInterval).After(deadline) {
+			return "", "", fmt.Errorf("timed out after %s waiting for a ready pod: %v", timeout, err)
+		}
+		logger.Info().Msgf("No ready pod yet (namespace: %s, selectors: %v): %v. Retrying in %s...", namespace, selectors, err, podReadyPollInterval)
+		time.Sleep(podReadyPollInterval)
+	}
+}
+
+// execRetryDelay is the pause before retrying an exec that failed at the transport level
+const execRetryDelay = 2 * time.Second
+
+// transientExecErrorPatterns are kubectl/API server messages indicating the exec never reached
+// (or lost contact with) the script, as opposed to the script itself exiting non-zero.
+var transientExecErrorPatterns = []string{
+	"unable to upgrade connection",
+	"error dialing backend",
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"tls handshake timeout",
+	"unable to connect to the server",
+	"the server is currently unable to handle the request",
+	"http2: client connection lost",
+	"use of closed network connection",
+	"context deadline exceeded",
+	"container not found",
+	"container not running",
+	"is being deleted", // pod terminating mid-exec
+}
+
+// podNotFoundPattern matches e.g. `Error from server (NotFound): pods "query-server-abc" not found`
+var podNotFoundPattern = regexp.MustCompile(`pods "[^"]+" not found`)
+
+// isTransientExecFailure reports whether a failed kubectl exec looks like a transport-level failure
+// that is worth retrying on a fresh pod, rather than a genuine script failure.
+func isTransientExecFailure(output string, err error) bool {
+	if err == nil {
+		return false
+	}
+	lowerOutput := strings.ToLower(output)
+	// kubectl reports the remote command's non-zero exit this way - that is a script failure, not transport
+	if strings.Contains(lowerOutput, "command terminated with exit code") {
+		return false
+	}
+	for _, pattern := range transientExecErrorPatterns {
+		if strings.Contains(lowerOutput, pattern) {
+			return true
+		}
+	}
+	return podNotFoundPattern.MatchString(lowerOutput)
+}
+
+// execInPod runs the command in the target pod via kubectl exec and returns the combined output.
+func execInPod(namespace, podName, fullCommand string) (string, error) {
+	return execInPodAs(context.Background(), namespace, podName, fullCommand, nil)
+}
+
+// execInPodAs runs the command like execInPod, impersonating the caller unless as is nil. When ctx
+// is done, kubectl is killed; the process it started in the pod keeps running (see terminateInPod).
+func execInPodAs(ctx context.Context, namespace, podName, fullCommand string, as *Caller) (string, error) {
+	// kubectl is run without a shell, so neither caller names nor the command are ever parsed by one here
+	args := append([]string{"exec", "-n", namespace}, impersonationArgs(as)...)
+	args = append(args, podName, "--", "/bin/bash", "-c", fullCommand)
+	logger.Debug().Msgf("Constructed kubectl command: kubectl %s", strings.Join(args, " "))
+	output, err := exec.CommandContext(ctx, "kubectl", args...).CombinedOutput()
+	return string(output), err
+}
+
 // listScripts handles the /v1/options endpoint.
 // It loads script definitions and returns them in the Java service's format.
 func listScripts(c *gin.Context) {
-	config := loadConfig()
+	config := configFromContext(c)
 
-	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	registry, err := currentScripts(config.ScriptsPath)
 	if err != nil {
-		log.Printf("Error loading script definitions: %v", err)
+		logger.Error().Msgf("Error loading script definitions: %v", err)
 		statusCode := http.StatusInternalServerError
 		if os.IsNotExist(err) {
-			log.Printf("Script definitions file not found at %s", config.ScriptsPath)
+			logger.Info().Msgf("Script definitions file not found at %s", config.ScriptsPath)
 		}
 		// On error, return status code and an empty JSON array body "[]"
 		c.Header("Content-Type", "application/json; charset=utf-8")
@@ -228,24 +935,40 @@ func listScripts(c *gin.Context) {
 		return
 	}
 
+	// Optional filters: every ?tag= must be present on the script, ?search= matches name, ID, description or tags
+	tags := c.QueryArray("tag")
+	caller := callerFromContext(c)
+	search := strings.ToLower(strings.TrimSpace(c.Query("search")))
+
+	// Only the latest version of each script is advertised; older versions can still be pinned on execute
+	definitions := registry.latest
+
 	// Create the response structure matching the Java service
-	scriptResponses := make([]ScriptResponse, len(definitions))
-	for i, def := range definitions {
+	scriptResponses := make([]ScriptResponse, 0, len(definitions))
+	for i := range definitions {
+		def := &definitions[i]
+		if def.Disabled || !callerMaySeeScript(caller, def) || !scriptHasTags(def, tags) || !scriptMatchesSearch(def, search) {
+			continue
+		}
 		// Ensure Parameters is not nil if Parameters is empty
 		params := def.Parameters
 		if params == nil {
 			params = []InputParameterDef{} // Return empty array instead of null
 		}
-		scriptResponses[i] = ScriptResponse{
+		scriptResponses = append(scriptResponses, ScriptResponse{
 			Name:       def.Name,
 			Parameters: params,
-		}
+			Tags:       def.Tags,
+			Version:    def.Version,
+			Deprecated: def.Deprecated,
+			Warning:    deprecationWarning(def),
+		})
 	}
 
-	// Manually marshal the new response structure to JSON bytes
-	jsonData, err := json.Marshal(scriptResponses)
+	// Manually marshal the new response structure to JSON bytes, applying the negotiated field naming
+	jsonData, err := marshalWithNaming(scriptResponses, negotiateNaming(c))
 	if err != nil {
-		log.Printf("Error marshaling script responses to JSON: %v", err)
+		logger.Error().Msgf("Error marshaling script responses to JSON: %v", err)
 		c.Header("Content-Type", "application/json; charset=utf-8")
 		c.String(http.StatusInternalServerError, "[]")
 		return
@@ -259,76 +982,127 @@ func listScripts(c *gin.Context) {
 // --- Process Tracking Helpers ---
 var httpClient = &http.Client{Timeout: 10 * time.Second}
 
+// monitorsProcess reports whether executions of a script are reported to process tracking: as set
+// by its monitorProcess, or MONITOR_PROCESS_DEFAULT when the definition leaves it unset.
+func monitorsProcess(config *Config, def *ScriptDefinition) bool {
+	if def.MonitorProcess != nil {
+		return *def.MonitorProcess
+	}
+	return config.MonitorProcessDefault
+}
+
+// deprecationWarning returns the deprecation notice of a deprecated script, or "" if it isn't deprecated.
+func deprecationWarning(def *ScriptDefinition) string {
+	if !def.Deprecated {
+		return ""
+	}
+	if def.DeprecationMessage != "" {
+		return fmt.Sprintf("Script '%s' is deprecated: %s", def.Name, def.DeprecationMessage)
+	}
+	return fmt.Sprintf("Script '%s' is deprecated and may be removed in a future release", def.Name)
+}
+
+// scriptHasTags reports whether the script carries every one of the given tags (case-insensitive).
+func scriptHasTags(def *ScriptDefinition, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, scriptTag := range def.Tags {
+			if strings.EqualFold(scriptTag, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// scriptMatchesSearch reports whether a lower-cased search term occurs in the script's name, ID,
+// description or tags. An empty search matches every script.
+func scriptMatchesSearch(def *ScriptDefinition, search string) bool {
+	if search == "" {
+		return true
+	}
+	fields := append([]string{def.Name, def.ID, def.Description}, def.Tags...)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
+}
+
 // notifyProcessTrackingCreate sends the initial creation request SYNCHRONOUSLY
 // and returns the numeric ProcessID from the response header.
-func notifyProcessTrackingCreate(config *Config, payload ProcessTrackingCreatePayload) (int64, error) {
+func notifyProcessTrackingCreate(ctx context.Context, config *Config, executionID string, payload ProcessTrackingCreatePayload) (numericProcessID int64, err error) {
+	ctx, span := tracer().Start(ctx, "processTracking.create", trace.WithAttributes(attribute.String("tracking.id", payload.TrackingID)))
+	defer func() { endSpan(span, err) }()
+	xlog := executionLog(ctx)
+
 	if config.ProcessTrackingURL == "" {
-		log.Printf("[ProcessTracking CREATE] Skipping creation for TrackingID %s: PROCESS_TRACKING_SERVICE_URL not set.", payload.TrackingID)
-		return 0, fmt.Errorf("process tracking URL not configured") // Return error as creation is required
+		// No tracking service: track in the execution history store instead
+		return createBuiltinProcessRecord(ctx, executionID, payload)
 	}
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("[ProcessTracking CREATE] Error marshaling payload for TrackingID %s: %v", payload.TrackingID, err)
+		xlog.Error().Msgf("[ProcessTracking CREATE] Error marshaling payload for TrackingID %s: %v", payload.TrackingID, err)
 		return 0, fmt.Errorf("failed to marshal create payload: %w", err)
 	}
 
-	// POST to base URL
-	req, err := http.NewRequest("POST", config.ProcessTrackingURL, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		log.Printf("[ProcessTracking CREATE] Error creating request for TrackingID %s: %v", payload.TrackingID, err)
-		return 0, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	// TODO: Add Cookie header if needed, based on Java impl: headers.set(HttpHeaders.COOKIE, "rights=1; rights_0=" + cookie);
-
-	log.Printf("[ProcessTracking CREATE] Sending creation request for Name: %s, TrackingID: %s, Stage: %s", payload.Name, payload.TrackingID, payload.Stage)
-	resp, err := httpClient.Do(req)
+	// POST to base URL, retrying transient failures
+	xlog.Info().Msgf("[ProcessTracking CREATE] Sending creation request for Name: %s, Stage: %s", payload.Name, payload.Stage)
+	resp, bodyBytes, err := sendProcessTrackingRequest(ctx, config, "CREATE", config.ProcessTrackingURL, payloadBytes)
 	if err != nil {
-		log.Printf("[ProcessTracking CREATE] Error sending notification for TrackingID %s: %v", payload.TrackingID, err)
+		xlog.Error().Msgf("[ProcessTracking CREATE] Error sending notification for TrackingID %s: %v", payload.TrackingID, err)
 		return 0, fmt.Errorf("failed to send create request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	bodyBytes, readErr := ioutil.ReadAll(resp.Body) // Read body for logging context
-	if readErr != nil {
-		log.Printf("[ProcessTracking CREATE] Failed to read response body for TrackingID %s after status %d: %v", payload.TrackingID, resp.StatusCode, readErr)
-		// Still might have the header, but log the read error
-	}
 
 	// Expect 201 CREATED
 	if resp.StatusCode != http.StatusCreated {
-		log.Printf("[ProcessTracking CREATE] Notification failed for TrackingID %s: Expected Status 201, Got %d, Body: %s", payload.TrackingID, resp.StatusCode, string(bodyBytes))
+		xlog.Error().Msgf("[ProcessTracking CREATE] Notification failed for TrackingID %s: Expected Status 201, Got %d, Body: %s", payload.TrackingID, resp.StatusCode, string(bodyBytes))
 		return 0, fmt.Errorf("create request failed with status %d", resp.StatusCode)
 	}
 
 	// Get numeric ID from 'processid' header
 	processIDHeader := resp.Header.Get("processid")
 	if processIDHeader == "" {
-		log.Printf("[ProcessTracking CREATE] Notification success (Status 201) but 'processid' header missing or empty for TrackingID %s. Body: %s", payload.TrackingID, string(bodyBytes))
+		xlog.Warn().Msgf("[ProcessTracking CREATE] Notification success (Status 201) but 'processid' header missing or empty for TrackingID %s. Body: %s", payload.TrackingID, string(bodyBytes))
 		return 0, fmt.Errorf("'processid' header missing in create response")
 	}
 
 	numericProcessID, parseErr := strconv.ParseInt(processIDHeader, 10, 64)
+	span.SetAttributes(attribute.Int64("process.id", numericProcessID))
 	if parseErr != nil {
-		log.Printf("[ProcessTracking CREATE] Failed to parse 'processid' header value '%s' to int64 for TrackingID %s: %v", processIDHeader, payload.TrackingID, parseErr)
+		xlog.Error().Msgf("[ProcessTracking CREATE] Failed to parse 'processid' header value '%s' to int64 for TrackingID %s: %v", processIDHeader, payload.TrackingID, parseErr)
 		return 0, fmt.Errorf("failed to parse 'processid' header: %w", parseErr)
 	}
 
 	if numericProcessID == 0 {
 		// This case might be valid depending on the backend, but log a warning
-		log.Printf("[ProcessTracking CREATE] Warning: Received 'processid' header value was 0 for TrackingID %s.", payload.TrackingID)
+		xlog.Warn().Msgf("[ProcessTracking CREATE] Received 'processid' header value was 0 for TrackingID %s.", payload.TrackingID)
 	}
 
-	log.Printf("[ProcessTracking CREATE] Notification successful for TrackingID %s. Received numeric ProcessID: %d", payload.TrackingID, numericProcessID)
+	xlog.Info().Msgf("[ProcessTracking CREATE] Notification successful for TrackingID %s. Received numeric ProcessID: %d", payload.TrackingID, numericProcessID)
 	return numericProcessID, nil // Return the numeric ID from header
 }
 
 // notifyProcessTrackingUpdate sends the final status update using the numeric ProcessID obtained from creation.
-func notifyProcessTrackingUpdate(config *Config, numericProcessID int64, payload ProcessTrackingUpdatePayload) {
-	// Skip if URL not set OR if the numericProcessID is zero (indicating creation failed or header was missing/invalid)
-	if config.ProcessTrackingURL == "" || numericProcessID == 0 {
-		log.Printf("[ProcessTracking UPDATE] Skipping notification for numeric ProcessID %d: URL not set or ProcessID is zero.", numericProcessID)
+func notifyProcessTrackingUpdate(ctx context.Context, config *Config, numericProcessID int64, payload ProcessTrackingUpdatePayload) {
+	// Skip if the numericProcessID is zero (indicating creation failed, header was missing/invalid, or tracking is off)
+	xlog := executionLog(ctx)
+	if numericProcessID == 0 {
+		xlog.Info().Msgf("[ProcessTracking UPDATE] Skipping notification for status '%s': no ProcessID.", payload.Status)
+		return
+	}
+	if config.ProcessTrackingURL == "" {
+		updateBuiltinProcessRecord(ctx, numericProcessID, payload)
+		return
+	}
+	if !trackingThrottle.allow(ctx, config, numericProcessID, payload) {
+		xlog.Debug().Msgf("[ProcessTracking UPDATE] Holding back PROGRESS update of numeric ProcessID %d: PROCESS_TRACKING_MAX_UPDATES_PER_MINUTE reached.", numericProcessID)
 		return
 	}
 
@@ -342,178 +1116,563 @@ func notifyProcessTrackingUpdate(config *Config, numericProcessID int64, payload
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("[ProcessTracking UPDATE] Error marshaling payload for numeric ProcessID %d: %v", numericProcessID, err)
+		xlog.Error().Msgf("[ProcessTracking UPDATE] Error marshaling payload for numeric ProcessID %d: %v", numericProcessID, err)
 		return
 	}
 
-	// Construct the specific update URL using the numeric ID
-	processIDStr := strconv.FormatInt(numericProcessID, 10)
-	updateURL := strings.TrimSuffix(config.ProcessTrackingURL, "/") + "/" + processIDStr
+	ctx, span := tracer().Start(ctx, "processTracking.update", trace.WithAttributes(
+		attribute.Int64("process.id", numericProcessID), attribute.String("process.status", payload.Status)))
+	defer span.End()
 
-	// POST to /{id}
-	req, err := http.NewRequest("POST", updateURL, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		log.Printf("[ProcessTracking UPDATE] Error creating request for numeric ProcessID %d: %v", numericProcessID, err)
+	// Queue behind earlier updates still waiting in the outbox, keeping the statuses in order
+	if processTrackingOutbox.pending(numericProcessID) {
+		xlog.Warn().Msgf("[ProcessTracking UPDATE] Earlier updates of numeric ProcessID %d are pending in the outbox; queueing status '%s' behind them.", numericProcessID, payload.Status)
+		if err := processTrackingOutbox.add(numericProcessID, payloadBytes); err != nil {
+			xlog.Error().Msgf("[ProcessTracking UPDATE] Failed to queue update for numeric ProcessID %d in the outbox: %v", numericProcessID, err)
+		}
 		return
 	}
-	req.Header.Set("Content-Type", "application/json")
-	// TODO: Add Cookie header if needed
 
-	log.Printf("[ProcessTracking UPDATE] Sending status '%s' (Level: %s) for numeric ProcessID %d to %s", payload.Status, payload.MessageLevel, numericProcessID, updateURL)
-	resp, err := httpClient.Do(req)
+	xlog.Info().Msgf("[ProcessTracking UPDATE] Sending status '%s' (Level: %s) for numeric ProcessID %d", payload.Status, payload.MessageLevel, numericProcessID)
+	retryable, err := deliverProcessTrackingUpdate(ctx, config, numericProcessID, payloadBytes)
 	if err != nil {
-		log.Printf("[ProcessTracking UPDATE] Error sending notification for numeric ProcessID %d: %v", numericProcessID, err)
+		xlog.Error().Msgf("[ProcessTracking UPDATE] Notification failed for numeric ProcessID %d: %v", numericProcessID, err)
+		endSpan(span, err)
+		if retryable && processTrackingOutbox != nil {
+			if err := processTrackingOutbox.add(numericProcessID, payloadBytes); err != nil {
+				xlog.Error().Msgf("[ProcessTracking UPDATE] Failed to queue update for numeric ProcessID %d in the outbox: %v", numericProcessID, err)
+			} else {
+				xlog.Warn().Msgf("[ProcessTracking UPDATE] Queued status '%s' for numeric ProcessID %d in the outbox for background delivery.", payload.Status, numericProcessID)
+			}
+		}
 		return
 	}
-	defer resp.Body.Close()
+	xlog.Info().Msgf("[ProcessTracking UPDATE] Notification successful for numeric ProcessID %d (Status: %s)", numericProcessID, payload.Status)
+}
 
-	// Expect 200 OK
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		log.Printf("[ProcessTracking UPDATE] Notification failed for numeric ProcessID %d: Expected Status 200, Got %d, Body: %s", numericProcessID, resp.StatusCode, string(bodyBytes))
-	} else {
-		log.Printf("[ProcessTracking UPDATE] Notification successful for numeric ProcessID %d (Status: %s)", numericProcessID, payload.Status)
+// executionOutcome is the HTTP-independent result of running a TaskServiceRequest,
+// so the same execution flow can back several endpoints.
+type executionOutcome struct {
+	StatusCode  int
+	Status      string // WARNING or SKIPPED when the script's exit code mapped to one, else empty
+	Output      string // Output of a script that ran, for pipelines verifying their canary node
+	Category    string // Failure category of an execution that started and failed, see failure*
+	Body        gin.H  // nil for a bare status response with no body
+	ProcessID   int64  // Returned as the X-ProcessId header when non-zero
+	ExecutionID string // Returned as the X-Execution-Id header when the execution started
+}
+
+// writeOutcome writes an executionOutcome as the HTTP response.
+func writeOutcome(c *gin.Context, outcome executionOutcome) {
+	if outcome.ProcessID > 0 {
+		c.Header("X-ProcessId", strconv.FormatInt(outcome.ProcessID, 10))
+	}
+	if outcome.ExecutionID != "" {
+		c.Header("X-Execution-Id", outcome.ExecutionID)
+	}
+	if outcome.Body == nil {
+		c.Status(outcome.StatusCode)
+		return
 	}
+	writeJSON(c, outcome.StatusCode, outcome.Body)
 }
 
 // executeScript handles the /v1/execute endpoint, integrating Process Tracking.
 func executeScript(c *gin.Context) {
-	config := loadConfig()
+	config := configFromContext(c)
 	var request TaskServiceRequest
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+	if err := bindJSONWithNaming(c, &request); err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, "Invalid request body: "+err.Error()))
 		return
 	}
+	writeOutcome(c, runTask(c.Request.Context(), config, request, executionOptions{Caller: callerFromContext(c), ClientIP: c.ClientIP()}))
+}
+
+// executionOptions modify how runTask handles a request
+type executionOptions struct {
+	// DryRun resolves the script, validates its parameters, renders its command and selects its target
+	// pod, but executes and records nothing; the rendered execution is returned as the response body
+	DryRun   bool
+	Caller   Caller // Identity the execution runs on behalf of
+	ClientIP string // Remote address of the HTTP request, for the audit log
+	RerunOf  string // Execution this one re-runs (POST /v1/executions/:id/rerun)
+	// Scheduled is set only by the internal scheduler, never from a request: scheduled runs bypass
+	// allowedCallers/allowedGroups and caller quotas, since the schedule is part of the definition
+	Scheduled bool
+}
+
+// startedMessage is the default PROGRESS message of a starting execution.
+func startedMessage(opts executionOptions) string {
+	if opts.RerunOf != "" {
+		return fmt.Sprintf("Script execution starting (re-run of execution %s)", opts.RerunOf)
+	}
+	return "Script execution starting"
+}
+
+// runTask resolves the requested script, reports to Process Tracking, runs the script in the
+// target pod and returns the response to send to the caller. Dry runs (opts.DryRun) stop short of
+// executing and recording anything.
+func runTask(ctx context.Context, config *Config, request TaskServiceRequest, opts executionOptions) (outcome executionOutcome) {
+	dryRun := opts.DryRun
+	ctx, span := tracer().Start(ctx, "executeScript", trace.WithAttributes(
+		attribute.String("task.name", request.TaskName), attribute.String("caller", opts.Caller.Name), attribute.Bool("dry_run", dryRun)))
+	defer func() {
+		span.SetAttributes(attribute.Int("outcome.status_code", outcome.StatusCode))
+		if outcome.StatusCode >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("%v", outcome.Body["error"]))
+		}
+		span.End()
+	}()
+
+	// Every attempt is audited and counted with its final outcome, including rejected ones.
+	// Executions that started also notify the script's chat channels and callback URLs, and publish
+	// start and finish events.
+	started := time.Now()
+	var metricScript string                 // Set once the script definition is resolved
+	var startedDefinition *ScriptDefinition // Set once the execution passed all checks and started
+	var completion completionEvent
+	audit := newAuditEvent(request, opts)
+	// Executions are identified by a UUIDv7, recorded (and returned as X-Execution-Id) once started
+	executionID := newRecordID()
+	defer func() {
+		audit.finish(outcome, dryRun)
+		recordExecutionMetrics(metricScript, outcome, dryRun, started)
+		if startedDefinition != nil {
+			outcome.ExecutionID = executionID
+			completion.Successful = outcome.StatusCode == http.StatusOK
+			completion.Status = executionStatusFailed
+			if completion.Successful {
+				completion.Status = executionStatusSuccessful
+				if outcome.Status != "" {
+					completion.Status = outcome.Status
+				}
+			}
+			completion.Duration = time.Since(started).Round(time.Millisecond)
+			completion.ProcessID = outcome.ProcessID
+			completion.Pod = audit.TargetPod
+			if errMsg, ok := outcome.Body["error"].(string); ok {
+				completion.Error = errMsg
+			}
+			if completion.Status == executionStatusSuccessful {
+				lastSuccesses.record(startedDefinition.Name, completion.ExecutionID, outcome.ProcessID)
+			}
+			sendCompletionNotifications(config, startedDefinition, completion)
+			sendCompletionCallbacks(config, startedDefinition, request.CallbackURL, completion)
+			publishExecutionEvent(config, executionFinishedEventType, completion)
+		}
+	}()
 
 	// Dump the entire request for debugging
 	requestJSON, _ := json.MarshalIndent(request, "", "  ")
-	log.Printf("DEBUG - Full request received: %s", string(requestJSON))
+	logger.Debug().Msgf("Full request received: %s", string(requestJSON))
 
 	// --- Use Tracking ID from Request BODY ---
 	bodyTrackingID := request.TrackingID
 	if bodyTrackingID == "" {
-		// Generate a unique tracking ID if not provided - using timestamp
-		bodyTrackingID = fmt.Sprintf("%d", time.Now().UnixNano())
-		log.Printf("Auto-generated TrackingID '%s' because request TrackingID was empty.", bodyTrackingID)
+		// Without a tracking ID from the caller, the execution ID serves as one
+		bodyTrackingID = executionID
+		logger.Info().Msgf("Auto-generated TrackingID '%s' because request TrackingID was empty.", bodyTrackingID)
 	}
-	log.Printf("Received execute request. Body TrackingID: '%s'", bodyTrackingID)
+	// Every log entry of this execution carries its correlation fields; helpers get them through ctx
+	xlog := &executionLogger{TrackingID: bodyTrackingID}
+	ctx = withExecutionLogger(ctx, xlog)
+	xlog.Info().Msgf("Received execute request. Body TrackingID: '%s'", bodyTrackingID)
+	audit.TrackingID = bodyTrackingID
 
 	// Extract actual script name
 	scriptNameInterface, nameOk := request.TaskData["name"]
 	if !nameOk {
-		log.Printf("ERROR: taskData is missing the 'name' field. TrackingID: %s", bodyTrackingID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "taskData must contain a 'name' field specifying the script to run"})
-		return
+		xlog.Error().Msg("taskData is missing the 'name' field")
+		return executionOutcome{StatusCode: http.StatusBadRequest, Body: problem(http.StatusBadRequest, codeInvalidRequest, "taskData must contain a 'name' field specifying the script to run")}
 	}
 	actualScriptName, nameIsString := scriptNameInterface.(string)
+	audit.Script = actualScriptName
+	xlog.Script = actualScriptName
 	if !nameIsString || actualScriptName == "" {
-		log.Printf("ERROR: taskData 'name' field is not a non-empty string ('%v'). TrackingID: %s", scriptNameInterface, bodyTrackingID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "taskData 'name' field must be a non-empty string"})
-		return
+		xlog.Error().Msgf("taskData 'name' field is not a non-empty string ('%v')", scriptNameInterface)
+		return executionOutcome{StatusCode: http.StatusBadRequest, Body: problem(http.StatusBadRequest, codeInvalidRequest, "taskData 'name' field must be a non-empty string")}
+	}
+	if request.CallbackURL != "" {
+		if err := checkCallbackURL(config, request.CallbackURL); err != nil {
+			xlog.Warn().Msgf("Execute request rejected: %v", err)
+			return executionOutcome{StatusCode: http.StatusBadRequest, Body: problem(http.StatusBadRequest, codeInvalidRequest, err.Error())}
+		}
 	}
 
 	// Load script definitions - need to do this earlier to access the script's stage
-	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	_, loadSpan := tracer().Start(ctx, "loadScriptDefinitions")
+	registry, err := currentScripts(config.ScriptsPath)
+	endSpan(loadSpan, err)
 	if err != nil {
-		log.Printf("Error loading script definitions during execute: %v, TrackingID: %s", err, bodyTrackingID)
+		xlog.Error().Msgf("Error loading script definitions during execute: %v", err)
 		statusCode := http.StatusInternalServerError
 		errMsgStr := fmt.Sprintf("Failed to load script definitions: %v", err)
 		if os.IsNotExist(err) {
 			errMsgStr = fmt.Sprintf("Server configuration error: Script definitions file not found at %s", config.ScriptsPath)
 		}
-		c.JSON(statusCode, gin.H{"error": errMsgStr})
-		return
+		return executionOutcome{StatusCode: statusCode, Body: problem(statusCode, codeDefinitionsInvalid, errMsgStr)}
 	}
 
-	// Find the requested script definition
-	var selectedDefinition *ScriptDefinition
-	for i := range definitions {
-		// Match against the name extracted from taskData.name
-		if definitions[i].Name == actualScriptName {
-			selectedDefinition = &definitions[i]
-			break
+	// Find the requested script definition, matching against the name extracted from taskData.name
+	// and the pinned version if one was requested
+	selectedDefinition := registry.find(actualScriptName, request.Version)
+	if selectedDefinition == nil && request.Version != "" {
+		xlog.Warn().Msgf("Execute request failed: Script '%s' has no version '%s'", actualScriptName, request.Version)
+		return executionOutcome{StatusCode: http.StatusNotFound, Body: problem(http.StatusNotFound, codeScriptNotFound, fmt.Sprintf("Script '%s' version '%s' not found", actualScriptName, request.Version))}
+	}
+	if selectedDefinition == nil {
+		xlog.Warn().Msgf("Execute request failed: Script with name '%s' (from taskData) not found in definitions", actualScriptName)
+		return executionOutcome{StatusCode: http.StatusNotFound, Body: problem(http.StatusNotFound, codeScriptNotFound, fmt.Sprintf("Script '%s' not found", actualScriptName))}
+	}
+
+	xlog.Info().Msgf("Found definition for script '%s' (ID: %s, version: '%s')", selectedDefinition.Name, selectedDefinition.ID, selectedDefinition.Version)
+	metricScript = selectedDefinition.Name
+	xlog.Script = selectedDefinition.Name
+	span.SetAttributes(attribute.String("script.id", selectedDefinition.ID), attribute.String("script.name", selectedDefinition.Name))
+
+	// Re-check the command policy at execute time against the policy loaded with the definitions
+	if err := registry.policy.checkDefinition(selectedDefinition); err != nil {
+		xlog.Warn().Msgf("Execute request rejected: Script '%s' fails the command policy: %v", selectedDefinition.Name, err)
+		return executionOutcome{StatusCode: http.StatusForbidden, Body: problem(http.StatusForbidden, codePolicyDenied, fmt.Sprintf("Script '%s' is not allowed by the command policy: %v", actualScriptName, err))}
+	}
+
+	if !opts.Scheduled {
+		if err := authorizeCaller(selectedDefinition, opts.Caller); err != nil {
+			xlog.Warn().Msgf("Execute request rejected: %v", err)
+			return executionOutcome{StatusCode: http.StatusForbidden, Body: problem(http.StatusForbidden, codeForbidden, err.Error())}
 		}
 	}
 
-	if selectedDefinition == nil {
-		log.Printf("Execute request failed: Script with name '%s' (from taskData) not found in definitions. TrackingID: %s", actualScriptName, bodyTrackingID)
-		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Script '%s' not found", actualScriptName)})
-		return
+	// Optional external policy decision (OPA) on the script, parameters and caller
+	namespace := config.Namespace
+	if selectedDefinition.TektonNamespace != "" && selectedDefinition.TektonPipeline != "" {
+		namespace = selectedDefinition.TektonNamespace
+	}
+	if err := evaluateOPAPolicy(config, opaInput{
+		Script: opaScript{
+			ID:      selectedDefinition.ID,
+			Name:    selectedDefinition.Name,
+			Version: selectedDefinition.Version,
+			Tags:    selectedDefinition.Tags,
+			Command: selectedDefinition.Command,
+		},
+		Parameters: request.TaskData,
+		Caller:     opts.Caller,
+		Namespace:  namespace,
+		TaskName:   request.TaskName,
+		TrackingID: bodyTrackingID,
+		DryRun:     dryRun,
+	}); err != nil {
+		xlog.Warn().Msgf("Execute request denied by policy for script '%s' (caller: %s): %v", selectedDefinition.Name, opts.Caller.Name, err)
+		return executionOutcome{StatusCode: http.StatusForbidden, Body: problemWith(http.StatusForbidden, codePolicyDenied, "Execution denied by policy", gin.H{"reason": err.Error()})}
+	}
+
+	if selectedDefinition.Disabled {
+		xlog.Warn().Msgf("Execute request rejected: Script '%s' is disabled", selectedDefinition.Name)
+		return executionOutcome{StatusCode: http.StatusGone, Body: problem(http.StatusGone, codeScriptDisabled, fmt.Sprintf("Script '%s' is disabled", actualScriptName))}
+	}
+	if err := checkBackend(config, selectedDefinition); err != nil {
+		xlog.Warn().Msgf("Execute request rejected: %v", err)
+		return executionOutcome{StatusCode: http.StatusBadRequest, Body: problem(http.StatusBadRequest, codeInvalidRequest, err.Error())}
+	}
+	if selectedDefinition.Deprecated {
+		xlog.Warn().Msgf("DEPRECATION: %s (requested by task '%s')", deprecationWarning(selectedDefinition), request.TaskName)
 	}
 
-	log.Printf("Found definition for script '%s' (ID: %s). TrackingID: %s", selectedDefinition.Name, selectedDefinition.ID, bodyTrackingID)
+	// Pipelines run their nodes as separate executions, each with its own process tracking record
+	if len(selectedDefinition.Pipeline) > 0 && dryRun {
+		return executionOutcome{StatusCode: http.StatusOK, Body: gin.H{
+			"dryRun":    true,
+			"taskName":  actualScriptName,
+			"script_id": selectedDefinition.ID,
+			"mode":      executionModeOf(selectedDefinition),
+			"pipeline":  selectedDefinition.Pipeline,
+		}}
+	}
+	completion = completionEvent{
+		Script:     selectedDefinition.Name,
+		Version:    selectedDefinition.Version,
+		Caller:     opts.Caller.Name,
+		TrackingID: bodyTrackingID,
+		Started:    started,
+	}
+	// Record the execution in the history store (no-op when history persistence is disabled).
+	// Dry runs aren't recorded; the later updates then match no row.
+	audit.ScriptVersion = selectedDefinition.Version
+	if !dryRun {
+		audit.ExecutionID = executionID
+		// Maintenance mode freezes all executions; listing scripts and dry runs keep working
+		if message := pausedMessage(); message != "" {
+			xlog.Warn().Msgf("Execute request rejected for script '%s': execution is paused", selectedDefinition.Name)
+			return executionOutcome{StatusCode: http.StatusServiceUnavailable, Body: problemWith(http.StatusServiceUnavailable, codeMaintenance, message, gin.H{"maintenance": true})}
+		}
+		// Permissions revoked since startup fail fast instead of with an exec error
+		if err := checkRevokedPermissions(config, selectedDefinition); err != nil {
+			xlog.Warn().Msgf("Execute request rejected: %v", err)
+			return executionOutcome{StatusCode: http.StatusServiceUnavailable, Body: problem(http.StatusServiceUnavailable, codePermissionsMissing, err.Error())}
+		}
+		// A draining executor refuses new executions; running ones are waited for on shutdown. The
+		// execution ends with the request, e.g. when the client disconnects, or on a cancel request.
+		var cancelExecution context.CancelCauseFunc
+		ctx, cancelExecution = context.WithCancelCause(ctx)
+		defer cancelExecution(nil)
+		if err := inFlightExecutions.begin(executionID, selectedDefinition, cancelExecution); err != nil {
+			xlog.Warn().Msgf("Execute request rejected for script '%s': %v", selectedDefinition.Name, err)
+			return executionOutcome{StatusCode: http.StatusServiceUnavailable, Body: problem(http.StatusServiceUnavailable, codeShuttingDown, err.Error())}
+		}
+		defer inFlightExecutions.end(executionID)
+		// Serialized scripts wait for the running execution of the script on this replica
+		var queueDeadline time.Time
+		if selectedDefinition.Serialize {
+			if config.SerializeMaxWait > 0 {
+				queueDeadline = time.Now().Add(config.SerializeMaxWait)
+			}
+			release, ahead, err := serializedScripts.acquire(ctx, selectedDefinition.Name, config.SerializeMaxWait)
+			if err != nil {
+				xlog.Warn().Msgf("Execute request for script '%s' gave up waiting behind %d execution(s): %v", selectedDefinition.Name, ahead, err)
+				return executionOutcome{StatusCode: http.StatusConflict, Body: problemWith(http.StatusConflict, codeScriptLocked, fmt.Sprintf("Script '%s' is serialized and still busy: %v", actualScriptName, err), gin.H{"queuedBehind": ahead})}
+			}
+			defer release()
+			if ahead > 0 {
+				xlog.Info().Msgf("Script '%s' waited behind %d execution(s) for its turn.", selectedDefinition.Name, ahead)
+			}
+		}
+		// Exclusive scripts hold their Lease for the whole execution; serialized ones wait for it
+		if selectedDefinition.Exclusive {
+			var release func()
+			var err error
+			if selectedDefinition.Serialize {
+				release, err = acquireScriptLockWaiting(ctx, config, selectedDefinition, executionID, queueDeadline)
+			} else {
+				release, err = acquireScriptLock(ctx, config, selectedDefinition, executionID)
+			}
+			var locked *scriptLockedError
+			if errors.Is(err, errQueueTimeout) {
+				xlog.Warn().Msgf("Execute request rejected: %v", err)
+				return executionOutcome{StatusCode: http.StatusConflict, Body: problem(http.StatusConflict, codeScriptLocked, err.Error())}
+			}
+			if errors.As(err, &locked) {
+				xlog.Warn().Msgf("Execute request rejected: %v", err)
+				return executionOutcome{StatusCode: http.StatusConflict, Body: problemWith(http.StatusConflict, codeScriptLocked, err.Error(), gin.H{"holder": locked.Holder})}
+			}
+			if err != nil {
+				xlog.Error().Msgf("Execute request failed for script '%s': %v", selectedDefinition.Name, err)
+				return executionOutcome{StatusCode: http.StatusInternalServerError, Body: problem(http.StatusInternalServerError, codeInternal, err.Error())}
+			}
+			defer release()
+		}
+		// A script that already succeeded within its minimum interval isn't run again; checked after
+		// waiting for a lock, so a duplicate queued behind the first run sees it
+		previous, err := checkMinInterval(selectedDefinition)
+		if err != nil {
+			xlog.Error().Msgf("Execute request failed: %v", err)
+			return executionOutcome{StatusCode: http.StatusInternalServerError, Body: problem(http.StatusInternalServerError, codeInternal, err.Error())}
+		}
+		if previous != nil {
+			xlog.Warn().Msgf("Skipping script '%s': it last succeeded at %s (execution %s), within its minimum interval of %ds.",
+				selectedDefinition.Name, previous.FinishedAt.Format(time.RFC3339), previous.ExecutionID, selectedDefinition.MinIntervalSeconds)
+			return executionOutcome{StatusCode: http.StatusOK, Body: gin.H{
+				"skipped":           true,
+				"taskName":          actualScriptName,
+				"script_id":         selectedDefinition.ID,
+				"reason":            fmt.Sprintf("Script '%s' already succeeded within its minimum interval of %d seconds", actualScriptName, selectedDefinition.MinIntervalSeconds),
+				"previousExecution": previous,
+			}, ProcessID: previous.ProcessID}
+		}
+		// Quotas are checked and the start recorded atomically, so the start counts against them
+		err = quotas.acquire(config, selectedDefinition, opts.Caller, opts.Scheduled, func() {
+			executionStore.RecordStart(executionID, bodyTrackingID, request.TaskName, opts.Caller.Name, selectedDefinition)
+		})
+		if err == nil {
+			executionStore.RecordRequest(executionID, redactedRequest(request, sensitiveParameters(registry.definitions, selectedDefinition)), opts.RerunOf)
+		}
+		if err != nil {
+			xlog.Warn().Msgf("Execute request rejected for script '%s': %v", selectedDefinition.Name, err)
+			return executionOutcome{StatusCode: http.StatusTooManyRequests, Body: problem(http.StatusTooManyRequests, codeQuotaExceeded, fmt.Sprintf("Quota exceeded: %v", err))}
+		}
+		startedDefinition = selectedDefinition
+		completion.ExecutionID = executionID
+		publishExecutionEvent(config, executionStartedEventType, completion)
+		// The pipeline's own execution passed the same checks and is in flight like a script's, so
+		// cancelling it or a shutdown stops its nodes
+		if len(selectedDefinition.Pipeline) > 0 {
+			return runPipeline(ctx, config, request, selectedDefinition, executionID, bodyTrackingID, opts)
+		}
+	}
 
-	// Skip process tracking if monitorProcess is explicitly set to false
-	if !selectedDefinition.MonitorProcess {
-		log.Printf("Process tracking disabled for script '%s', skipping tracking. TrackingID: %s", selectedDefinition.Name, bodyTrackingID)
+	// Skip process tracking if monitorProcess is false, explicitly or by MONITOR_PROCESS_DEFAULT
+	monitorProcess := monitorsProcess(config, selectedDefinition)
+	if !monitorProcess {
+		xlog.Info().Msgf("Process tracking disabled for script '%s', skipping tracking", selectedDefinition.Name)
 	}
 
 	// --- Process Tracking Start ---
 	var numericProcessID int64 = 0
-	if selectedDefinition.MonitorProcess || selectedDefinition.MonitorProcess == false /* default to true if not specified */ {
-		// Determine stage to use: prefer script-specific stage if provided, fall back to config
+	trackingMessages := selectedDefinition.TrackingMessages
+	trackingData := trackingMessageData{
+		Script:     selectedDefinition.Name,
+		Version:    selectedDefinition.Version,
+		TaskName:   request.TaskName,
+		TrackingID: bodyTrackingID,
+		RerunOf:    opts.RerunOf,
+	}
+	if !dryRun && monitorProcess {
+		// Determine stage to use: the request's, then the script's, then the config default
 		stage := config.ProcessTrackingStage // Default from config
-		if selectedDefinition.Stage != "" {
+		if request.Stage != "" {
+			stage = request.Stage // The calling task knows which pipeline stage it runs in
+			xlog.Info().Msgf("Using request stage '%s' for process tracking", stage)
+		} else if selectedDefinition.Stage != "" {
 			stage = selectedDefinition.Stage // Override with script-specific stage
-			log.Printf("Using script-specific stage '%s' for process tracking. TrackingID: %s", stage, bodyTrackingID)
+			xlog.Info().Msgf("Using script-specific stage '%s' for process tracking", stage)
 		}
+		trackingData.Stage = stage
 
 		// Create the process record SYNCHRONOUSLY to get the numeric ID from the header
 		var createErr error
-		numericProcessID, createErr = notifyProcessTrackingCreate(config, ProcessTrackingCreatePayload{
-			Name:       request.TaskName,
+		numericProcessID, createErr = notifyProcessTrackingCreate(ctx, config, executionID, ProcessTrackingCreatePayload{
+			Name:       trackingMessages.render(ctx, "name", trackingData, request.TaskName),
 			TrackingID: bodyTrackingID,
 			Stage:      stage, // Use script-specific stage or config default
 		})
 
 		if createErr != nil {
 			// Log the creation error and fail the request
-			log.Printf("ERROR: Failed to create initial process tracking record for script '%s', Body TrackingID '%s': %v", actualScriptName, bodyTrackingID, createErr)
+			xlog.Error().Msgf("Failed to create initial process tracking record for script '%s', Body TrackingID '%s': %v", actualScriptName, bodyTrackingID, createErr)
 			// Do NOT send an update notification here, as creation failed.
 			// Return a server error. Do not set X-ProcessId header as we didn't get one.
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to initialize process tracking: %v", createErr)})
-			return
+			executionStore.RecordFinish(executionID, "", executionStatusFailed, "", fmt.Sprintf("Failed to initialize process tracking: %v", createErr))
+			executionStore.RecordFailureCategory(executionID, failureTrackingError)
+			return executionOutcome{
+				StatusCode: http.StatusInternalServerError,
+				Category:   failureTrackingError,
+				Body: problemWith(http.StatusInternalServerError, codeTrackingFailed, fmt.Sprintf("Failed to initialize process tracking: %v", createErr),
+					gin.H{"category": failureTrackingError}),
+			}
 		}
 
 		// If we reach here, creation was successful and numericProcessID holds the ID from the header.
-		log.Printf("Successfully created process tracking record. Numeric ProcessID: %d", numericProcessID)
+		xlog.ProcessID = numericProcessID
+		xlog.Info().Msgf("Successfully created process tracking record. Numeric ProcessID: %d", numericProcessID)
+		executionStore.RecordProcessID(executionID, numericProcessID)
+		inFlightExecutions.setProcessID(executionID, numericProcessID)
 
 		// Send a 'PROGRESS' update immediately after successful creation
-		notifyProcessTrackingUpdate(config, numericProcessID, ProcessTrackingUpdatePayload{
+		notifyProcessTrackingUpdate(ctx, config, numericProcessID, ProcessTrackingUpdatePayload{
 			Status:  "PROGRESS",
-			Message: "Script execution starting",
+			Message: trackingMessages.render(ctx, "started", trackingData, startedMessage(opts)),
 			// MessageLevel will be set to INFO inside notifyProcessTrackingUpdate
 		})
 	}
 
 	// --- Resume normal execution flow ---
-	log.Printf("Extracted actual script name '%s' from taskData. TrackingID: %s", actualScriptName, request.TrackingID)
+	xlog.Info().Msgf("Extracted actual script name '%s' from taskData", actualScriptName)
+
+	// Get the target pod from the script's ordered selectors, optionally waiting for one to become ready
+	podSelectors := podSelectorsFor(selectedDefinition, config)
+	var matchedSelector string
+	selectTargetPod := func() (podName string, err error) {
+		_, selectSpan := tracer().Start(ctx, "selectTargetPod", trace.WithAttributes(attribute.StringSlice("pod.selectors", podSelectors)))
+		defer func() {
+			selectSpan.SetAttributes(attribute.String("pod.name", podName))
+			endSpan(selectSpan, err)
+		}()
+		if selectedDefinition.WaitForPodReadySeconds > 0 {
+			waitTimeout := time.Duration(selectedDefinition.WaitForPodReadySeconds) * time.Second
+			xlog.Info().Msgf("Waiting up to %s for a ready pod for script '%s'", waitTimeout, selectedDefinition.Name)
+			podName, matchedSelector, err = waitForReadyPod(config.Namespace, podSelectors, waitTimeout)
+		} else {
+			podName, matchedSelector, err = findPod(config.Namespace, podSelectors, false)
+		}
+		return podName, err
+	}
 
-	// Get the target pod
-	targetPod, err := getTargetPod(config.Namespace, config.PodLabelSelector)
+	// With sticky affinity, executions sharing a caller-supplied TrackingID reuse the pod of the first one
+	// Backends that create their own pod (or don't run in Kubernetes) need no workload pod
+	backend := backendOf(config, selectedDefinition)
+	executor := executors[backend]
+	usesWorkloadPod := executor.UsesWorkloadPod()
+	nodeTargeted := isNodeTargeted(selectedDefinition)
+	affinityKey := ""
+	if config.StickyPodAffinity && request.TrackingID != "" && usesWorkloadPod {
+		affinityKey = podAffinityKey(request.TrackingID, config.Namespace, podSelectors)
+	}
+	var targetPod string
+	if pinnedPod, pinned := stickyPods.Get(affinityKey); affinityKey != "" && pinned {
+		running, checkErr := isPodRunning(config.Namespace, pinnedPod)
+		if checkErr == nil && running {
+			targetPod = pinnedPod
+			matchedSelector = "(sticky affinity)"
+			xlog.Info().Msgf("Using pod '%s' pinned to TrackingID '%s' by sticky affinity.", pinnedPod, request.TrackingID)
+		} else {
+			xlog.Warn().Msgf("Pod '%s' pinned to TrackingID '%s' is no longer running (err: %v); selecting a new pod. Files written by earlier steps are not available", pinnedPod, request.TrackingID, checkErr)
+		}
+	}
+	if targetPod == "" && usesWorkloadPod {
+		targetPod, err = selectTargetPod()
+	}
 	if err != nil {
-		log.Printf("Execute request failed for script '%s': Could not get target pod: %v. TrackingID: %s", selectedDefinition.Name, err, request.TrackingID)
+		xlog.Warn().Msgf("Execute request failed for script '%s': Could not get target pod: %v", selectedDefinition.Name, err)
 		// Send FAILED status UPDATE using the OBTAINED numeric ID if process tracking is enabled
 		if numericProcessID > 0 {
-			notifyProcessTrackingUpdate(config, numericProcessID, ProcessTrackingUpdatePayload{Status: "FAILED", Message: fmt.Sprintf("Failed to find target pod: %v", err)})
-			// Set Header and return error response
-			c.Header("X-ProcessId", strconv.FormatInt(numericProcessID, 10))
+			trackingData.Error = fmt.Sprintf("Failed to find target pod: %v", err)
+			trackingData.ExitCode = "unknown"
+			trackingData.Duration = time.Since(started).Round(time.Millisecond)
+			notifyProcessTrackingUpdate(ctx, config, numericProcessID, withOutcomeMetadata(config, ProcessTrackingUpdatePayload{
+				Status:  "FAILED",
+				Message: trackingMessages.render(ctx, "failed", trackingData, trackingData.Error),
+			}, trackingData))
+		}
+		category := classifyPodSelectionFailure(err)
+		executionStore.RecordFinish(executionID, "", executionStatusFailed, "", fmt.Sprintf("Failed to find target pod: %v", err))
+		executionStore.RecordFailureCategory(executionID, category)
+		return executionOutcome{
+			StatusCode: http.StatusInternalServerError,
+			Category:   category,
+			Body:       problemWith(http.StatusInternalServerError, codePodNotFound, fmt.Sprintf("Failed to find target pod: %v", err), gin.H{"category": category}),
+			ProcessID:  numericProcessID,
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to find target pod: %v", err)})
-		return
 	}
 
-	log.Printf("Target pod for script '%s' execution: %s (Namespace: %s, Selector: %s). TrackingID: %s", selectedDefinition.Name, targetPod, config.Namespace, config.PodLabelSelector, request.TrackingID)
+	xlog.Pod = targetPod
+	if affinityKey != "" && !dryRun {
+		stickyPods.Set(affinityKey, targetPod, config.StickyPodTTL)
+	}
+
+	if usesWorkloadPod {
+		xlog.Info().Msgf("Target pod for script '%s' execution: %s (Namespace: %s, Selector: %s)", selectedDefinition.Name, targetPod, config.Namespace, matchedSelector)
+	}
 
 	// Prepare environment variables by extracting values from taskData based on script's Parameters
 	envPrefix := ""
+	resolvedParams := make(map[string]string) // Declared parameter name -> value, for backends that take parameters directly
+	renderedEnv := make(map[string]string)    // Env var name -> value (masked if sensitive), returned by dry runs
+	var sensitiveValues []string
+	var paramFiles []paramFile            // Parameter values delivered as files
+	fileParams := make(map[string]string) // Declared parameter name -> file path, for those delivered as files
+	injectedEnv := newEnvReport(config)   // Only collected in debug mode
+	if injectedEnv == nil && dryRun {
+		// Dry runs always report unresolved placeholders
+		injectedEnv = &envReport{Injected: []injectedEnvVar{}, SkippedOptional: []string{}, UnresolvedPlaceholders: []string{}}
+	}
+	// Every execution sees its task context, then the script's static env; parameters come later in
+	// the prefix and win on a name clash
+	contextEnv := taskContextEnv(request, executionID, bodyTrackingID, numericProcessID)
+	scriptEnv := staticEnv(selectedDefinition)
+	var baseVars []string
+	for _, variable := range append(contextEnv, scriptEnv...) {
+		baseVars = append(baseVars, envAssignment(variable.Name, variable.Value))
+		injectedEnv.addBaseEnv(variable.Name, variable.Value, config.DebugEnvValues)
+		renderedEnv[variable.Name] = variable.Value
+	}
+	envPrefix = strings.Join(baseVars, " ") + " "
 	if len(selectedDefinition.Parameters) > 0 {
 		var envVars []string
-		log.Printf("Processing %d parameters for script '%s'. TrackingID: %s", len(selectedDefinition.Parameters), selectedDefinition.Name, request.TrackingID)
+		xlog.Info().Msgf("Processing %d parameters for script '%s'", len(selectedDefinition.Parameters), selectedDefinition.Name)
 
 		// Dump entire taskData for debugging
 		taskDataJSON, _ := json.MarshalIndent(request.TaskData, "", "  ")
-		log.Printf("DEBUG - Raw taskData contents: %s", string(taskDataJSON))
+		xlog.Debug().Msgf("Raw taskData contents: %s", string(taskDataJSON))
 
 		// Create a normalized parameters map that merges all possible parameter sources
 		// This helps us handle different parameter passing conventions
@@ -524,19 +1683,17 @@ func executeScript(c *gin.Context) {
 			// Skip special keys that aren't actual parameters
 			if k != "name" && k != "parameters" {
 				normalizedParamsMap[k] = v
-				log.Printf("Added direct parameter from taskData: '%s'. TrackingID: %s", k, bodyTrackingID)
+				xlog.Debug().Msgf("Added direct parameter from taskData: '%s'", k)
 			}
 		}
 
 		// 2. Check for parameters in a dedicated 'parameters' array/object
 		if parametersInterface, hasParams := request.TaskData["parameters"]; hasParams {
-			log.Printf("Found 'parameters' field in taskData (type: %T). TrackingID: %s",
-				parametersInterface, bodyTrackingID)
+			xlog.Debug().Msgf("Found 'parameters' field in taskData (type: %T)", parametersInterface)
 
 			// Handle parameters as array of {name,value} objects
 			if paramsArray, isArray := parametersInterface.([]interface{}); isArray {
-				log.Printf("Processing parameters array with %d items. TrackingID: %s",
-					len(paramsArray), bodyTrackingID)
+				xlog.Debug().Msgf("Processing parameters array with %d items", len(paramsArray))
 
 				for i, paramItem := range paramsArray {
 					if paramObj, isObj := paramItem.(map[string]interface{}); isObj {
@@ -544,30 +1701,26 @@ func executeScript(c *gin.Context) {
 						if name, hasName := paramObj["name"].(string); hasName {
 							if value, hasValue := paramObj["value"]; hasValue {
 								normalizedParamsMap[name] = value
-								log.Printf("Added parameter from array item %d: '%s'='%v'. TrackingID: %s",
-									i, name, value, bodyTrackingID)
+								xlog.Debug().Msgf("Added parameter from array item %d: '%s'='%v'", i, name, value)
 							}
 						} else {
 							// If no name/value pattern, treat the whole object as parameters
 							for k, v := range paramObj {
 								normalizedParamsMap[k] = v
-								log.Printf("Added parameter from array item %d property: '%s'='%v'. TrackingID: %s",
-									i, k, v, bodyTrackingID)
+								xlog.Debug().Msgf("Added parameter from array item %d property: '%s'='%v'", i, k, v)
 							}
 						}
 					} else if paramName, isString := paramItem.(string); isString {
 						// Handle case where parameters is just an array of strings (names without values)
 						normalizedParamsMap[paramName] = ""
-						log.Printf("Added parameter name from array item %d: '%s' (no value). TrackingID: %s",
-							i, paramName, bodyTrackingID)
+						xlog.Debug().Msgf("Added parameter name from array item %d: '%s' (no value)", i, paramName)
 					}
 				}
 			} else if paramsObj, isObj := parametersInterface.(map[string]interface{}); isObj {
 				// Handle parameters as a simple object of key/value pairs
 				for k, v := range paramsObj {
 					normalizedParamsMap[k] = v
-					log.Printf("Added parameter from parameters object: '%s'='%v'. TrackingID: %s",
-						k, v, bodyTrackingID)
+					xlog.Debug().Msgf("Added parameter from parameters object: '%s'='%v'", k, v)
 				}
 			}
 		}
@@ -577,19 +1730,16 @@ func executeScript(c *gin.Context) {
 		for k := range normalizedParamsMap {
 			availableParamNames = append(availableParamNames, k)
 		}
-		log.Printf("Available normalized parameters for script '%s': %v. TrackingID: %s",
-			selectedDefinition.Name, availableParamNames, bodyTrackingID)
+		xlog.Debug().Msgf("Available normalized parameters for script '%s': %v", selectedDefinition.Name, availableParamNames)
 
 		// Now process each expected parameter against our normalized map
 		for _, paramDef := range selectedDefinition.Parameters {
-			log.Printf("Looking for parameter '%s' (optional: %v). TrackingID: %s",
-				paramDef.Name, paramDef.Optional, bodyTrackingID)
+			xlog.Debug().Msgf("Looking for parameter '%s' (optional: %v)", paramDef.Name, paramDef.Optional)
 
 			// First try exact match
 			paramValueInterface, valueOk := normalizedParamsMap[paramDef.Name]
 			if valueOk {
-				log.Printf("Found parameter '%s' with exact match. TrackingID: %s",
-					paramDef.Name, bodyTrackingID)
+				xlog.Debug().Msgf("Found parameter '%s' with exact match", paramDef.Name)
 			}
 
 			// Then try case-insensitive match and handle spaces/underscores
@@ -613,8 +1763,7 @@ func executeScript(c *gin.Context) {
 						normalizedKeyWithUnderscores == normalizedParamName {
 						paramValueInterface = v
 						valueOk = true
-						log.Printf("Found parameter '%s' with fuzzy match on key '%s'. TrackingID: %s",
-							paramDef.Name, k, bodyTrackingID)
+						xlog.Debug().Msgf("Found parameter '%s' with fuzzy match on key '%s'", paramDef.Name, k)
 						break
 					}
 				}
@@ -623,69 +1772,76 @@ func executeScript(c *gin.Context) {
 			if !valueOk {
 				// Handle missing parameter value - check if it was optional in definition
 				if !paramDef.Optional {
-					log.Printf("Execute request failed for script '%s': Required parameter '%s' missing. TrackingID: %s",
-						selectedDefinition.Name, paramDef.Name, bodyTrackingID)
-					log.Printf("DEBUG - Expected parameter: '%s', Available normalized parameters: %v",
-						paramDef.Name, availableParamNames)
+					xlog.Warn().Msgf("Execute request failed for script '%s': Required parameter '%s' missing", selectedDefinition.Name, paramDef.Name)
+					xlog.Debug().Msgf("Expected parameter: '%s', Available normalized parameters: %v", paramDef.Name, availableParamNames)
 
 					// Send FAILED status UPDATE using the OBTAINED numeric ID if process tracking is enabled
 					failureMsg := fmt.Sprintf("Required parameter '%s' missing. Available parameters: %v",
 						paramDef.Name, availableParamNames)
 
 					if numericProcessID > 0 {
-						notifyProcessTrackingUpdate(config, numericProcessID, ProcessTrackingUpdatePayload{
+						notifyProcessTrackingUpdate(ctx, config, numericProcessID, ProcessTrackingUpdatePayload{
 							Status:  "FAILED",
 							Message: failureMsg,
 						})
-						// Set Header
-						c.Header("X-ProcessId", strconv.FormatInt(numericProcessID, 10))
 					}
-					c.JSON(http.StatusBadRequest, gin.H{"error": failureMsg})
-					return
+					executionStore.RecordFinish(executionID, targetPod, executionStatusFailed, "", failureMsg)
+					return executionOutcome{StatusCode: http.StatusBadRequest, Body: problem(http.StatusBadRequest, codeParamMissing, failureMsg), ProcessID: numericProcessID}
 				} else {
 					// Optional parameter is missing, skip setting env var for it
-					log.Printf("Optional parameter '%s' for script '%s' missing, skipping. TrackingID: %s",
-						paramDef.Name, selectedDefinition.Name, bodyTrackingID)
+					xlog.Info().Msgf("Optional parameter '%s' for script '%s' missing, skipping", paramDef.Name, selectedDefinition.Name)
+					injectedEnv.addSkippedOptional(paramDef.Name)
 					continue
 				}
 			}
 
 			// Log the value type for debugging
 			valueType := fmt.Sprintf("%T", paramValueInterface)
-			log.Printf("Found parameter '%s' with value type '%s'. TrackingID: %s",
-				paramDef.Name, valueType, bodyTrackingID)
+			xlog.Debug().Msgf("Found parameter '%s' with value type '%s'", paramDef.Name, valueType)
 
 			// Convert value to string
 			paramValueStr := fmt.Sprintf("%v", paramValueInterface)
+			resolvedParams[paramDef.Name] = paramValueStr
+			audit.setParameter(paramDef, paramValueStr)
 
-			// Sanitize the DEFINED parameter name for use as an env var key
-			envVarName := sanitizeEnvVarName(paramDef.Name)
+			// The env var of the DEFINED parameter: its envName, or else its sanitized name
+			envVarName := paramDef.envVarName()
 			if !isValidEnvVarName(envVarName) {
 				// This should ideally not happen if sanitizeEnvVarName is robust
-				log.Printf("Internal Error for script '%s': Sanitized parameter name '%s' (from '%s') is invalid. TrackingID: %s", selectedDefinition.Name, envVarName, paramDef.Name, bodyTrackingID)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error processing parameter names", "trackingId": bodyTrackingID})
-				return
+				xlog.Error().Msgf("Internal Error for script '%s': Sanitized parameter name '%s' (from '%s') is invalid", selectedDefinition.Name, envVarName, paramDef.Name)
+				executionStore.RecordFinish(executionID, targetPod, executionStatusFailed, "", fmt.Sprintf("Invalid parameter name '%s'", paramDef.Name))
+				return executionOutcome{StatusCode: http.StatusInternalServerError, Body: problemWith(http.StatusInternalServerError, codeInternal, "Internal server error processing parameter names", gin.H{"trackingId": bodyTrackingID})}
+			}
+
+			// Large values, and parameters asking for it, are written to a file whose path is passed instead
+			if deliversAsFile(config, backend, paramDef, paramValueStr) {
+				fileEnvName := envVarName + "_FILE"
+				filePath := paramFilePath(executionID, envVarName)
+				paramFiles = append(paramFiles, paramFile{Path: filePath, Content: paramValueStr})
+				fileParams[paramDef.Name] = filePath
+				envVars = append(envVars, fmt.Sprintf("%s=%s", fileEnvName, filePath))
+				injectedEnv.addInjected(paramDef, fileEnvName, filePath, config.DebugEnvValues)
+				renderedEnv[fileEnvName] = filePath
+				xlog.Info().Msgf("Delivering parameter '%s' (%d bytes) of script '%s' as file %s", paramDef.Name, len(paramValueStr), selectedDefinition.Name, filePath)
+				continue
 			}
 
 			// Quote the string value for shell safety
-			quotedValue := fmt.Sprintf("%q", paramValueStr)
-			envVars = append(envVars, fmt.Sprintf("%s=%s", envVarName, quotedValue))
+			envVars = append(envVars, envAssignment(envVarName, paramValueStr))
+			injectedEnv.addInjected(paramDef, envVarName, paramValueStr, config.DebugEnvValues)
+			renderedEnv[envVarName] = paramValueStr
+			if paramDef.Sensitive {
+				renderedEnv[envVarName] = maskedValue
+				sensitiveValues = append(sensitiveValues, paramValueStr)
+			}
 		}
 
 		if len(envVars) > 0 {
-			envPrefix = strings.Join(envVars, " ") + " "
-			log.Printf("Prepared environment variables for script '%s': %s. TrackingID: %s", selectedDefinition.Name, strings.TrimSpace(envPrefix), bodyTrackingID)
+			envPrefix += strings.Join(envVars, " ") + " "
+			xlog.Debug().Msgf("Prepared environment variables for script '%s': %s", selectedDefinition.Name, strings.TrimSpace(envPrefix))
 		}
 	}
 
-	// Construct the command
-	// Look for ${VAR_NAME} patterns in the command and perform replacement
-	commandWithVarsExpanded := selectedDefinition.Command
-
-	// Extract all ${VAR_NAME} patterns from the command
-	varPattern := regexp.MustCompile(`\${([A-Za-z0-9_]+)}`)
-	matches := varPattern.FindAllStringSubmatch(commandWithVarsExpanded, -1)
-
 	// Create a map of environment variables for easy lookup by scanning parameters
 	envVarMap := make(map[string]string)
 
@@ -712,99 +1868,322 @@ func executeScript(c *gin.Context) {
 		}
 	}
 
+	// Cached results are keyed before the task context joins the map, as it differs per request
+	var cacheKey string
+	if selectedDefinition.CacheTTLSeconds > 0 {
+		cacheKey = resultCacheKey(selectedDefinition, resolvedParams, envVarMap)
+	}
+	// Placeholders of parameters delivered as files expand to the path, keeping the value off the command line
+	for _, paramDef := range selectedDefinition.Parameters {
+		if filePath, delivered := fileParams[paramDef.Name]; delivered {
+			envVarMap[sanitizeEnvVarName(paramDef.Name)] = filePath
+			envVarMap[paramDef.envVarName()] = filePath
+			envVarMap[paramDef.envVarName()+"_FILE"] = filePath
+		}
+	}
+	// Parameters with an envName also expand as ${ENV_NAME}
+	for _, paramDef := range selectedDefinition.Parameters {
+		if value, resolved := resolvedParams[paramDef.Name]; resolved && paramDef.EnvName != "" {
+			if _, exists := envVarMap[paramDef.EnvName]; !exists {
+				envVarMap[paramDef.EnvName] = value
+			}
+		}
+	}
+	// Static env and ${TASK_NAME} and friends expand like parameters, unless taskData has a value of that name
+	for _, variable := range append(scriptEnv, contextEnv...) {
+		if _, exists := envVarMap[variable.Name]; !exists {
+			envVarMap[variable.Name] = variable.Value
+		}
+	}
+
 	// Log the environment variable map for debugging
 	envVarMapJSON, _ := json.Marshal(envVarMap)
-	log.Printf("Environment variable map for substitution: %s. TrackingID: %s", string(envVarMapJSON), bodyTrackingID)
-
-	// Pre-process the command to replace ${VAR_NAME} with actual values before it's executed
-	for _, match := range matches {
-		if len(match) >= 2 {
-			varName := match[1]                             // This is the name inside ${...}
-			varPattern := "${" + varName + "}"              // Full pattern like ${INTERFACE_NAME}
-			sanitizedVarName := sanitizeEnvVarName(varName) // Sanitized version for lookup
-
-			// Try to find the variable in our environment map
-			if value, exists := envVarMap[sanitizedVarName]; exists {
-				// Quote the value for shell safety when expanding in command
-				quotedValue := fmt.Sprintf("'%s'", strings.ReplaceAll(value, "'", "'\\''"))
-				commandWithVarsExpanded = strings.ReplaceAll(commandWithVarsExpanded, varPattern, quotedValue)
-				log.Printf("Replaced variable %s with quoted value %s in command. TrackingID: %s", varPattern, quotedValue, bodyTrackingID)
-			} else {
-				// Try case-insensitive match
-				foundCaseInsensitive := false
-				for envName, envValue := range envVarMap {
-					if strings.EqualFold(envName, sanitizedVarName) {
-						// Quote the value for shell safety when expanding in command
-						quotedValue := fmt.Sprintf("'%s'", strings.ReplaceAll(envValue, "'", "'\\''"))
-						commandWithVarsExpanded = strings.ReplaceAll(commandWithVarsExpanded, varPattern, quotedValue)
-						log.Printf("Replaced variable %s with case-insensitive match %s=%s (quoted) in command. TrackingID: %s",
-							varPattern, envName, quotedValue, bodyTrackingID)
-						foundCaseInsensitive = true
-						break
+	xlog.Debug().Msgf("Environment variable map for substitution: %s", string(envVarMapJSON))
+
+	// Pre-process a command to replace ${VAR_NAME} with actual values before it's executed,
+	// and prefix it with the environment variables. Used for the script command and for each step.
+	expandCommand := func(command string) string {
+		commandWithVarsExpanded := command
+		// Extract all ${VAR_NAME} patterns from the command
+		matches := placeholderPattern.FindAllStringSubmatch(commandWithVarsExpanded, -1)
+		for _, match := range matches {
+			if len(match) >= 2 {
+				varName := match[1]                             // This is the name inside ${...}
+				varPattern := "${" + varName + "}"              // Full pattern like ${INTERFACE_NAME}
+				sanitizedVarName := sanitizeEnvVarName(varName) // Sanitized version for lookup
+
+				// Try to find the variable in our environment map
+				if value, exists := envVarMap[sanitizedVarName]; exists {
+					// Quote the value for shell safety when expanding in command
+					quotedValue := shellQuote(value)
+					commandWithVarsExpanded = strings.ReplaceAll(commandWithVarsExpanded, varPattern, quotedValue)
+					xlog.Debug().Msgf("Replaced variable %s with quoted value %s in command", varPattern, quotedValue)
+				} else {
+					// Try case-insensitive match
+					foundCaseInsensitive := false
+					for envName, envValue := range envVarMap {
+						if strings.EqualFold(envName, sanitizedVarName) {
+							// Quote the value for shell safety when expanding in command
+							quotedValue := shellQuote(envValue)
+							commandWithVarsExpanded = strings.ReplaceAll(commandWithVarsExpanded, varPattern, quotedValue)
+							xlog.Debug().Msgf("Replaced variable %s with case-insensitive match %s=%s (quoted) in command", varPattern, envName, quotedValue)
+							foundCaseInsensitive = true
+							break
+						}
 					}
-				}
 
-				if !foundCaseInsensitive {
-					log.Printf("WARNING: Variable %s used in command but not found in parameters. TrackingID: %s", varPattern, bodyTrackingID)
+					if !foundCaseInsensitive {
+						xlog.Warn().Msgf("Variable %s used in command but not found in parameters", varPattern)
+						injectedEnv.addUnresolvedPlaceholder(varPattern)
+					}
 				}
 			}
 		}
+		return envPrefix + commandWithVarsExpanded
 	}
 
-	// Construct the final command with environment variables and expanded placeholders
-	fullCommand := envPrefix + commandWithVarsExpanded
-	execCmd := fmt.Sprintf("kubectl exec -n %s %s -- /bin/bash -c '%s'",
-		config.Namespace,
-		targetPod,
-		fullCommand,
-	)
-	log.Printf("Constructed kubectl command for script '%s': %s. TrackingID: %s", selectedDefinition.Name, execCmd, bodyTrackingID)
+	// Construct the final command(s) with environment variables and expanded placeholders
+	fullCommand := expandCommand(selectedDefinition.Command)
+	var stepCommands []string
+	for _, step := range selectedDefinition.Steps {
+		stepCommands = append(stepCommands, expandCommand(step.Command))
+	}
+	audit.TargetPod = targetPod
+	if len(stepCommands) > 0 {
+		audit.setCommands(stepCommands...)
+	} else {
+		audit.setCommands(fullCommand)
+	}
+	if dryRun {
+		body := gin.H{
+			"dryRun":    true,
+			"taskName":  actualScriptName,
+			"script_id": selectedDefinition.ID,
+			"mode":      executionModeOf(selectedDefinition),
+			"backend":   backend,
+			"env":       renderedEnv,
+		}
+		switch {
+		case selectedDefinition.TektonPipeline != "":
+			body["tektonPipeline"] = selectedDefinition.TektonPipeline
+			body["params"] = resolvedParams
+		case len(stepCommands) > 0:
+			var renderedSteps []gin.H
+			for i, step := range selectedDefinition.Steps {
+				renderedSteps = append(renderedSteps, gin.H{"name": stepName(step, i), "command": maskSensitiveValues(stepCommands[i], sensitiveValues)})
+			}
+			body["steps"] = renderedSteps
+		default:
+			body["command"] = maskSensitiveValues(fullCommand, sensitiveValues)
+		}
+		if nodeTargeted {
+			nodeName, nodeErr := resolveNodeName(selectedDefinition.NodeName, envVarMap)
+			if nodeErr != nil {
+				return executionOutcome{StatusCode: http.StatusBadRequest, Body: problem(http.StatusBadRequest, codeParamInvalid, nodeErr.Error())}
+			}
+			body["nodeName"] = nodeName
+		}
+		if usesWorkloadPod {
+			body["targetPod"] = targetPod
+			body["podSelector"] = matchedSelector
+		}
+		if selectedDefinition.RunAsUser != "" {
+			body["runAsUser"] = selectedDefinition.RunAsUser
+		}
+		if injectedEnv != nil && len(injectedEnv.UnresolvedPlaceholders) > 0 {
+			body["unresolvedPlaceholders"] = injectedEnv.UnresolvedPlaceholders
+		}
+		xlog.Info().Msgf("Dry run of script '%s' complete, nothing executed", selectedDefinition.Name)
+		return executionOutcome{StatusCode: http.StatusOK, Body: body}
+	}
+	injectedEnv.record(executionID, bodyTrackingID)
+	fullCommand = runAsUserCommand(selectedDefinition, backend, fullCommand)
+	for i := range stepCommands {
+		stepCommands[i] = runAsUserCommand(selectedDefinition, backend, stepCommands[i])
+	}
+
+	// Cached scripts answer identical requests with the output of the last successful execution
+	if cacheKey != "" {
+		if cached, ok := cachedResults.Get(cacheKey); ok {
+			xlog.Info().Msgf("Execution SUCCESSFUL for script '%s' (ID: %s) from the cached result of execution %s.", selectedDefinition.Name, selectedDefinition.ID, cached.ExecutionID)
+			completion.Output = cached.Output
+			audit.TargetPod = cached.Pod
+			trackingData.Pod = cached.Pod
+			trackingData.Duration = time.Since(started).Round(time.Millisecond)
+			trackingData.ExitCode = "0"
+			trackingData.Output = cached.Output
+			trackingData.OutputTail = outputTail(cached.Output)
+			if numericProcessID > 0 {
+				truncatedOutput := summarizeTrackingOutput(ctx, config, selectedDefinition, cached.Output, executionID)
+				notifyProcessTrackingUpdate(ctx, config, numericProcessID, withOutcomeMetadata(config, ProcessTrackingUpdatePayload{
+					Status: "SUCCESSFUL",
+					Message: fmt.Sprintf("(cached result of execution %s from %s)\n%s", cached.ExecutionID, cached.Finished.UTC().Format(time.RFC3339),
+						trackingMessages.render(ctx, "succeeded", trackingData, truncatedOutput)),
+				}, trackingData))
+			}
+			executionStore.RecordFinish(executionID, cached.Pod, executionStatusSuccessful, cached.Output, "")
+			return executionOutcome{StatusCode: http.StatusOK, Output: cached.Output, ProcessID: numericProcessID}
+		}
+	}
 
 	// Execute command
-	cmd := exec.Command("sh", "-c", execCmd)
-	log.Printf("Executing command for script '%s' in pod '%s'... TrackingID: %s", selectedDefinition.Name, targetPod, request.TrackingID)
+	_, execSpan := tracer().Start(ctx, "exec", scriptAttributes(selectedDefinition), trace.WithAttributes(attribute.String("execution.mode", executionModeOf(selectedDefinition)), attribute.String("execution.backend", backend)))
+	run := &executorRun{
+		Config:      config,
+		Definition:  selectedDefinition,
+		ExecutionID: executionID,
+		TrackingID:  bodyTrackingID,
+		Caller:      opts.Caller.Name,
+		Impersonate: impersonatedCaller(config, opts.Caller),
+		ProcessID:   numericProcessID,
+		TargetPod:   targetPod,
+		Command:     fullCommand,
+		Steps:       stepCommands,
+		Params:      resolvedParams,
+		Env:         envVarMap,
+		Files:       paramFiles,
+	}
+	// Journaled until finished, so a restart of the executor meanwhile can reconcile it
+	inFlightJournal.record(journalEntry{
+		ExecutionID: executionID,
+		ProcessID:   numericProcessID,
+		Script:      selectedDefinition.Name,
+		Backend:     backend,
+		Namespace:   config.Namespace,
+		Pod:         targetPod,
+		StartedAt:   started.UTC(),
+	})
+	defer inFlightJournal.remove(executionID)
+	var outputStr string
+	targetPod, outputStr, err = executor.Run(ctx, run)
+
+	// Retry failures classified as pod-unavailable (connection refused, pod deleted mid-exec, API timeout) on a fresh pod.
+	// Script errors, permission failures and timeouts are never retried, nor are multi-step scripts, whose earlier steps may have had side effects.
+	for attempt := 1; backend == backendPodExec && len(stepCommands) == 0 && attempt <= config.ExecTransientRetries && err != nil && classifyFailure(ctx, backend, outputStr, err) == failurePodUnavailable; attempt++ {
+		xlog.Warn().Msgf("Transient exec failure for script '%s' in pod '%s' (attempt %d/%d): %v. Output: %s", selectedDefinition.Name, targetPod, attempt, config.ExecTransientRetries, err, outputStr)
+		if numericProcessID > 0 {
+			notifyProcessTrackingUpdate(ctx, config, numericProcessID, ProcessTrackingUpdatePayload{
+				Status:  "PROGRESS",
+				Message: fmt.Sprintf("Transient exec failure on pod %s, retrying on a fresh pod (attempt %d/%d)", targetPod, attempt, config.ExecTransientRetries),
+			})
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(execRetryDelay):
+		}
+		if ctx.Err() != nil {
+			break // Cancelled or shutting down meanwhile: don't try again
+		}
+
+		freshPod, podErr := selectTargetPod()
+		if podErr != nil {
+			xlog.Warn().Msgf("Could not select a fresh pod for retry of script '%s': %v", selectedDefinition.Name, podErr)
+			break
+		}
+		targetPod = freshPod
+		xlog.Pod = targetPod
+		if affinityKey != "" {
+			stickyPods.Set(affinityKey, targetPod, config.StickyPodTTL)
+		}
+		xlog.Info().Msgf("Retrying script '%s' in pod '%s'...", selectedDefinition.Name, targetPod)
+		run.TargetPod = targetPod
+		inFlightJournal.setPod(executionID, targetPod)
+		targetPod, outputStr, err = executor.Run(ctx, run)
+	}
 
-	output, err := cmd.CombinedOutput()
-	outputStr := string(output)
-	truncatedOutput := outputStr
-	if len(truncatedOutput) > maxProcessTrackingMessageLength {
-		truncatedOutput = truncatedOutput[:maxProcessTrackingMessageLength] + "... (truncated)"
+	if err != nil && ctx.Err() != nil {
+		err = executionStopped(ctx)
+		if run.Termination != "" {
+			executionStore.RecordTermination(executionID, run.Termination)
+		}
+	}
+
+	xlog.Pod = targetPod // Backends creating their own pod only learn it here
+	execSpan.SetAttributes(attribute.String("pod.name", targetPod))
+	endSpan(execSpan, err)
+	completion.Output = outputStr
+	audit.TargetPod = targetPod
+	truncatedOutput := summarizeTrackingOutput(ctx, config, selectedDefinition, outputStr, executionID)
+	trackingData.Pod = targetPod
+	trackingData.Duration = time.Since(started).Round(time.Millisecond)
+	trackingData.ExitCode = "0"
+	trackingData.Output = outputStr
+	trackingData.OutputTail = outputTail(outputStr)
+
+	// Exit codes the definition maps to WARNING or SKIPPED end the execution without failing it
+	if mappedStatus, exitCode := mappedExitStatus(selectedDefinition, err); mappedStatus != "" && ctx.Err() == nil {
+		trackingData.ExitCode = strconv.Itoa(exitCode)
+		xlog.Warn().Msgf("Execution of script '%s' (ID: %s) in pod '%s' exited with code %d, mapped to %s. Output: %s", selectedDefinition.Name, selectedDefinition.ID, targetPod, exitCode, mappedStatus, outputStr)
+		if numericProcessID > 0 {
+			notifyProcessTrackingUpdate(ctx, config, numericProcessID, withOutcomeMetadata(config, ProcessTrackingUpdatePayload{
+				Status:  mappedTrackingStatus(config, mappedStatus),
+				Message: trackingMessages.render(ctx, strings.ToLower(mappedStatus), trackingData, fmt.Sprintf("Script exited with code %d (%s)\n--- Output ---\n%s", exitCode, mappedStatus, truncatedOutput)),
+			}, trackingData))
+		}
+		executionStore.RecordFinish(executionID, targetPod, mappedStatus, outputStr, "")
+		body := gin.H{
+			"status":    mappedStatus,
+			"exitCode":  exitCode,
+			"taskName":  actualScriptName,
+			"script_id": selectedDefinition.ID,
+			"output":    outputStr,
+		}
+		if mappedStatus == executionStatusSkipped {
+			body["skipped"] = true
+		}
+		return executionOutcome{StatusCode: http.StatusOK, Status: mappedStatus, Output: outputStr, Body: body, ProcessID: numericProcessID}
 	}
 
 	if err != nil {
 		errMsgStr := fmt.Sprintf("Execution error: %v", err)
-		log.Printf("Execution FAILED for script '%s' (ID: %s) in pod '%s'. TrackingID: %s. Error: %v. Output: %s", selectedDefinition.Name, selectedDefinition.ID, targetPod, request.TrackingID, err, outputStr)
+		category := classifyFailure(ctx, backend, outputStr, err)
+		trackingData.Error = errMsgStr
+		trackingData.ExitCode = exitCodeOf(errMsgStr)
+		xlog.Error().Msgf("Execution FAILED (%s) for script '%s' (ID: %s) in pod '%s'. Error: %v. Output: %s", category, selectedDefinition.Name, selectedDefinition.ID, targetPod, err, outputStr)
 		// Send FAILED status UPDATE using the OBTAINED numeric ID if process tracking is enabled
 		if numericProcessID > 0 {
-			notifyProcessTrackingUpdate(config, numericProcessID, ProcessTrackingUpdatePayload{
+			notifyProcessTrackingUpdate(ctx, config, numericProcessID, withOutcomeMetadata(config, ProcessTrackingUpdatePayload{
 				Status:  "FAILED",
-				Message: fmt.Sprintf("%s\n--- Output ---\n%s", errMsgStr, truncatedOutput),
-			})
-			// Set Header (using OBTAINED numericProcessID)
-			c.Header("X-ProcessId", strconv.FormatInt(numericProcessID, 10))
+				Message: trackingMessages.render(ctx, "failed", trackingData, fmt.Sprintf("%s\n--- Output ---\n%s", errMsgStr, truncatedOutput)),
+			}, trackingData))
+		}
+		executionStore.RecordFinish(executionID, targetPod, executionStatusFailed, outputStr, errMsgStr)
+		executionStore.RecordFailureCategory(executionID, category)
+		return executionOutcome{
+			StatusCode: http.StatusInternalServerError,
+			Category:   category,
+			Body: problemWith(http.StatusInternalServerError, codeExecFailed, errMsgStr, gin.H{
+				"taskName":  actualScriptName,
+				"script_id": selectedDefinition.ID,
+				"output":    outputStr,
+				"category":  category,
+			}),
+			ProcessID: numericProcessID,
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"taskName":  actualScriptName,
-			"script_id": selectedDefinition.ID,
-			"error":     errMsgStr,
-			"output":    outputStr,
-		})
-		return
 	}
 
 	// --- Execution Successful ---
-	log.Printf("Execution SUCCESSFUL for script '%s' (ID: %s) in pod '%s'. TrackingID: %s. Output: %s", selectedDefinition.Name, selectedDefinition.ID, targetPod, request.TrackingID, outputStr)
+	xlog.Info().Msgf("Execution SUCCESSFUL for script '%s' (ID: %s) in pod '%s'. Output: %s", selectedDefinition.Name, selectedDefinition.ID, targetPod, outputStr)
 	// Send COMPLETED/SUCCESSFUL status UPDATE using the OBTAINED numeric ID if process tracking is enabled
 	if numericProcessID > 0 {
-		notifyProcessTrackingUpdate(config, numericProcessID, ProcessTrackingUpdatePayload{
+		notifyProcessTrackingUpdate(ctx, config, numericProcessID, withOutcomeMetadata(config, ProcessTrackingUpdatePayload{
 			Status:  "SUCCESSFUL", // Changed from COMPLETED to SUCCESSFUL
-			Message: truncatedOutput,
+			Message: trackingMessages.render(ctx, "succeeded", trackingData, truncatedOutput),
+		}, trackingData))
+	}
+	executionStore.RecordFinish(executionID, targetPod, executionStatusSuccessful, outputStr, "")
+	if cacheKey != "" {
+		now := time.Now()
+		cachedResults.Set(cacheKey, cachedResult{
+			ExecutionID: executionID,
+			Pod:         targetPod,
+			Output:      outputStr,
+			Finished:    now,
+			Expires:     now.Add(time.Duration(selectedDefinition.CacheTTLSeconds) * time.Second),
 		})
-		// Set Header (using OBTAINED numericProcessID)
-		c.Header("X-ProcessId", strconv.FormatInt(numericProcessID, 10))
 	}
 	// Return status OK with ONLY the header and NO body
-	c.Status(http.StatusOK)
+	return executionOutcome{StatusCode: http.StatusOK, Output: outputStr, ProcessID: numericProcessID}
 }
 
 // isValidEnvVarName checks if a string is a valid environment variable name
@@ -824,6 +2203,14 @@ func isValidEnvVarName(name string) bool {
 	return true
 }
 
+// envVarName returns the env var a parameter is passed in: its envName, or else its sanitized name.
+func (p InputParameterDef) envVarName() string {
+	if p.EnvName != "" {
+		return p.EnvName
+	}
+	return sanitizeEnvVarName(p.Name)
+}
+
 // sanitizeEnvVarName converts a parameter name into a potentially valid env var name
 // Replaces spaces and invalid characters with underscores.
 // WARNING: This is basic; ensure it doesn't cause collisions and meets shell requirements.
@@ -839,92 +2226,281 @@ func sanitizeEnvVarName(name string) string {
 	return replaced
 }
 
+// initKubernetesClients creates the Kubernetes clients and runs the startup permission check.
+func initKubernetesClients(config *Config) {
+	logger.Info().Msg("Initializing Kubernetes client...")
+	k8sConfig, err := kubernetesRESTConfig(config)
+	if err != nil {
+		logger.Fatal().Msgf("%v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		logger.Fatal().Msgf("Failed to create Kubernetes clientset: %v", err)
+	}
+	kubeClient = clientset
+	dynamicClient, err := dynamic.NewForConfig(k8sConfig)
+	if err != nil {
+		logger.Fatal().Msgf("Failed to create Kubernetes dynamic client: %v", err)
+	}
+	kubeDynamicClient = dynamicClient
+	logger.Info().Msg("Kubernetes client initialized successfully.")
+
+	// --- Startup Permission Check ---
+	if config.SkipPermissionCheck {
+		logger.Warn().Msg("SKIP_PERMISSION_CHECK is set; the Kubernetes permissions are not verified.")
+	} else if err := checkPermissions(clientset, config.Namespace); err != nil {
+		// Log fatal will exit the program
+		logger.Fatal().Msgf("Startup failed due to missing permissions: %v", err)
+	} else {
+		checkScriptNamespacePermissions(config)
+	}
+}
+
 // checkPermissions verifies if the service account has the required RBAC permissions.
 func checkPermissions(clientset *kubernetes.Clientset, namespace string) error {
-	log.Printf("Checking required Kubernetes permissions in namespace '%s'...", namespace)
-
-	requiredPermissions := []struct {
-		verb        string
-		resource    string
-		subresource string
-		description string
-	}{
-		{"get", "pods", "", "Get Pods"},
-		{"create", "pods", "exec", "Create Pods/Exec"},
+	logger.Info().Msgf("Checking required Kubernetes permissions in namespace '%s'...", namespace)
+	if err := verifyPermissions(context.TODO(), clientset, namespace, requiredPermissions); err != nil {
+		logger.Error().Msgf("Permission check FAILED: %v", err)
+		return err
 	}
+	logger.Info().Msgf("All required Kubernetes permissions verified successfully in namespace '%s'.", namespace)
+	return nil
+}
+
+// permission is a Kubernetes permission the executor needs
+type permission struct {
+	verb        string
+	group       string
+	resource    string
+	subresource string
+	description string
+}
+
+// requiredPermissions are the permissions the executor needs in its namespace
+var requiredPermissions = []permission{
+	{"get", "", "pods", "", "Get Pods"},
+	{"create", "", "pods", "exec", "Create Pods/Exec"},
+}
 
-	for _, perm := range requiredPermissions {
+// verifyPermissions checks permissions in a namespace with SelfSubjectAccessReviews, without logging,
+// and returns the first one denied. Used at startup and by the readiness probe.
+func verifyPermissions(ctx context.Context, clientset kubernetes.Interface, namespace string, perms []permission) error {
+	for _, perm := range perms {
 		ssar := &authv1.SelfSubjectAccessReview{
 			Spec: authv1.SelfSubjectAccessReviewSpec{
 				ResourceAttributes: &authv1.ResourceAttributes{
 					Namespace:   namespace,
 					Verb:        perm.verb,
+					Group:       perm.group,
 					Resource:    perm.resource,
 					Subresource: perm.subresource,
 				},
 			},
 		}
 
-		result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), ssar, metav1.CreateOptions{})
+		result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, ssar, metav1.CreateOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to perform self subject access review for %s: %v", perm.description, err)
 		}
-
 		if !result.Status.Allowed {
-			log.Printf("Permission check FAILED: '%s' permission is DENIED. Reason: %s", perm.description, result.Status.Reason)
 			return fmt.Errorf("missing required Kubernetes permission: %s in namespace %s. Reason: %s", perm.description, namespace, result.Status.Reason)
-		} else {
-			log.Printf("Permission check PASSED: '%s' permission is allowed.", perm.description)
 		}
 	}
-
-	log.Printf("All required Kubernetes permissions verified successfully in namespace '%s'.", namespace)
 	return nil
 }
 
-// healthzHandler handles the /healthz endpoint.
-func healthzHandler(c *gin.Context) {
-	// Simple health check - relies on the startup permission check having passed.
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+// versionHandler handles the /version endpoint, reporting the build and history schema versions.
+func versionHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"version":             version,
+		"schemaVersion":       executionStore.SchemaVersion(),
+		"latestSchemaVersion": latestSchemaVersion(),
+	})
 }
 
 func main() {
+	// Subcommands run without the HTTP server or Kubernetes client
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
+	// Configuration is read and validated once; handlers get it injected by injectConfig
+	if err := loadConfigOverrides(); err != nil {
+		logger.Fatal().Msgf("Invalid configuration: %v", err)
+	}
 	config := loadConfig()
-	log.Printf("Starting server with configuration:")
-	log.Printf("- Scripts Definition Path: %s", config.ScriptsPath)
-	log.Printf("- Pod Label Selector: %s", config.PodLabelSelector)
-	log.Printf("- Namespace: %s", config.Namespace)
+	if err := applyServerFlags(config, os.Args[1:]); err != nil {
+		logger.Fatal().Msgf("Invalid command line: %v", err)
+	}
+	if err := validateConfig(config); err != nil {
+		logger.Fatal().Msgf("Invalid configuration:\n%v", err)
+	}
+	activeConfig.Store(config)
+	if err := reloadAPIKeys(config); err != nil {
+		logger.Fatal().Msgf("Invalid configuration: %v", err)
+	}
+	setLogLevel(config.LogLevel)
+	if config.MaintenanceMode {
+		setMaintenance(config, true, "", "config")
+		logger.Warn().Msg("Starting in maintenance mode: script execution is paused.")
+	}
+	reloadConfigOnSIGHUP()
+	logger.Info().Msg("Starting server with configuration:")
+	logger.Info().Msgf("- Scripts Definition Path: %s", config.ScriptsPath)
+	logger.Info().Msgf("- Pod Label Selector: %s", config.PodLabelSelector)
+	logger.Info().Msgf("- Namespace: %s", config.Namespace)
+	logger.Info().Msgf("- Version: %s", version)
+
+	// --- Execution History Store ---
+	if config.HistoryDBDSN != "" {
+		store, err := newExecutionStore(config)
+		if err != nil {
+			logger.Fatal().Msgf("Failed to initialize execution history store: %v", err)
+		}
+		executionStore = store
+	} else {
+		logger.Info().Msg("HISTORY_DB_DSN not set; execution history persistence is disabled.")
+	}
 
-	// --- Kubernetes Client Setup ---
-	log.Println("Initializing Kubernetes client...")
-	k8sConfig, err := rest.InClusterConfig()
+	// --- Shared State ---
+	if config.RedisURL != "" {
+		client, err := newSharedState(config)
+		if err != nil {
+			logger.Fatal().Msgf("Failed to initialize shared state: %v", err)
+		}
+		sharedState = client
+		logger.Info().Msg("- Shared State: redis")
+	} else {
+		logger.Info().Msg("REDIS_URL not set; rate limits and pod pins are kept per replica.")
+	}
+
+	// --- Audit Log ---
+	sink, err := newAuditSink(config)
 	if err != nil {
-		log.Fatalf("Failed to get in-cluster Kubernetes config: %v", err)
+		logger.Fatal().Msgf("Failed to initialize audit log: %v", err)
 	}
-	clientset, err := kubernetes.NewForConfig(k8sConfig)
+	auditLog = sink
+	logger.Info().Msgf("- Audit Log: %s", config.AuditLogSink)
+
+	// --- Execution Events ---
+	publisher, err := newEventPublisher(config)
 	if err != nil {
-		log.Fatalf("Failed to create Kubernetes clientset: %v", err)
+		logger.Fatal().Msgf("Failed to initialize event publisher: %v", err)
 	}
-	log.Println("Kubernetes client initialized successfully.")
+	eventBus = publisher
+	logger.Info().Msgf("- Execution Events: %s", config.EventsSink)
 
-	// --- Startup Permission Check ---
-	if err := checkPermissions(clientset, config.Namespace); err != nil {
-		// Log fatal will exit the program
-		log.Fatalf("Startup failed due to missing permissions: %v", err)
+	// --- Process Tracking Client ---
+	if err := configureProcessTrackingTLS(config); err != nil {
+		logger.Fatal().Msgf("Failed to configure process tracking TLS: %v", err)
 	}
 
+	// --- Process Tracking Outbox ---
+	if err := startTrackingOutbox(config); err != nil {
+		logger.Fatal().Msgf("Failed to initialize process tracking outbox: %v", err)
+	}
+
+	// --- Execution Journal ---
+	// Opened before serving, so the journal holds only executions of the previous run at this point
+	if err := startExecutionJournal(config); err != nil {
+		logger.Fatal().Msgf("Failed to initialize execution journal: %v", err)
+	}
+
+	// --- Kubernetes Client Setup ---
+	// Local mode runs scripts on this host and needs no cluster, unless a kubeconfig is given
+	if config.ExecutorMode == executorModeLocal && config.Kubeconfig == "" {
+		logger.Warn().Msg("EXECUTOR_MODE=local: scripts run on this host and no Kubernetes client is initialized.")
+	} else {
+		initKubernetesClients(config)
+		startResourceReaper(config)
+		startPermissionMonitor(config)
+		startPreflightChecks(config)
+	}
+	// --- Scheduled Scripts ---
+	switch config.SchedulerMode {
+	case schedulerModeCronJob:
+		startCronJobReconciler(kubeClient, config)
+	case schedulerModeInternal:
+		startInternalScheduler(config)
+	}
+
+	// --- Diagnostics ---
+	if config.DiagnosticsAddr != "" {
+		startDiagnosticsServer(config.DiagnosticsAddr)
+	}
+
+	// --- Tracing ---
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		logger.Fatal().Msgf("Failed to initialize tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// --- Gin Router Setup ---
-	r := gin.Default()
+	// gin.New instead of gin.Default: access logs are written as JSON by logRequests
+	r := gin.New()
+	r.Use(gin.Recovery(), injectConfig, handleCORS, traceRequests, logRequests, authenticateClientCertificate, authenticateTrustedHeaders, authenticateServiceAccountToken, authenticateAPIKey)
 
 	// Define API routes
 	r.GET("/v1/options", listScripts)
-	r.POST("/v1/execute", executeScript)
-	r.GET("/healthz", healthzHandler) // Add health check endpoint
-
-	// Start server on port 8080
-	port := "8080"
-	log.Printf("Starting server on port %s...", port)
-	if err := r.Run("0.0.0.0:" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	r.POST("/v1/execute", rateLimitExecute, executeScript)
+	r.POST("/v1/execute/dry-run", rateLimitExecute, dryRunScript)
+	r.POST("/v1/scripts/validate", validateScripts)
+	r.GET("/livez", livezHandler)
+	r.GET("/readyz", readyzHandler)
+	r.GET("/healthz", livezHandler) // Former combined health check, kept for existing probes
+	r.GET("/version", versionHandler)
+	r.GET("/v1/status", statusHandler)
+	r.GET("/metrics", metricsHandler())
+	r.GET("/v1/catalog/export", exportCatalog)
+	r.GET("/v1/quotas", getQuotas)
+	r.GET("/v1/audit", exportAudit)
+	r.GET("/v1/executions", listExecutions)
+	r.GET("/v1/executions/compare", compareExecutions)
+	r.GET("/v1/executions/:id", getExecution)
+	r.GET("/v1/executions/:id/output", getExecutionOutput)
+	r.POST("/v1/executions/:id/rerun", rateLimitExecute, rerunExecution)
+	r.POST("/v1/executions/:id/cancel", cancelExecution)
+	r.GET("/v1/trigger/:script", rateLimitExecute, triggerScript)
+	r.POST("/v1/trigger/:script", rateLimitExecute, triggerScript)
+
+	// Admin endpoints have their own router and authentication, on their own listener if configured
+	var handler http.Handler = r
+	var adminServers []*http.Server
+	adminRouter := newAdminRouter(config)
+	if !adminAuthConfigured(config) {
+		logger.Warn().Msg("The /admin endpoints are not served: set ADMIN_TOKEN, ADMIN_CALLERS, or ADMIN_LISTEN_ADDR with ADMIN_CLIENT_CA_FILE to enable them.")
+	} else if config.AdminListenAddr != "" {
+		adminServer, err := startAdminServer(config, withRequestLimits(config, adminRouter))
+		if err != nil {
+			logger.Fatal().Msgf("Failed to configure admin server: %v", err)
+		}
+		adminServers = append(adminServers, adminServer)
+	} else {
+		handler = withAdminRoutes(r, adminRouter)
+	}
+	handler = withRequestLimits(config, handler)
+
+	// Start server on LISTEN_ADDR:PORT, over HTTPS when a certificate is configured
+	addr := net.JoinHostPort(config.ListenAddr, strconv.Itoa(config.Port))
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+	if config.TLSCertFile != "" {
+		tlsFiles, err := newReloadingTLSFiles(config.TLSCertFile, config.TLSKeyFile, config.TLSClientCAFile)
+		if err != nil {
+			logger.Fatal().Msgf("Failed to configure TLS: %v", err)
+		}
+		server.TLSConfig = tlsFiles.tlsConfig(config.TLSClientAuth)
+		logger.Info().Msgf("Starting HTTPS server on %s (client CA: '%s', client auth: %s)...", addr, config.TLSClientCAFile, config.TLSClientAuth)
+		serveUntilShutdown(config, server, func() error { return server.ListenAndServeTLS("", "") }, adminServers...)
+		return
 	}
+	logger.Info().Msgf("Starting server on %s...", addr)
+	serveUntilShutdown(config, server, server.ListenAndServe, adminServers...)
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceState is the maintenance (pause) mode of the executor, returned by /admin/maintenance
+type MaintenanceState struct {
+	Paused  bool       `json:"paused"`
+	Message string     `json:"message,omitempty"` // Returned to execute requests while paused
+	Since   *time.Time `json:"since,omitempty"`
+	By      string     `json:"by,omitempty"` // Caller that paused execution, or "config"
+}
+
+// maintenance is the current maintenance state; nil means not paused
+var maintenance atomic.Pointer[MaintenanceState]
+
+// setMaintenance pauses or resumes script execution. An empty message falls back to MAINTENANCE_MESSAGE.
+func setMaintenance(config *Config, paused bool, message, by string) *MaintenanceState {
+	state := &MaintenanceState{Paused: paused}
+	if paused {
+		if message == "" {
+			message = config.MaintenanceMessage
+		}
+		now := time.Now().UTC()
+		state.Message, state.Since, state.By = message, &now, by
+	}
+	maintenance.Store(state)
+	return state
+}
+
+// pausedMessage returns the message to reject executions with, or "" when execution isn't paused.
+func pausedMessage() string {
+	if state := maintenance.Load(); state != nil && state.Paused {
+		return state.Message
+	}
+	return ""
+}
+
+// MaintenanceRequest is the body of PUT /admin/maintenance
+type MaintenanceRequest struct {
+	Paused  bool   `json:"paused"`
+	Message string `json:"message"`
+}
+
+// getMaintenance handles GET /admin/maintenance and returns the current maintenance state.
+func getMaintenance(c *gin.Context) {
+	state := maintenance.Load()
+	if state == nil {
+		state = &MaintenanceState{}
+	}
+	writeJSON(c, http.StatusOK, state)
+}
+
+// putMaintenance handles PUT /admin/maintenance with {"paused": true, "message": "..."}. While
+// paused, execute requests, triggers and scheduled runs are refused with 503 and the message;
+// listing scripts and dry runs keep working. The state lasts until changed or the next restart,
+// which falls back to MAINTENANCE_MODE.
+func putMaintenance(c *gin.Context) {
+	config := configFromContext(c)
+	caller := callerFromContext(c)
+	var request MaintenanceRequest
+	if err := bindJSONWithNaming(c, &request); err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Invalid request body: %v", err)))
+		return
+	}
+	state := setMaintenance(config, request.Paused, request.Message, caller.Name)
+	if state.Paused {
+		logger.Warn().Msgf("Script execution paused by caller '%s': %s", caller.Name, state.Message)
+	} else {
+		logger.Warn().Msgf("Script execution resumed by caller '%s'.", caller.Name)
+	}
+	writeJSON(c, http.StatusOK, state)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics of script executions, served on GET /metrics
+var (
+	executionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "script_executor_executions_total",
+		Help: "Execute attempts by script and outcome (SUCCESSFUL, WARNING, SKIPPED, FAILED, REJECTED, DRY_RUN).",
+	}, []string{"script", "outcome"})
+
+	executionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "script_executor_execution_duration_seconds",
+		Help: "Duration of script executions that ran, by script and outcome.",
+		// Scripts range from sub-second checks to hour-long jobs
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600, 1800, 3600},
+	}, []string{"script", "outcome"})
+
+	executionExitCodes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "script_executor_exit_codes_total",
+		Help: "Exit codes of script executions that ran, by script.",
+	}, []string{"script", "exit_code"})
+
+	executionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "script_executor_failures_total",
+		Help: "Failed executions by script and category (pod-unavailable, permission, timeout, script-error, tracking-error, cancelled).",
+	}, []string{"script", "category"})
+
+	trackingOutboxPending = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "script_executor_tracking_outbox_pending",
+		Help: "Process tracking updates waiting in the outbox for background delivery.",
+	}, func() float64 { return float64(processTrackingOutbox.size()) })
+)
+
+// exitStatusPattern extracts the exit code from "exit status N" execution errors
+var exitStatusPattern = regexp.MustCompile(`exit status (\d+)`)
+
+func init() {
+	prometheus.MustRegister(executionsTotal, executionDuration, executionExitCodes, executionFailures, trackingOutboxPending)
+}
+
+// metricsHandler serves the Prometheus metrics.
+func metricsHandler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// recordExecutionMetrics records an execute attempt. script is empty when no definition was
+// resolved, which is counted under "unknown" so arbitrary requested names can't blow up cardinality.
+func recordExecutionMetrics(script string, outcome executionOutcome, dryRun bool, started time.Time) {
+	label := executionOutcomeLabel(outcome, dryRun)
+	if script == "" {
+		script = "unknown"
+	}
+	executionsTotal.WithLabelValues(script, label).Inc()
+	if outcome.Category != "" {
+		executionFailures.WithLabelValues(script, outcome.Category).Inc()
+	}
+	if label == auditOutcomeRejected || label == auditOutcomeDryRun {
+		return
+	}
+	executionDuration.WithLabelValues(script, label).Observe(time.Since(started).Seconds())
+
+	exitCode := "0"
+	switch label {
+	case auditOutcomeFailed:
+		errMsg, _ := outcome.Body["error"].(string)
+		exitCode = exitCodeOf(errMsg)
+	case executionStatusWarning, executionStatusSkipped:
+		exitCode = fmt.Sprintf("%v", outcome.Body["exitCode"])
+	}
+	executionExitCodes.WithLabelValues(script, exitCode).Inc()
+}
+
+// exitCodeOf returns the exit code in the error message of a failed execution, or "unknown" if it
+// failed without one, e.g. because the pod could not be reached.
+func exitCodeOf(errMsg string) string {
+	if match := exitStatusPattern.FindStringSubmatch(errMsg); match != nil {
+		return match[1]
+	}
+	return "unknown"
+}
+
+// executionOutcomeLabel classifies an execution outcome as SUCCESSFUL, FAILED, REJECTED or DRY_RUN,
+// or as the WARNING or SKIPPED status the script's exit code mapped to.
+func executionOutcomeLabel(outcome executionOutcome, dryRun bool) string {
+	switch {
+	case dryRun && outcome.StatusCode == http.StatusOK:
+		return auditOutcomeDryRun
+	case outcome.StatusCode == http.StatusOK && outcome.Status != "":
+		return outcome.Status
+	case outcome.StatusCode == http.StatusOK:
+		return auditOutcomeSuccessful
+	case outcome.StatusCode >= 500:
+		return auditOutcomeFailed
+	default:
+		return auditOutcomeRejected
+	}
+}
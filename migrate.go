@@ -0,0 +1,259 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema migrations are embedded into the binary so the schema always matches the code that uses it.
+// Files follow the golang-migrate naming convention: <version>_<name>.up.sql / <version>_<name>.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration is a single versioned schema change loaded from the embedded migrations directory
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// loadMigrations parses the embedded migration files and returns them sorted by version.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %v", err)
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		// Split "0001_create_executions.up.sql" into version 1 and name "create_executions"
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration file '%s' does not match <version>_<name>.<up|down>.sql", fileName)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file '%s' has an invalid version '%s'", fileName, versionStr)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file '%s': %v", fileName, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d has conflicting names '%s' and '%s'", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration version %d (%s) has no .up.sql file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// latestSchemaVersion returns the highest migration version embedded in the binary.
+func latestSchemaVersion() int {
+	migrations, err := loadMigrations()
+	if err != nil || len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// ensureMigrationsTable creates the bookkeeping table that records applied migrations.
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version     INTEGER PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    applied_at  BIGINT NOT NULL
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %v", err)
+	}
+	return nil
+}
+
+// currentSchemaVersion returns the highest applied migration version (0 for an empty database).
+func currentSchemaVersion(db *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read current schema version: %v", err)
+	}
+	return int(version.Int64), nil
+}
+
+// migrateUp applies every pending migration in order, each in its own transaction.
+// It returns the schema version after the run.
+func migrateUp(db *sql.DB, dialect string) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	current, err := currentSchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		logger.Info().Msgf("[Migrate] Applying migration %04d_%s...", m.Version, m.Name)
+		tx, err := db.Begin()
+		if err != nil {
+			return current, fmt.Errorf("failed to begin transaction for migration %d: %v", m.Version, err)
+		}
+		if _, err := tx.Exec(m.Up); err != nil {
+			tx.Rollback()
+			return current, fmt.Errorf("migration %04d_%s failed: %v", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(rebindQuery(dialect, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+			m.Version, m.Name, time.Now().UnixMilli()); err != nil {
+			tx.Rollback()
+			return current, fmt.Errorf("failed to record migration %d: %v", m.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return current, fmt.Errorf("failed to commit migration %d: %v", m.Version, err)
+		}
+		current = m.Version
+	}
+	return current, nil
+}
+
+// migrateDown reverts the most recently applied migration. It returns the schema version after the run.
+func migrateDown(db *sql.DB, dialect string) (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	current, err := currentSchemaVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if current == 0 {
+		return 0, nil
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version != current {
+			continue
+		}
+		if m.Down == "" {
+			return current, fmt.Errorf("migration %04d_%s has no .down.sql file and cannot be reverted", m.Version, m.Name)
+		}
+		logger.Info().Msgf("[Migrate] Reverting migration %04d_%s...", m.Version, m.Name)
+		tx, err := db.Begin()
+		if err != nil {
+			return current, fmt.Errorf("failed to begin transaction for migration %d: %v", m.Version, err)
+		}
+		if _, err := tx.Exec(m.Down); err != nil {
+			tx.Rollback()
+			return current, fmt.Errorf("reverting migration %04d_%s failed: %v", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(rebindQuery(dialect, `DELETE FROM schema_migrations WHERE version = ?`), m.Version); err != nil {
+			tx.Rollback()
+			return current, fmt.Errorf("failed to remove migration record %d: %v", m.Version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return current, fmt.Errorf("failed to commit revert of migration %d: %v", m.Version, err)
+		}
+		return currentSchemaVersion(db)
+	}
+	return current, fmt.Errorf("applied schema version %d is unknown to this binary", current)
+}
+
+// runMigrateCommand implements the `migrate` subcommand for environments where schema changes
+// are applied in a controlled step (HISTORY_DB_AUTO_MIGRATE=false) rather than on startup.
+// Usage: main migrate [up|down|version]
+func runMigrateCommand(args []string) int {
+	if err := loadConfigOverrides(); err != nil {
+		logger.Error().Msgf("[Migrate] %v", err)
+		return 1
+	}
+	config := loadConfig()
+	if config.HistoryDBDSN == "" {
+		logger.Info().Msg("[Migrate] HISTORY_DB_DSN is not set; nothing to migrate.")
+		return 1
+	}
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	db, err := openHistoryDB(config)
+	if err != nil {
+		logger.Error().Msgf("[Migrate] %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	switch action {
+	case "up":
+		version, err := migrateUpLocked(db, config.HistoryDBDriver)
+		if err != nil {
+			logger.Error().Msgf("[Migrate] %v", err)
+			return 1
+		}
+		logger.Info().Msgf("[Migrate] Schema is at version %d (latest: %d).", version, latestSchemaVersion())
+	case "down":
+		version, err := migrateDown(db, config.HistoryDBDriver)
+		if err != nil {
+			logger.Error().Msgf("[Migrate] %v", err)
+			return 1
+		}
+		logger.Info().Msgf("[Migrate] Schema is at version %d (latest: %d).", version, latestSchemaVersion())
+	case "version":
+		version, err := currentSchemaVersion(db)
+		if err != nil {
+			logger.Error().Msgf("[Migrate] %v", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stdout, "current: %d\nlatest: %d\n", version, latestSchemaVersion())
+	default:
+		logger.Error().Msgf("[Migrate] Unknown action '%s'. Usage: migrate [up|down|version]", action)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,3 @@
+DROP INDEX idx_executions_script_started;
+DROP INDEX idx_executions_tracking_id;
+DROP TABLE executions;
@@ -0,0 +1,17 @@
+CREATE TABLE executions (
+    id           VARCHAR(64) PRIMARY KEY,
+    tracking_id  VARCHAR(255) NOT NULL,
+    process_id   BIGINT NOT NULL DEFAULT 0,
+    script_id    VARCHAR(255) NOT NULL,
+    script_name  VARCHAR(255) NOT NULL,
+    task_name    VARCHAR(255) NOT NULL DEFAULT '',
+    target_pod   VARCHAR(255) NOT NULL DEFAULT '',
+    status       VARCHAR(32) NOT NULL,
+    output       TEXT NOT NULL DEFAULT '',
+    error        TEXT NOT NULL DEFAULT '',
+    started_at   BIGINT NOT NULL,
+    finished_at  BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_executions_tracking_id ON executions (tracking_id);
+CREATE INDEX idx_executions_script_started ON executions (script_name, started_at);
@@ -0,0 +1 @@
+ALTER TABLE executions DROP COLUMN env_report;
@@ -0,0 +1 @@
+ALTER TABLE executions ADD COLUMN env_report TEXT NOT NULL DEFAULT '';
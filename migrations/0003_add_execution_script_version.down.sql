@@ -0,0 +1 @@
+ALTER TABLE executions DROP COLUMN script_version;
@@ -0,0 +1 @@
+ALTER TABLE executions ADD COLUMN script_version VARCHAR(64) NOT NULL DEFAULT '';
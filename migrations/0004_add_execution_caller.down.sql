@@ -0,0 +1,2 @@
+DROP INDEX idx_executions_caller_started;
+ALTER TABLE executions DROP COLUMN caller;
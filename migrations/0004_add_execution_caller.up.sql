@@ -0,0 +1,2 @@
+ALTER TABLE executions ADD COLUMN caller VARCHAR(255) NOT NULL DEFAULT '';
+CREATE INDEX idx_executions_caller_started ON executions (caller, started_at);
@@ -0,0 +1,13 @@
+CREATE TABLE audit_events (
+    id              VARCHAR(64) PRIMARY KEY,
+    occurred_at     BIGINT NOT NULL,
+    execution_id    VARCHAR(64) NOT NULL DEFAULT '',
+    tracking_id     VARCHAR(255) NOT NULL DEFAULT '',
+    caller          VARCHAR(255) NOT NULL,
+    script_name     VARCHAR(255) NOT NULL DEFAULT '',
+    outcome         VARCHAR(32) NOT NULL,
+    status_code     INTEGER NOT NULL,
+    event           TEXT NOT NULL
+);
+
+CREATE INDEX idx_audit_events_occurred_at ON audit_events (occurred_at);
@@ -0,0 +1,5 @@
+DROP INDEX idx_executions_process_id;
+ALTER TABLE executions DROP COLUMN tracking_updated_at;
+ALTER TABLE executions DROP COLUMN tracking_message;
+ALTER TABLE executions DROP COLUMN tracking_status;
+ALTER TABLE executions DROP COLUMN tracking_stage;
@@ -0,0 +1,5 @@
+ALTER TABLE executions ADD COLUMN tracking_stage VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE executions ADD COLUMN tracking_status VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE executions ADD COLUMN tracking_message TEXT NOT NULL DEFAULT '';
+ALTER TABLE executions ADD COLUMN tracking_updated_at BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX idx_executions_process_id ON executions (process_id);
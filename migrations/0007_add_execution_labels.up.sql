@@ -0,0 +1 @@
+ALTER TABLE executions ADD COLUMN labels TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE executions DROP COLUMN rerun_of;
+ALTER TABLE executions DROP COLUMN request;
@@ -0,0 +1,2 @@
+ALTER TABLE executions ADD COLUMN request TEXT NOT NULL DEFAULT '';
+ALTER TABLE executions ADD COLUMN rerun_of VARCHAR(64) NOT NULL DEFAULT '';
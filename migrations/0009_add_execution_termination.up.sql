@@ -0,0 +1 @@
+ALTER TABLE executions ADD COLUMN termination VARCHAR(16) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE executions ADD COLUMN failure_category VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+CREATE TABLE tracking_outbox (
+    id          VARCHAR(64) PRIMARY KEY,
+    process_id  BIGINT NOT NULL,
+    payload     TEXT NOT NULL,
+    queued_at   BIGINT NOT NULL,
+    attempts    INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_tracking_outbox_process_id ON tracking_outbox (process_id);
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// previousExecution references the last successful execution of a script
+type previousExecution struct {
+	ExecutionID string    `json:"executionId,omitempty"`
+	ProcessID   int64     `json:"processId,omitempty"`
+	FinishedAt  time.Time `json:"finishedAt"`
+}
+
+// recentSuccesses remembers the last successful execution of each script, for the minIntervalSeconds
+// guard. With a history store the executions table is used instead, so the guard holds across
+// restarts and replicas.
+type recentSuccesses struct {
+	mu   sync.Mutex
+	last map[string]previousExecution
+}
+
+var lastSuccesses = &recentSuccesses{last: make(map[string]previousExecution)}
+
+// record notes a successful execution of a script.
+func (r *recentSuccesses) record(script, executionID string, processID int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last[script] = previousExecution{ExecutionID: executionID, ProcessID: processID, FinishedAt: time.Now().UTC()}
+}
+
+// within returns the last successful execution of the script if it finished after since.
+func (r *recentSuccesses) within(script string, since time.Time) (*previousExecution, error) {
+	if executionStore != nil {
+		return executionStore.LastSuccessfulExecution(script, since)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, ok := r.last[script]
+	if !ok || previous.FinishedAt.Before(since) {
+		return nil, nil
+	}
+	return &previous, nil
+}
+
+// checkMinInterval returns the previous execution when a script with minIntervalSeconds already
+// ran successfully within the interval, meaning this execution is to be skipped.
+func checkMinInterval(def *ScriptDefinition) (*previousExecution, error) {
+	if def.MinIntervalSeconds <= 0 {
+		return nil, nil
+	}
+	previous, err := lastSuccesses.within(def.Name, time.Now().Add(-time.Duration(def.MinIntervalSeconds)*time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the last successful execution of script '%s': %v", def.Name, err)
+	}
+	return previous, nil
+}
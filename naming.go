@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// namingStrategy controls how JSON object keys are spelled in requests and responses.
+// Task Service integrations use camelCase, while some downstream consumers require snake_case.
+type namingStrategy string
+
+const (
+	namingAsIs  namingStrategy = ""           // Keys exactly as defined by the Go structs (historical mixed conventions)
+	namingCamel namingStrategy = "camelCase"  // e.g. trackingId, scriptId
+	namingSnake namingStrategy = "snake_case" // e.g. tracking_id, script_id
+)
+
+// parseNamingStrategy validates a strategy name from configuration or an Accept profile.
+func parseNamingStrategy(value string) (namingStrategy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "default", "asis":
+		return namingAsIs, nil
+	case "camelcase", "camel":
+		return namingCamel, nil
+	case "snake_case", "snake":
+		return namingSnake, nil
+	}
+	return namingAsIs, fmt.Errorf("unknown JSON naming strategy '%s' (supported: camelCase, snake_case)", value)
+}
+
+// parseEndpointNaming parses JSON_NAMING_ENDPOINTS ("/v1/options=snake_case,/v1/execute=camelCase").
+// Invalid entries are skipped; the route path must match gin's registered path (e.g. /v1/trigger/:script).
+func parseEndpointNaming(value string) map[string]namingStrategy {
+	strategies := make(map[string]namingStrategy)
+	for _, entry := range strings.Split(value, ",") {
+		path, name, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || path == "" {
+			continue
+		}
+		strategy, err := parseNamingStrategy(name)
+		if err != nil {
+			continue
+		}
+		strategies[path] = strategy
+	}
+	return strategies
+}
+
+// negotiateNaming picks the strategy for a request: an explicit Accept profile
+// (Accept: application/json; profile=snake_case) wins, then the per-endpoint configuration,
+// then the global JSON_NAMING_DEFAULT.
+func negotiateNaming(c *gin.Context) namingStrategy {
+	for _, accepted := range strings.Split(c.GetHeader("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil || (mediaType != "application/json" && mediaType != "*/*") {
+			continue
+		}
+		if profile, ok := params["profile"]; ok {
+			if strategy, err := parseNamingStrategy(profile); err == nil {
+				return strategy
+			}
+		}
+	}
+
+	config := configFromContext(c)
+	if strategy, ok := config.JSONNamingEndpoints[c.FullPath()]; ok {
+		return strategy
+	}
+	return config.JSONNamingDefault
+}
+
+// toSnakeCase converts "trackingId" / "TrackingID" to "tracking_id".
+func toSnakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at a lower->Upper boundary, or at the last capital of an acronym ("IDValue" -> "id_value")
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// toCamelCase converts "tracking_id" to "trackingId"; keys without underscores are returned unchanged.
+func toCamelCase(key string) string {
+	if !strings.Contains(key, "_") {
+		return key
+	}
+	parts := strings.Split(key, "_")
+	var b strings.Builder
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		if i == 0 || b.Len() == 0 {
+			b.WriteString(strings.ToLower(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// renameKeys recursively rewrites object keys in a decoded JSON value.
+func renameKeys(value interface{}, rename func(string) string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(v))
+		for key, inner := range v {
+			renamed[rename(key)] = renameKeys(inner, rename)
+		}
+		return renamed
+	case []interface{}:
+		for i := range v {
+			v[i] = renameKeys(v[i], rename)
+		}
+		return v
+	}
+	return value
+}
+
+// marshalWithNaming marshals v to JSON and rewrites its keys according to the strategy.
+func marshalWithNaming(v interface{}, strategy namingStrategy) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || strategy == namingAsIs {
+		return data, err
+	}
+
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep large integers (e.g. process IDs) exact
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	rename := toCamelCase
+	if strategy == namingSnake {
+		rename = toSnakeCase
+	}
+	return json.Marshal(renameKeys(decoded, rename))
+}
+
+// writeJSON writes a JSON response using the negotiated naming strategy.
+func writeJSON(c *gin.Context, statusCode int, v interface{}) {
+	data, err := marshalWithNaming(v, negotiateNaming(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, "Failed to encode response: "+err.Error()))
+		return
+	}
+	contentType := "application/json; charset=utf-8"
+	if isProblem(v) {
+		contentType = problemContentType
+	}
+	c.Data(statusCode, contentType, data)
+}
+
+// bindJSONWithNaming decodes a request body into v, accepting snake_case spellings of its
+// top-level fields (task_name, tracking_id, ...). Nested values such as taskData are user
+// data and are never renamed.
+func bindJSONWithNaming(c *gin.Context, v interface{}) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+
+	var topLevel map[string]json.RawMessage
+	if err := json.Unmarshal(body, &topLevel); err != nil {
+		return err
+	}
+	renamed := make(map[string]json.RawMessage, len(topLevel))
+	for key, raw := range topLevel {
+		renamed[toCamelCase(key)] = raw
+	}
+	normalized, err := json.Marshal(renamed)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, v)
+}
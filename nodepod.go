@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// isNodeTargeted reports whether a script runs on a node (via a privileged helper pod) rather than in a workload pod.
+func isNodeTargeted(def *ScriptDefinition) bool {
+	return def.NodeName != "" || len(def.NodeSelector) > 0
+}
+
+// resolveNodeName expands a ${PARAM} reference in a script's nodeName from the request parameters,
+// so one definition can target whichever node the caller names.
+func resolveNodeName(nodeName string, params map[string]string) (string, error) {
+	matches := placeholderPattern.FindAllStringSubmatch(nodeName, -1)
+	for _, match := range matches {
+		value, ok := params[sanitizeEnvVarName(match[1])]
+		if !ok || value == "" {
+			return "", fmt.Errorf("nodeName references parameter '%s' which was not provided", match[1])
+		}
+		nodeName = strings.ReplaceAll(nodeName, match[0], value)
+	}
+	return nodeName, nil
+}
+
+// runOnNode runs the command in the host namespaces of a specific node through a short-lived
+// privileged helper pod (hostPID + nsenter into PID 1), so node-level maintenance such as clearing
+// disk caches or rotating certificates can go through the same API as other scripts.
+func runOnNode(ctx context.Context, run *executorRun, nodeName string) (string, string, error) {
+	config, def := run.Config, run.Definition
+	if kubeClient == nil {
+		return "", "", fmt.Errorf("kubernetes client not initialized")
+	}
+	requirements, err := toResourceRequirements(def.Resources)
+	if err != nil {
+		return "", "", err
+	}
+	image := def.Image
+	if image == "" {
+		image = config.NodeHelperImage
+	}
+	privileged := true
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        dedicatedPodName(def.ID, run.ExecutionID),
+			Namespace:   config.Namespace,
+			Labels:      ownerLabels(run),
+			Annotations: ownerAnnotations(run),
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			NodeName:      nodeName,
+			NodeSelector:  def.NodeSelector,
+			HostPID:       true,
+			// Node maintenance must be able to land on tainted (e.g. control-plane or drained) nodes
+			Tolerations: []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+			Containers: []corev1.Container{{
+				Name:  "script",
+				Image: image,
+				// Enter the mount, UTS, IPC, network and PID namespaces of the node's init process
+				Command:         []string{"nsenter", "-t", "1", "-m", "-u", "-i", "-n", "-p", "--", "/bin/sh", "-c", run.Command},
+				Resources:       requirements,
+				SecurityContext: &corev1.SecurityContext{Privileged: &privileged},
+			}},
+		},
+	}
+	return runPodToCompletion(ctx, run, pod)
+}
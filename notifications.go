@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Notification channel types
+const (
+	notificationSlack = "slack"
+	notificationTeams = "teams"
+	notificationEmail = "email"
+)
+
+// Completion states a notification channel can subscribe to with `on`
+const (
+	notifyOnSuccess = "success"
+	notifyOnFailure = "failure"
+)
+
+// notificationClient posts completion notifications; chat webhooks are expected to answer quickly
+var notificationClient = &http.Client{Timeout: 10 * time.Second}
+
+// NotificationChannel is a chat channel or email recipient list a script definition notifies when
+// an execution finishes.
+type NotificationChannel struct {
+	Type          string   `json:"type"`                    // "slack", "teams" or "email"
+	WebhookURL    string   `json:"webhookUrl,omitempty"`    // Incoming webhook URL (slack, teams)
+	WebhookURLEnv string   `json:"webhookUrlEnv,omitempty"` // Or: env var holding the URL, keeping it out of the definitions
+	To            []string `json:"to,omitempty"`            // Recipient addresses (email), sent through SMTP_HOST
+	Template      string   `json:"template,omitempty"`      // Go text/template of the message, rendered with a completionEvent
+	On            []string `json:"on,omitempty"`            // "success" and/or "failure"; defaults to both
+}
+
+// Default message templates per channel type, using each type's link syntax
+var defaultNotificationTemplates = map[string]string{
+	notificationSlack: "{{if .Successful}}:white_check_mark:{{else}}:x:{{end}} Script *{{.Script}}*{{if .Version}} ({{.Version}}){{end}} {{.Status}} in {{.Duration}}" +
+		"{{if .Error}}: {{.Error}}{{end}}{{if .Link}} - <{{.Link}}|execution record>{{end}}",
+	notificationTeams: "Script **{{.Script}}**{{if .Version}} ({{.Version}}){{end}} {{.Status}} in {{.Duration}}" +
+		"{{if .Error}}: {{.Error}}{{end}}{{if .Link}} - [execution record]({{.Link}}){{end}}",
+	notificationEmail: "Script: {{.Script}}{{if .Version}} ({{.Version}}){{end}}\nStatus: {{.Status}}\nDuration: {{.Duration}}\n" +
+		"Started: {{.Started.UTC.Format \"2006-01-02 15:04:05 MST\"}}\n{{if .Caller}}Caller: {{.Caller}}\n{{end}}{{if .Pod}}Pod: {{.Pod}}\n{{end}}" +
+		"Tracking ID: {{.TrackingID}}\n{{if .ProcessID}}Process ID: {{.ProcessID}}\n{{end}}{{if .Error}}\nError: {{.Error}}\n{{end}}" +
+		"{{if .Link}}\nExecution record: {{.Link}}\n{{end}}",
+}
+
+// completionEvent describes a finished execution, for notification templates and callbacks.
+type completionEvent struct {
+	Script      string
+	Version     string
+	Status      string // SUCCESSFUL or FAILED
+	Successful  bool
+	Started     time.Time
+	Duration    time.Duration
+	Error       string
+	Output      string // Full output, for callbacks
+	Caller      string
+	Pod         string
+	TrackingID  string
+	ExecutionID string
+	ProcessID   int64
+	Link        string // Link to the execution record, from EXECUTION_LINK_TEMPLATE
+}
+
+// validateNotifications checks the notification channels of a script definition.
+func validateNotifications(def *ScriptDefinition) error {
+	for i, channel := range def.Notifications {
+		if _, ok := defaultNotificationTemplates[channel.Type]; !ok {
+			return fmt.Errorf("notification %d has unsupported type '%s' (supported: slack, teams, email)", i+1, channel.Type)
+		}
+		if channel.Type == notificationEmail {
+			if len(channel.To) == 0 {
+				return fmt.Errorf("email notification %d has no 'to' recipients", i+1)
+			}
+			for _, address := range channel.To {
+				if _, err := mail.ParseAddress(address); err != nil {
+					return fmt.Errorf("email notification %d has an invalid recipient '%s': %v", i+1, address, err)
+				}
+			}
+		} else if (channel.WebhookURL == "") == (channel.WebhookURLEnv == "") {
+			return fmt.Errorf("notification %d must set exactly one of 'webhookUrl' and 'webhookUrlEnv'", i+1)
+		}
+		if channel.Template != "" {
+			if _, err := template.New("notification").Parse(channel.Template); err != nil {
+				return fmt.Errorf("notification %d has an invalid template: %v", i+1, err)
+			}
+		}
+		for _, on := range channel.On {
+			if on != notifyOnSuccess && on != notifyOnFailure {
+				return fmt.Errorf("notification %d has unsupported 'on' value '%s' (supported: success, failure)", i+1, on)
+			}
+		}
+	}
+	return nil
+}
+
+// executionLink renders EXECUTION_LINK_TEMPLATE for an execution, replacing {processId},
+// {trackingId} and {executionId}. It returns "" if no template is configured.
+func executionLink(config *Config, event *completionEvent) string {
+	if config.ExecutionLinkTemplate == "" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{processId}", strconv.FormatInt(event.ProcessID, 10),
+		"{trackingId}", event.TrackingID,
+		"{executionId}", event.ExecutionID,
+	).Replace(config.ExecutionLinkTemplate)
+}
+
+// sendCompletionNotifications posts the outcome of an execution to the script's notification
+// channels. Delivery happens in the background and failures are only logged, so a slow or broken
+// webhook never delays or fails the execution itself.
+func sendCompletionNotifications(config *Config, def *ScriptDefinition, event completionEvent) {
+	if len(def.Notifications) == 0 {
+		return
+	}
+	event.Link = executionLink(config, &event)
+	on := notifyOnFailure
+	if event.Successful {
+		on = notifyOnSuccess
+	}
+
+	for _, channel := range def.Notifications {
+		if len(channel.On) > 0 && !containsString(channel.On, on) {
+			continue
+		}
+		go func(channel NotificationChannel) {
+			if err := postNotification(config, channel, &event); err != nil {
+				logger.Error().Str("trackingId", event.TrackingID).Msgf("Failed to send %s notification for script '%s': %v", channel.Type, event.Script, err)
+			}
+		}(channel)
+	}
+}
+
+// postNotification renders the message of one channel and posts it to the channel's webhook, or
+// mails it to the channel's recipients.
+func postNotification(config *Config, channel NotificationChannel, event *completionEvent) error {
+	text := channel.Template
+	if text == "" {
+		text = defaultNotificationTemplates[channel.Type]
+	}
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, event); err != nil {
+		return fmt.Errorf("failed to render template: %v", err)
+	}
+	if channel.Type == notificationEmail {
+		return sendNotificationEmail(config, channel.To, fmt.Sprintf("[k8s-script-executor] Script %s %s", event.Script, event.Status), message.String())
+	}
+
+	webhookURL := channel.WebhookURL
+	if channel.WebhookURLEnv != "" {
+		webhookURL = os.Getenv(channel.WebhookURLEnv)
+		if webhookURL == "" {
+			return fmt.Errorf("env var '%s' holding the webhook URL is not set", channel.WebhookURLEnv)
+		}
+	}
+
+	var payload interface{}
+	switch channel.Type {
+	case notificationTeams:
+		// Office 365 connector MessageCard, colored by outcome
+		color := "2EB886"
+		if !event.Successful {
+			color = "D00000"
+		}
+		payload = map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    fmt.Sprintf("Script %s %s", event.Script, event.Status),
+			"themeColor": color,
+			"text":       message.String(),
+		}
+	default:
+		payload = map[string]string{"text": message.String()}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	resp, err := notificationClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sendNotificationEmail mails a plain-text message through SMTP_HOST. STARTTLS is used when the server
+// offers it; credentials are only sent over TLS (or to localhost), as enforced by net/smtp.
+func sendNotificationEmail(config *Config, to []string, subject, body string) error {
+	if config.SMTPHost == "" || config.SMTPFrom == "" {
+		return fmt.Errorf("SMTP_HOST and SMTP_FROM must be set to send email notifications")
+	}
+	var auth smtp.Auth
+	if config.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", config.SMTPFrom)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(config.SMTPHost, strconv.Itoa(config.SMTPPort))
+	return smtp.SendMail(addr, auth, config.SMTPFrom, to, message.Bytes())
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// opaInput is the document sent to OPA as `input` for every execute request
+type opaInput struct {
+	Script     opaScript              `json:"script"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Caller     Caller                 `json:"caller"`
+	Namespace  string                 `json:"namespace"`
+	TaskName   string                 `json:"taskName"`
+	TrackingID string                 `json:"trackingId"`
+	DryRun     bool                   `json:"dryRun"`
+}
+
+// opaScript describes the requested script in opaInput
+type opaScript struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Version string   `json:"version,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Command string   `json:"command,omitempty"`
+}
+
+// opaDecision is the policy result. Policies may return a plain boolean or an object with a reason.
+type opaDecision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// evaluateOPAPolicy asks the OPA decision endpoint (OPA_URL, e.g.
+// http://localhost:8181/v1/data/executor/decision) whether the execution may proceed.
+// It returns nil when allowed or no OPA_URL is configured, and an error carrying the deny reason
+// otherwise. OPA being unreachable or returning an undefined decision denies the execution.
+func evaluateOPAPolicy(config *Config, input opaInput) error {
+	if config.OPAURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{"input": input})
+	if err != nil {
+		return fmt.Errorf("failed to encode policy input: %v", err)
+	}
+
+	client := &http.Client{Timeout: config.OPATimeout}
+	resp, err := client.Post(config.OPAURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logger.Error().Str("trackingId", input.TrackingID).Msgf("[OPA] Policy evaluation failed for script '%s': %v", input.Script.Name, err)
+		return fmt.Errorf("policy evaluation failed: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		logger.Info().Str("trackingId", input.TrackingID).Msgf("[OPA] Policy evaluation for script '%s' returned status %d: %s", input.Script.Name, resp.StatusCode, string(respBody))
+		return fmt.Errorf("policy evaluation failed with status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("failed to decode policy decision: %v", err)
+	}
+	if len(result.Result) == 0 {
+		return fmt.Errorf("policy decision is undefined")
+	}
+
+	var decision opaDecision
+	if err := json.Unmarshal(result.Result, &decision.Allow); err != nil {
+		if err := json.Unmarshal(result.Result, &decision); err != nil {
+			return fmt.Errorf("policy decision must be a boolean or {\"allow\": ..., \"reason\": ...}: %v", err)
+		}
+	}
+	logger.Info().Str("trackingId", input.TrackingID).Msgf("[OPA] Policy decision for script '%s' by caller '%s': allow=%v reason=%q", input.Script.Name, input.Caller.Name, decision.Allow, decision.Reason)
+	if !decision.Allow {
+		if decision.Reason == "" {
+			decision.Reason = "denied by policy"
+		}
+		return fmt.Errorf("%s", decision.Reason)
+	}
+	return nil
+}
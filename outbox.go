@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// outboxEntry is a process tracking update that could not be delivered yet
+type outboxEntry struct {
+	ID        int64           `json:"id"` // Unique within the outbox, in queueing order
+	ProcessID int64           `json:"processId"`
+	Payload   json.RawMessage `json:"payload"` // Marshaled ProcessTrackingUpdatePayload
+	QueuedAt  time.Time       `json:"queuedAt"`
+	Attempts  int             `json:"attempts"` // Background delivery attempts so far
+	RowID     string          `json:"-"`        // Key of the entry in the history database's outbox
+}
+
+// outboxFlushLockID is the Postgres advisory lock key held while a replica delivers the outbox, so
+// replicas sharing the history database don't deliver the same updates at the same time.
+const outboxFlushLockID = 7305135613
+
+// trackingOutbox journals process tracking updates that failed with a transient error, so they are
+// delivered once the tracking service is back instead of leaving records in PROGRESS. With a
+// history database the journal is its tracking_outbox table, shared by all replicas; otherwise it is
+// a JSON file rewritten atomically on every change, which survives executor restarts when
+// TRACKING_OUTBOX_PATH is on a persistent volume.
+type trackingOutbox struct {
+	mu      sync.Mutex
+	path    string
+	entries []outboxEntry
+	lastID  int64
+	store   *ExecutionStore // Set when the journal is kept in the history database
+}
+
+// processTrackingOutbox is the outbox of failed updates, nil when neither HISTORY_DB_DSN nor
+// TRACKING_OUTBOX_PATH is set
+var processTrackingOutbox *trackingOutbox
+
+// openTrackingOutbox loads the journal at path, starting empty if it doesn't exist yet.
+func openTrackingOutbox(path string) (*trackingOutbox, error) {
+	outbox := &trackingOutbox{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return outbox, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tracking outbox '%s': %v", path, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &outbox.entries); err != nil {
+			return nil, fmt.Errorf("failed to parse tracking outbox '%s': %v", path, err)
+		}
+	}
+	for _, entry := range outbox.entries {
+		if entry.ID > outbox.lastID {
+			outbox.lastID = entry.ID
+		}
+	}
+	return outbox, nil
+}
+
+// pending reports whether updates of a process are waiting in the outbox. Later updates of that
+// process must queue behind them, or a stale PROGRESS could overwrite a final status.
+func (o *trackingOutbox) pending(processID int64) bool {
+	if o == nil {
+		return false
+	}
+	if o.store != nil {
+		pending, err := o.store.trackingOutboxPending(processID)
+		if err != nil {
+			logger.Error().Int64("processId", processID).Msgf("[ProcessTracking OUTBOX] Failed to look up pending updates: %v", err)
+		}
+		return pending
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, entry := range o.entries {
+		if entry.ProcessID == processID {
+			return true
+		}
+	}
+	return false
+}
+
+// size returns the number of queued updates.
+func (o *trackingOutbox) size() int {
+	if o == nil {
+		return 0
+	}
+	if o.store != nil {
+		size, err := o.store.trackingOutboxSize()
+		if err != nil {
+			logger.Error().Msgf("[ProcessTracking OUTBOX] Failed to count pending updates: %v", err)
+		}
+		return size
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// add journals an update for background delivery.
+func (o *trackingOutbox) add(processID int64, payload []byte) error {
+	if o.store != nil {
+		return o.store.addTrackingOutboxEntry(processID, payload)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.lastID++
+	o.entries = append(o.entries, outboxEntry{ID: o.lastID, ProcessID: processID, Payload: payload, QueuedAt: time.Now().UTC()})
+	return o.save()
+}
+
+// save rewrites the journal. The caller holds mu.
+func (o *trackingOutbox) save() error {
+	data, err := json.Marshal(o.entries)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomically(o.path, data)
+}
+
+// writeFileAtomically replaces a file through a temporary file in the same directory, so a crash
+// mid-write never leaves it corrupted.
+func writeFileAtomically(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after the rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// flush tries to deliver every queued update once, oldest first. When an update of a process
+// fails, the later updates of that process wait for the next round so they stay in order; other
+// processes are unaffected. Acknowledged updates, and those the tracking service rejects for good
+// (e.g. an unknown process), are removed from the journal. In the history database, one replica at
+// a time delivers a round; the others skip it.
+func (o *trackingOutbox) flush(config *Config) {
+	var queued []outboxEntry
+	if o.store != nil {
+		release, acquired, err := o.store.lockTrackingOutbox()
+		if err != nil {
+			logger.Error().Msgf("[ProcessTracking OUTBOX] Failed to lock the outbox: %v", err)
+			return
+		}
+		if !acquired {
+			return // Another replica is delivering this round
+		}
+		defer release()
+		if queued, err = o.store.trackingOutboxEntries(); err != nil {
+			logger.Error().Msgf("[ProcessTracking OUTBOX] Failed to read the outbox: %v", err)
+			return
+		}
+	} else {
+		o.mu.Lock()
+		queued = append([]outboxEntry(nil), o.entries...)
+		o.mu.Unlock()
+	}
+	if len(queued) == 0 {
+		return
+	}
+
+	// Each entry gets one attempt per round; the round interval is the backoff
+	flushConfig := *config
+	flushConfig.ProcessTrackingMaxAttempts = 1
+
+	done := make(map[int64]bool)
+	attempted := make(map[int64]bool)
+	blocked := make(map[int64]bool)
+	for _, entry := range queued {
+		if blocked[entry.ProcessID] {
+			continue
+		}
+		attempted[entry.ID] = true
+		retryable, err := deliverProcessTrackingUpdate(context.Background(), &flushConfig, entry.ProcessID, entry.Payload)
+		switch {
+		case err == nil:
+			logger.Info().Int64("processId", entry.ProcessID).Msgf("[ProcessTracking OUTBOX] Delivered update queued at %s.", entry.QueuedAt.Format(time.RFC3339))
+			done[entry.ID] = true
+		case !retryable:
+			logger.Error().Int64("processId", entry.ProcessID).Msgf("[ProcessTracking OUTBOX] Dropping update queued at %s, rejected by the tracking service: %v", entry.QueuedAt.Format(time.RFC3339), err)
+			done[entry.ID] = true
+		default:
+			logger.Warn().Int64("processId", entry.ProcessID).Msgf("[ProcessTracking OUTBOX] Update queued at %s still fails (attempt %d): %v", entry.QueuedAt.Format(time.RFC3339), entry.Attempts+1, err)
+			blocked[entry.ProcessID] = true
+		}
+	}
+
+	if o.store != nil {
+		if err := o.store.settleTrackingOutbox(queued, done, attempted); err != nil {
+			logger.Error().Msgf("[ProcessTracking OUTBOX] Failed to update the outbox: %v", err)
+		}
+		return
+	}
+
+	// Entries may have been queued meanwhile; keep them and everything not delivered
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	remaining := o.entries[:0]
+	for _, entry := range o.entries {
+		if done[entry.ID] {
+			continue
+		}
+		if attempted[entry.ID] {
+			entry.Attempts++
+		}
+		remaining = append(remaining, entry)
+	}
+	o.entries = remaining
+	if err := o.save(); err != nil {
+		logger.Error().Msgf("[ProcessTracking OUTBOX] Failed to write outbox '%s': %v", o.path, err)
+	}
+}
+
+// startTrackingOutbox opens the outbox in the history database, or else at TRACKING_OUTBOX_PATH, and
+// delivers its updates every TRACKING_OUTBOX_RETRY_SECONDS until acknowledged. It does nothing when
+// neither is configured.
+func startTrackingOutbox(config *Config) error {
+	var outbox *trackingOutbox
+	switch {
+	case executionStore != nil:
+		if config.TrackingOutboxPath != "" {
+			logger.Warn().Msgf("TRACKING_OUTBOX_PATH '%s' is ignored: the outbox is kept in the history database.", config.TrackingOutboxPath)
+		}
+		outbox = &trackingOutbox{store: executionStore}
+		logger.Info().Msgf("Process tracking outbox in the history database holds %d pending update(s); retrying every %s.", outbox.size(), config.TrackingOutboxRetryInterval)
+	case config.TrackingOutboxPath != "":
+		var err error
+		if outbox, err = openTrackingOutbox(config.TrackingOutboxPath); err != nil {
+			return err
+		}
+		logger.Info().Msgf("Process tracking outbox at '%s' holds %d pending update(s); retrying every %s.", outbox.path, outbox.size(), config.TrackingOutboxRetryInterval)
+	default:
+		return nil
+	}
+	processTrackingOutbox = outbox
+
+	go func() {
+		ticker := time.NewTicker(config.TrackingOutboxRetryInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			outbox.flush(currentConfig()) // A reload may have retargeted the tracking service
+		}
+	}()
+	return nil
+}
+
+// addTrackingOutboxEntry queues an update in the tracking_outbox table.
+func (s *ExecutionStore) addTrackingOutboxEntry(processID int64, payload []byte) error {
+	_, err := s.db.Exec(rebindQuery(s.dialect, `INSERT INTO tracking_outbox (id, process_id, payload, queued_at, attempts) VALUES (?, ?, ?, ?, 0)`),
+		newRecordID(), processID, string(payload), time.Now().UnixMilli())
+	return err
+}
+
+// trackingOutboxPending reports whether updates of a process are queued in the tracking_outbox table.
+func (s *ExecutionStore) trackingOutboxPending(processID int64) (bool, error) {
+	var count int
+	err := s.db.QueryRow(rebindQuery(s.dialect, `SELECT COUNT(*) FROM tracking_outbox WHERE process_id = ?`), processID).Scan(&count)
+	return count > 0, err
+}
+
+// trackingOutboxSize returns the number of updates queued in the tracking_outbox table.
+func (s *ExecutionStore) trackingOutboxSize() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM tracking_outbox`).Scan(&count)
+	return count, err
+}
+
+// trackingOutboxEntries returns the queued updates in queueing order (record IDs sort by creation
+// time). Their IDs only number the entries of one flush round; RowID identifies them in the table.
+func (s *ExecutionStore) trackingOutboxEntries() ([]outboxEntry, error) {
+	rows, err := s.db.Query(`SELECT id, process_id, payload, queued_at, attempts FROM tracking_outbox ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []outboxEntry
+	for rows.Next() {
+		var entry outboxEntry
+		var payload string
+		var queuedAt int64
+		if err := rows.Scan(&entry.RowID, &entry.ProcessID, &payload, &queuedAt, &entry.Attempts); err != nil {
+			return nil, err
+		}
+		entry.ID = int64(len(entries) + 1)
+		entry.Payload = json.RawMessage(payload)
+		entry.QueuedAt = time.UnixMilli(queuedAt).UTC()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// settleTrackingOutbox removes the entries of a flush round that are done and counts the attempt of
+// the others that were tried.
+func (s *ExecutionStore) settleTrackingOutbox(queued []outboxEntry, done, attempted map[int64]bool) error {
+	for _, entry := range queued {
+		var err error
+		switch {
+		case done[entry.ID]:
+			_, err = s.db.Exec(rebindQuery(s.dialect, `DELETE FROM tracking_outbox WHERE id = ?`), entry.RowID)
+		case attempted[entry.ID]:
+			_, err = s.db.Exec(rebindQuery(s.dialect, `UPDATE tracking_outbox SET attempts = attempts + 1 WHERE id = ?`), entry.RowID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockTrackingOutbox takes the Postgres advisory lock of a flush round without waiting for it,
+// reporting whether it was acquired. SQLite databases are local to a single replica and need no lock.
+func (s *ExecutionStore) lockTrackingOutbox() (release func(), acquired bool, err error) {
+	if s.dialect != "postgres" {
+		return func() {}, true, nil
+	}
+
+	// Advisory locks are held per session, so pin one connection for the lock's lifetime
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, outboxFlushLockID).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return nil, false, err
+	}
+	return func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, outboxFlushLockID); err != nil {
+			logger.Error().Msgf("[ProcessTracking OUTBOX] Failed to release the outbox lock: %v", err)
+		}
+		conn.Close()
+	}, true, nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Parameter delivery modes (deliverAs)
+const (
+	deliverAsEnv  = "env"  // The value is passed in an env var (default)
+	deliverAsFile = "file" // The value is written to a file whose path is passed in <ENV>_FILE
+)
+
+// fileDeliveryBackends are the backends able to write parameter files where the command runs
+var fileDeliveryBackends = []string{backendPodExec, backendLocal, backendSSH}
+
+// paramFile is a parameter value delivered as a file instead of an env var
+type paramFile struct {
+	Path    string
+	Content string
+}
+
+// paramFilePath returns where a parameter's file is written. The path contains only the numeric
+// execution ID and a valid env var name, so it needs no quoting in shell commands.
+func paramFilePath(executionID, envName string) string {
+	return fmt.Sprintf("/tmp/script-executor-%s-%s", executionID, envName)
+}
+
+// deliversAsFile reports whether a parameter value is delivered as a file: when the parameter asks
+// for it, or when the value exceeds PARAM_FILE_THRESHOLD_BYTES and the backend can write files.
+// Env vars are limited in size (the whole command must fit in 128 KiB) and visible in /proc.
+func deliversAsFile(config *Config, backend string, param InputParameterDef, value string) bool {
+	if param.DeliverAs == deliverAsFile {
+		return true
+	}
+	return config.ParamFileThreshold > 0 && len(value) > config.ParamFileThreshold && containsString(fileDeliveryBackends, backend)
+}
+
+// validateParameterDelivery checks the "deliverAs" field of a script's parameters.
+func validateParameterDelivery(def *ScriptDefinition) error {
+	for _, param := range def.Parameters {
+		switch param.DeliverAs {
+		case "", deliverAsEnv:
+		case deliverAsFile:
+			if backend := inferBackend(def); !containsString(fileDeliveryBackends, backend) {
+				return fmt.Errorf("parameter '%s' is delivered as a file, which backend '%s' doesn't support (supported: %v)", param.Name, backend, fileDeliveryBackends)
+			}
+		default:
+			return fmt.Errorf("parameter '%s' has unknown 'deliverAs' '%s' (supported: env, file)", param.Name, param.DeliverAs)
+		}
+	}
+	return nil
+}
+
+// deliverParamFiles writes the parameter files of an execution with write before its command runs,
+// and returns a function removing them again with exec.
+func deliverParamFiles(ctx context.Context, run *executorRun, write func(file paramFile) error, exec func(command string) (string, error)) (func(), error) {
+	if len(run.Files) == 0 {
+		return func() {}, nil
+	}
+	paths := make([]string, 0, len(run.Files))
+	cleanup := func() {
+		if len(paths) == 0 {
+			return
+		}
+		if output, err := exec("rm -f " + strings.Join(paths, " ")); err != nil {
+			executionLog(ctx).Warn().Msgf("Failed to remove the parameter files of script '%s': %v. Output: %s", run.Definition.Name, err, output)
+		}
+	}
+	for _, file := range run.Files {
+		if err := write(file); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to write parameter file '%s': %v", file.Path, err)
+		}
+		paths = append(paths, file.Path)
+	}
+	executionLog(ctx).Debug().Msgf("Delivered %d parameter(s) of script '%s' as files.", len(run.Files), run.Definition.Name)
+	return cleanup, nil
+}
+
+// paramFileWriteCommand returns the shell command writing a parameter file from stdin, readable by
+// its owner only: the script's runAsUser, if it has one.
+func paramFileWriteCommand(def *ScriptDefinition, path string) string {
+	command := "umask 077 && cat > " + path
+	if def.RunAsUser != "" {
+		command += " && chown " + def.RunAsUser + " " + path
+	}
+	return command
+}
+
+// writeFileInPod writes a parameter file into a pod, passing the content on stdin so it appears in
+// no command line.
+func writeFileInPod(namespace, podName string, def *ScriptDefinition, file paramFile, as *Caller) error {
+	args := append([]string{"exec", "-i", "-n", namespace}, impersonationArgs(as)...)
+	cmd := exec.Command("kubectl", append(args, podName, "--", "/bin/sh", "-c", paramFileWriteCommand(def, file.Path))...)
+	cmd.Stdin = strings.NewReader(file.Content)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// writeFileLocally writes a parameter file on the executor's host.
+func writeFileLocally(def *ScriptDefinition, file paramFile) error {
+	cmd := exec.Command("/bin/sh", "-c", paramFileWriteCommand(def, file.Path))
+	cmd.Stdin = strings.NewReader(file.Content)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tektonPermissions are the permissions Tekton scripts need in their tektonNamespace
+var tektonPermissions = []permission{
+	{"create", "tekton.dev", "pipelineruns", "", "Create PipelineRuns"},
+	{"get", "tekton.dev", "pipelineruns", "", "Get PipelineRuns"},
+}
+
+// namespacePermissions returns the permissions the scripts need per namespace: requiredPermissions in
+// NAMESPACE, where workload pods are exec'd, and tektonPermissions in the namespace of every enabled
+// Tekton script. Without definitions, only NAMESPACE is returned.
+func namespacePermissions(config *Config, definitions []ScriptDefinition) map[string][]permission {
+	perms := map[string][]permission{config.Namespace: requiredPermissions}
+	tektonNamespaces := make(map[string]bool)
+	for i := range definitions {
+		def := &definitions[i]
+		if def.Disabled || backendOf(config, def) != backendTekton {
+			continue
+		}
+		namespace := def.TektonNamespace
+		if namespace == "" {
+			namespace = config.Namespace
+		}
+		if !tektonNamespaces[namespace] {
+			tektonNamespaces[namespace] = true
+			perms[namespace] = append(append([]permission{}, perms[namespace]...), tektonPermissions...)
+		}
+	}
+	return perms
+}
+
+// namespacePermissionResult is the permission check of one namespace
+type namespacePermissionResult struct {
+	Namespace string
+	Err       error
+}
+
+// verifyNamespacePermissions checks the permissions of every namespace the scripts use, NAMESPACE
+// first and the others sorted. Used by the readiness probe and /v1/status. When the definitions
+// can't be loaded, the namespaces of their Tekton scripts are unknown and only NAMESPACE is checked.
+func verifyNamespacePermissions(ctx context.Context, config *Config) []namespacePermissionResult {
+	var definitions []ScriptDefinition
+	registry, err := currentScripts(config.ScriptsPath)
+	if err != nil {
+		logger.Error().Msgf("[RBAC] Checking the permissions of NAMESPACE only, as the script definitions failed to load: %v", err)
+	} else {
+		definitions = registry.definitions
+	}
+	perms := namespacePermissions(config, definitions)
+	namespaces := make([]string, 0, len(perms))
+	for namespace := range perms {
+		if namespace != config.Namespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	namespaces = append([]string{config.Namespace}, namespaces...)
+
+	results := make([]namespacePermissionResult, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		err := withTimeout(ctx, func(ctx context.Context) error {
+			return verifyPermissions(ctx, kubeClient, namespace, perms[namespace])
+		})
+		results = append(results, namespacePermissionResult{Namespace: namespace, Err: err})
+	}
+	return results
+}
+
+// checkScriptNamespacePermissions logs, at startup, the namespaces whose scripts lack permissions.
+// Unlike a denied NAMESPACE, they don't stop the executor; the readiness probe reports them.
+func checkScriptNamespacePermissions(config *Config) {
+	for _, result := range verifyNamespacePermissions(context.Background(), config) {
+		if result.Err != nil {
+			logger.Error().Msgf("Permission check FAILED for the scripts of namespace '%s': %v", result.Namespace, result.Err)
+		}
+	}
+}
+
+// permissionReport is the outcome of the last permission check of the monitor
+type permissionReport struct {
+	CheckedAt time.Time
+	Results   []namespacePermissionResult
+}
+
+// lastPermissions is the last report of the permission monitor; nil while it doesn't run
+var lastPermissions atomic.Pointer[permissionReport]
+
+// startPermissionMonitor re-runs the permission checks every PERMISSION_CHECK_INTERVAL_SECONDS, so
+// permissions revoked while the executor runs make it unready and reject executions with a clear
+// 503 instead of failing them with exec errors.
+func startPermissionMonitor(config *Config) {
+	if kubeClient == nil || config.SkipPermissionCheck || config.PermissionCheckInterval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			refreshPermissions(context.Background(), currentConfig())
+			time.Sleep(config.PermissionCheckInterval)
+		}
+	}()
+	logger.Info().Msgf("[RBAC] Permission monitor started (interval: %s).", config.PermissionCheckInterval)
+}
+
+// refreshPermissions re-runs the permission checks, stores the report and logs namespaces whose
+// permissions were revoked or restored since the previous check.
+func refreshPermissions(ctx context.Context, config *Config) *permissionReport {
+	report := &permissionReport{Results: verifyNamespacePermissions(ctx, config), CheckedAt: time.Now().UTC()}
+	previous := lastPermissions.Swap(report)
+	for _, result := range report.Results {
+		wasDenied := previous != nil && previous.denied(result.Namespace) != nil
+		switch {
+		case result.Err != nil && !wasDenied:
+			logger.Error().Msgf("[RBAC] Permissions revoked in namespace '%s'; its scripts are rejected until they are restored: %v", result.Namespace, result.Err)
+		case result.Err == nil && wasDenied:
+			logger.Info().Msgf("[RBAC] Permissions restored in namespace '%s'.", result.Namespace)
+		}
+	}
+	return report
+}
+
+// denied returns the permission error of a namespace, or nil if the report has none for it.
+func (r *permissionReport) denied(namespace string) error {
+	for _, result := range r.Results {
+		if result.Namespace == namespace {
+			return result.Err
+		}
+	}
+	return nil
+}
+
+// currentPermissions returns the per-namespace permission checks: the monitor's last report while
+// it runs, so probes don't each send access reviews, or else a fresh check.
+func currentPermissions(ctx context.Context, config *Config) []namespacePermissionResult {
+	if report := lastPermissions.Load(); report != nil {
+		return report.Results
+	}
+	return verifyNamespacePermissions(ctx, config)
+}
+
+// checkRevokedPermissions returns an error when the monitor found the permissions a script needs
+// revoked in the namespace it runs in.
+func checkRevokedPermissions(config *Config, def *ScriptDefinition) error {
+	report := lastPermissions.Load()
+	if report == nil {
+		return nil
+	}
+	namespace := config.Namespace
+	if backendOf(config, def) == backendTekton && def.TektonNamespace != "" {
+		namespace = def.TektonNamespace
+	}
+	if err := report.denied(namespace); err != nil {
+		return fmt.Errorf("the executor lacks the Kubernetes permissions script '%s' needs (checked %s): %v", def.Name, report.CheckedAt.Format(time.RFC3339), err)
+	}
+	return nil
+}
+
+// postPermissionsCheck handles POST /admin/permissions/check, re-running the permission checks now.
+// The result updates readiness and the execute rejection like a periodic check.
+func postPermissionsCheck(c *gin.Context) {
+	config := configFromContext(c)
+	if kubeClient == nil || config.SkipPermissionCheck {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotEnabled, "Permission checks are disabled (SKIP_PERMISSION_CHECK or no Kubernetes client)"))
+		return
+	}
+	logger.Info().Msgf("[RBAC] Permission check requested by %s.", callerFromContext(c).Name)
+	report := refreshPermissions(c.Request.Context(), config)
+	namespaces := gin.H{}
+	allowed := true
+	for _, result := range report.Results {
+		namespaces[result.Namespace] = "ok"
+		if result.Err != nil {
+			namespaces[result.Namespace] = result.Err.Error()
+			allowed = false
+		}
+	}
+	writeJSON(c, http.StatusOK, gin.H{"allowed": allowed, "checkedAt": report.CheckedAt, "namespaces": namespaces})
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PipelineNode is one script of a pipeline definition. Nodes run as soon as every node they
+// depend on has succeeded, so independent branches run in parallel.
+type PipelineNode struct {
+	ID        string   `json:"id,omitempty"`      // Referenced by dependsOn; defaults to the script name
+	Script    string   `json:"script"`            // Required; name of the script definition to run
+	Version   string   `json:"version,omitempty"` // Pin a script version; the latest version runs when empty
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// PipelineRollout is the rollout strategy of a pipeline. With the canary strategy, the canary node
+// runs alone first; the other nodes only start once it succeeded (exit code 0, not a mapped WARNING
+// or SKIPPED) and its output matched outputPattern, if set. Otherwise the rollout is aborted: the
+// other nodes are skipped and the pipeline fails with the results so far.
+type PipelineRollout struct {
+	Strategy      string `json:"strategy"`                // Required; "canary"
+	CanaryNode    string `json:"canaryNode,omitempty"`    // Node ID; defaults to the first node without dependencies
+	OutputPattern string `json:"outputPattern,omitempty"` // Regular expression the canary's output must match
+}
+
+// pipelineStrategyCanary runs one node first and rolls out to the others only if it succeeds
+const pipelineStrategyCanary = "canary"
+
+// Pipeline node statuses reported in the aggregated result
+const (
+	pipelineNodeSuccessful = "SUCCESSFUL"
+	pipelineNodeFailed     = "FAILED"
+	pipelineNodeSkipped    = "SKIPPED" // A node it depends on did not succeed
+)
+
+// pipelineNodeResult is the outcome of a single pipeline node
+type pipelineNodeResult struct {
+	ID          string `json:"id"`
+	Script      string `json:"script"`
+	Status      string `json:"status"`
+	ExecutionID string `json:"executionId,omitempty"`
+	ProcessID   int64  `json:"processId,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// validatePipeline checks a pipeline definition: node IDs are unique, scripts exist and are not
+// pipelines themselves, dependencies refer to nodes of the same pipeline and there are no cycles.
+// It defaults node IDs to their script name.
+func validatePipeline(def *ScriptDefinition, definitions []ScriptDefinition) error {
+	if len(def.Pipeline) == 0 {
+		return nil
+	}
+	if def.Command != "" || len(def.Steps) > 0 || def.TektonPipeline != "" {
+		return fmt.Errorf("'pipeline' can't be combined with 'command', 'steps' or 'tektonPipeline'")
+	}
+
+	nodes := make(map[string]*PipelineNode)
+	for i := range def.Pipeline {
+		node := &def.Pipeline[i]
+		if node.Script == "" {
+			return fmt.Errorf("node %d is missing required 'script' field", i)
+		}
+		script := findScriptVersion(definitions, node.Script, node.Version)
+		if script == nil {
+			return fmt.Errorf("node %d references unknown script '%s' (version: '%s')", i, node.Script, node.Version)
+		}
+		if len(script.Pipeline) > 0 {
+			return fmt.Errorf("node %d references script '%s', which is a pipeline itself", i, node.Script)
+		}
+		if node.ID == "" {
+			node.ID = node.Script
+		}
+		if _, duplicate := nodes[node.ID]; duplicate {
+			return fmt.Errorf("duplicate node id '%s'", node.ID)
+		}
+		nodes[node.ID] = node
+	}
+	for _, node := range def.Pipeline {
+		for _, dep := range node.DependsOn {
+			if _, exists := nodes[dep]; !exists {
+				return fmt.Errorf("node '%s' depends on unknown node '%s'", node.ID, dep)
+			}
+		}
+	}
+
+	// Depth-first search for cycles: visiting marks nodes on the current path
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var visit func(id string, path []string) error
+	visit = func(id string, path []string) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, id), " -> "))
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		for _, dep := range nodes[id].DependsOn {
+			if err := visit(dep, append(path, id)); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		return nil
+	}
+	for _, node := range def.Pipeline {
+		if err := visit(node.ID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePipelineRollout checks the "rollout" of a pipeline definition, once validatePipeline
+// defaulted its node IDs, and defaults the canary node.
+func validatePipelineRollout(def *ScriptDefinition) error {
+	rollout := def.Rollout
+	if rollout == nil {
+		return nil
+	}
+	if len(def.Pipeline) == 0 {
+		return fmt.Errorf("'rollout' requires a 'pipeline'")
+	}
+	if rollout.Strategy != pipelineStrategyCanary {
+		return fmt.Errorf("unknown strategy '%s' (supported: %s)", rollout.Strategy, pipelineStrategyCanary)
+	}
+	if rollout.CanaryNode == "" {
+		for _, node := range def.Pipeline {
+			if len(node.DependsOn) == 0 {
+				rollout.CanaryNode = node.ID
+				break
+			}
+		}
+	}
+	var canary *PipelineNode
+	for i := range def.Pipeline {
+		if def.Pipeline[i].ID == rollout.CanaryNode {
+			canary = &def.Pipeline[i]
+		}
+	}
+	if canary == nil {
+		return fmt.Errorf("canary node '%s' is not a node of the pipeline", rollout.CanaryNode)
+	}
+	if len(canary.DependsOn) > 0 {
+		return fmt.Errorf("canary node '%s' must not depend on other nodes, as it runs first", canary.ID)
+	}
+	if _, err := regexp.Compile(rollout.OutputPattern); err != nil {
+		return fmt.Errorf("invalid 'outputPattern': %v", err)
+	}
+	return nil
+}
+
+// canaryNodeOf returns the ID of the pipeline's canary node, or "" without the canary strategy.
+func canaryNodeOf(def *ScriptDefinition) string {
+	if def.Rollout == nil || def.Rollout.Strategy != pipelineStrategyCanary {
+		return ""
+	}
+	return def.Rollout.CanaryNode
+}
+
+// verifyCanary checks the outcome of a pipeline's canary node that answered 200: it must have
+// succeeded outright, and its output must match the rollout's outputPattern, if set.
+func verifyCanary(rollout *PipelineRollout, outcome executionOutcome) error {
+	if outcome.Status != "" {
+		return fmt.Errorf("canary ended %s instead of %s", outcome.Status, pipelineNodeSuccessful)
+	}
+	if rollout.OutputPattern != "" && !regexp.MustCompile(rollout.OutputPattern).MatchString(outcome.Output) {
+		return fmt.Errorf("canary output doesn't match outputPattern '%s'", rollout.OutputPattern)
+	}
+	return nil
+}
+
+// runPipeline executes every node of a pipeline definition as its own task request (with its own
+// process tracking record), starting each node once its dependencies have succeeded. Nodes whose
+// dependencies failed are skipped. With the canary strategy, every other node also waits for the
+// canary node. The pipeline succeeds only if every node succeeds.
+func runPipeline(ctx context.Context, config *Config, request TaskServiceRequest, def *ScriptDefinition, executionID, trackingID string, opts executionOptions) executionOutcome {
+	xlog := executionLog(ctx)
+	xlog.Info().Msgf("Running pipeline '%s' with %d nodes", def.Name, len(def.Pipeline))
+	canary := canaryNodeOf(def)
+	if canary != "" {
+		xlog.Info().Msgf("Pipeline '%s' rolls out to its other nodes once canary node '%s' succeeded", def.Name, canary)
+	}
+
+	results := make([]pipelineNodeResult, len(def.Pipeline))
+	done := make(map[string]chan struct{})
+	index := make(map[string]int)
+	for i, node := range def.Pipeline {
+		done[node.ID] = make(chan struct{})
+		index[node.ID] = i
+	}
+
+	var wg sync.WaitGroup
+	for i, node := range def.Pipeline {
+		wg.Add(1)
+		go func(i int, node PipelineNode) {
+			defer wg.Done()
+			defer close(done[node.ID])
+			results[i] = pipelineNodeResult{ID: node.ID, Script: node.Script}
+
+			dependencies := node.DependsOn
+			if canary != "" && node.ID != canary {
+				dependencies = append([]string{canary}, dependencies...)
+			}
+			for _, dep := range dependencies {
+				<-done[dep]
+				if results[index[dep]].Status != pipelineNodeSuccessful {
+					reason := fmt.Sprintf("dependency '%s' did not succeed", dep)
+					if dep == canary {
+						reason = fmt.Sprintf("canary '%s' did not succeed, rollout aborted", dep)
+					}
+					xlog.Info().Msgf("Skipping pipeline '%s' node '%s': %s", def.Name, node.ID, reason)
+					results[i].Status = pipelineNodeSkipped
+					results[i].Error = reason
+					return
+				}
+			}
+
+			if ctx.Err() != nil {
+				results[i].Status = pipelineNodeSkipped
+				results[i].Error = executionStopped(ctx).Error()
+				return
+			}
+
+			// Each node gets the pipeline's taskData with the node's script name
+			taskData := make(map[string]interface{}, len(request.TaskData))
+			for k, v := range request.TaskData {
+				taskData[k] = v
+			}
+			taskData["name"] = node.Script
+			xlog.Info().Msgf("Starting pipeline '%s' node '%s' (script: %s)", def.Name, node.ID, node.Script)
+			outcome := runTask(ctx, config, TaskServiceRequest{
+				TaskName:    fmt.Sprintf("%s/%s", request.TaskName, node.ID),
+				LastRunTime: request.LastRunTime,
+				TrackingID:  fmt.Sprintf("%s-%s", trackingID, node.ID),
+				TaskData:    taskData,
+				Version:     node.Version,
+				Stage:       request.Stage,
+			}, executionOptions{Caller: opts.Caller, ClientIP: opts.ClientIP, Scheduled: opts.Scheduled})
+
+			results[i].ExecutionID = outcome.ExecutionID
+			results[i].ProcessID = outcome.ProcessID
+			if outcome.StatusCode == http.StatusOK && node.ID == canary {
+				if err := verifyCanary(def.Rollout, outcome); err != nil {
+					xlog.Warn().Msgf("Pipeline '%s' canary node '%s' failed verification: %v", def.Name, node.ID, err)
+					results[i].Status = pipelineNodeFailed
+					results[i].Error = err.Error()
+					return
+				}
+			}
+			if outcome.StatusCode == http.StatusOK {
+				results[i].Status = pipelineNodeSuccessful
+				return
+			}
+			results[i].Status = pipelineNodeFailed
+			if errMsg, ok := outcome.Body["error"]; ok {
+				results[i].Error = fmt.Sprintf("%v", errMsg)
+			}
+		}(i, node)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, result := range results {
+		if result.Status != pipelineNodeSuccessful {
+			failed = append(failed, fmt.Sprintf("%s (%s)", result.ID, result.Status))
+		}
+	}
+	if len(failed) > 0 {
+		errMsgStr := fmt.Sprintf("Pipeline nodes did not succeed: %s", strings.Join(failed, ", "))
+		extensions := gin.H{
+			"taskName":  def.Name,
+			"script_id": def.ID,
+			"nodes":     results,
+		}
+		if canary != "" && results[index[canary]].Status != pipelineNodeSuccessful {
+			errMsgStr = fmt.Sprintf("Canary node '%s' did not succeed, rollout aborted. %s", canary, errMsgStr)
+			extensions["rolloutAborted"] = true
+		}
+		xlog.Error().Msgf("Pipeline '%s' FAILED: %s", def.Name, errMsgStr)
+		executionStore.RecordFinish(executionID, "", executionStatusFailed, "", errMsgStr)
+		return executionOutcome{
+			StatusCode: http.StatusInternalServerError,
+			Body:       problemWith(http.StatusInternalServerError, codeExecFailed, errMsgStr, extensions),
+		}
+	}
+
+	xlog.Info().Msgf("Pipeline '%s' SUCCESSFUL (%d nodes)", def.Name, len(results))
+	executionStore.RecordFinish(executionID, "", executionStatusSuccessful, "", "")
+	return executionOutcome{StatusCode: http.StatusOK, Body: gin.H{
+		"taskName":  def.Name,
+		"script_id": def.ID,
+		"nodes":     results,
+	}}
+}
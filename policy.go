@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// CommandPolicy is the server-side allowlist of commands script definitions may run, loaded from
+// COMMAND_POLICY_PATH. It lives outside the definitions file so a compromised scripts.json can't
+// widen it. Besides commands, it covers the definition fields that choose what else runs and with
+// which privileges: those are refused unless the policy allows them too.
+type CommandPolicy struct {
+	AllowedPrefixes []string `json:"allowedPrefixes,omitempty"` // E.g. "/scripts/", "kubectl get "
+	// Matched against the first word of the command: an absolute path only matches itself, a bare
+	// name only the bare name looked up in the PATH of the target
+	AllowedBinaries []string `json:"allowedBinaries,omitempty"`
+	// Shell operators (; | & ` $ < > and newlines) let a command run arbitrary other programs after an
+	// allowed prefix, so they are rejected unless explicitly allowed. ${PARAM} placeholders are not
+	// operators: their values are quoted when they are expanded.
+	AllowShellOperators bool `json:"allowShellOperators,omitempty"`
+	// Images of dedicated pods and debug containers: exact references, or prefixes ending in "/"
+	AllowedImages []string `json:"allowedImages,omitempty"`
+	// Node-targeted scripts run in the host namespaces of a node through a privileged pod
+	AllowNodeTargets bool `json:"allowNodeTargets,omitempty"`
+	// Secrets SSH targets may connect with; a target overriding the Secret's host must use one of allowedSshHosts
+	AllowedSSHSecrets []string `json:"allowedSshSecrets,omitempty"`
+	AllowedSSHHosts   []string `json:"allowedSshHosts,omitempty"`
+	// Users (names or UIDs) scripts may run as with runAsUser
+	AllowedUsers []string `json:"allowedUsers,omitempty"`
+}
+
+// shellOperators are the sequences that chain, substitute, expand or redirect commands in /bin/sh
+var shellOperators = []string{";", "|", "&", "`", "$", "<", ">", "\n"}
+
+// unsafeEnvNames are environment variables that change which program a command runs or what it
+// loads, so definitions under a command policy may not set them, nor pass parameters in them
+var unsafeEnvNames = []string{"PATH", "IFS", "ENV", "BASH_ENV", "SHELLOPTS", "BASHOPTS", "PS4", "PROMPT_COMMAND",
+	"GLOBIGNORE", "PYTHONPATH", "PYTHONSTARTUP", "PERL5LIB", "PERL5OPT", "RUBYLIB", "RUBYOPT", "NODE_OPTIONS",
+	"JAVA_TOOL_OPTIONS"}
+
+// unsafeEnvPrefixes are prefixes of unsafe environment variables, like the dynamic linker's
+var unsafeEnvPrefixes = []string{"LD_", "DYLD_", "BASH_FUNC_"}
+
+// loadCommandPolicy reads the command policy file; it returns nil when no policy is configured.
+func loadCommandPolicy(policyPath string) (*CommandPolicy, error) {
+	if policyPath == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command policy '%s': %v", policyPath, err)
+	}
+	var policy CommandPolicy
+	if err := json.Unmarshal(content, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse command policy '%s': %v", policyPath, err)
+	}
+	if len(policy.AllowedPrefixes) == 0 && len(policy.AllowedBinaries) == 0 {
+		return nil, fmt.Errorf("command policy '%s' allows no commands", policyPath)
+	}
+	return &policy, nil
+}
+
+// checkCommand returns an error if the command is not allowed by the policy. A nil policy allows everything.
+func (p *CommandPolicy) checkCommand(command string) error {
+	if p == nil {
+		return nil
+	}
+	command = strings.TrimSpace(command)
+	if !p.AllowShellOperators {
+		unquoted := placeholderPattern.ReplaceAllString(command, "")
+		for _, operator := range shellOperators {
+			if strings.Contains(unquoted, operator) {
+				return fmt.Errorf("command '%s' contains shell operator %q, which the command policy does not allow", command, operator)
+			}
+		}
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return fmt.Errorf("empty command is not allowed by the command policy")
+	}
+	// "/scripts/../tmp/x" or "./kubectl" would otherwise pass as an allowed prefix or binary
+	if program := fields[0]; path.Clean(program) != program {
+		return fmt.Errorf("command '%s' names its program by a relative or unclean path, which the command policy does not allow", command)
+	}
+	for _, prefix := range p.AllowedPrefixes {
+		if strings.HasPrefix(command, prefix) {
+			return nil
+		}
+	}
+	for _, binary := range p.AllowedBinaries {
+		if fields[0] == binary {
+			return nil
+		}
+	}
+	return fmt.Errorf("command '%s' is not allowed by the command policy", command)
+}
+
+// checkImage returns an error if the policy doesn't allow the image.
+func (p *CommandPolicy) checkImage(image string) error {
+	for _, allowed := range p.AllowedImages {
+		if image == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(image, allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("image '%s' is not allowed by the command policy", image)
+}
+
+// checkEnvName returns an error if the environment variable is one the policy never lets definitions set.
+func checkEnvName(name string) error {
+	upper := strings.ToUpper(name)
+	for _, unsafe := range unsafeEnvNames {
+		if upper == unsafe {
+			return fmt.Errorf("environment variable '%s' is not allowed by the command policy", name)
+		}
+	}
+	for _, prefix := range unsafeEnvPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return fmt.Errorf("environment variable '%s' is not allowed by the command policy", name)
+		}
+	}
+	return nil
+}
+
+// checkDefinition checks a script definition against the policy: its command and every step, and
+// the fields choosing where and how they run. A nil policy allows everything.
+func (p *CommandPolicy) checkDefinition(def *ScriptDefinition) error {
+	if p == nil {
+		return nil
+	}
+	if def.Command != "" {
+		if err := p.checkCommand(def.Command); err != nil {
+			return err
+		}
+	}
+	for i, step := range def.Steps {
+		if err := p.checkCommand(step.Command); err != nil {
+			return fmt.Errorf("step %d: %v", i+1, err)
+		}
+	}
+	for _, image := range []string{def.Image, def.DebugImage} {
+		if image != "" {
+			if err := p.checkImage(image); err != nil {
+				return err
+			}
+		}
+	}
+	if isNodeTargeted(def) && !p.AllowNodeTargets {
+		return fmt.Errorf("node-targeted scripts are not allowed by the command policy")
+	}
+	if def.SSH != nil {
+		if !slices.Contains(p.AllowedSSHSecrets, def.SSH.Secret) {
+			return fmt.Errorf("SSH Secret '%s' is not allowed by the command policy", def.SSH.Secret)
+		}
+		if def.SSH.Host != "" && !slices.Contains(p.AllowedSSHHosts, def.SSH.Host) {
+			return fmt.Errorf("SSH host '%s' is not allowed by the command policy", def.SSH.Host)
+		}
+	}
+	if def.RunAsUser != "" && !slices.Contains(p.AllowedUsers, def.RunAsUser) {
+		return fmt.Errorf("user '%s' is not allowed by the command policy", def.RunAsUser)
+	}
+	for name := range def.Env {
+		if err := checkEnvName(name); err != nil {
+			return fmt.Errorf("env: %v", err)
+		}
+	}
+	for _, param := range def.Parameters {
+		if err := checkEnvName(param.envVarName()); err != nil {
+			return fmt.Errorf("parameter '%s': %v", param.Name, err)
+		}
+	}
+	return nil
+}
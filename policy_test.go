@@ -0,0 +1,111 @@
+package main
+
+import "testing"
+
+func TestCheckCommand(t *testing.T) {
+	policy := &CommandPolicy{
+		AllowedPrefixes: []string{"/scripts/", "kubectl get "},
+		AllowedBinaries: []string{"kubectl", "/usr/bin/pg_dump"},
+	}
+	tests := []struct {
+		name    string
+		command string
+		allowed bool
+	}{
+		{"allowed prefix", "/scripts/restore.sh --full", true},
+		{"allowed bare binary", "kubectl rollout restart deploy/api", true},
+		{"allowed absolute binary", "/usr/bin/pg_dump -d app", true},
+		{"placeholder", "/scripts/restore.sh ${BACKUP_ID}", true},
+		{"binary under another path", "/tmp/evil/kubectl get pods", false},
+		{"binary in working directory", "./kubectl get pods", false},
+		{"absolute binary by bare name", "pg_dump -d app", false},
+		{"absolute binary under another path", "/tmp/usr/bin/pg_dump -d app", false},
+		{"prefix escaped with ..", "/scripts/../tmp/evil.sh", false},
+		{"unclean prefix", "/scripts//restore.sh", false},
+		{"not allowed", "bash -i", false},
+		{"empty", "  ", false},
+		{"semicolon", "/scripts/restore.sh; id", false},
+		{"pipe", "kubectl get pods | sh", false},
+		{"background", "/scripts/restore.sh & id", false},
+		{"command substitution", "/scripts/restore.sh $(id)", false},
+		{"backticks", "/scripts/restore.sh `id`", false},
+		{"variable", "/scripts/restore.sh $HOME", false},
+		{"braced variable with operator", "/scripts/restore.sh ${HOME:-$(id)}", false},
+		{"redirect", "/scripts/restore.sh > /etc/passwd", false},
+		{"newline", "/scripts/restore.sh\nid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.checkCommand(tt.command)
+			if (err == nil) != tt.allowed {
+				t.Errorf("checkCommand(%q) = %v, want allowed=%v", tt.command, err, tt.allowed)
+			}
+		})
+	}
+
+	withOperators := &CommandPolicy{AllowedPrefixes: []string{"/scripts/"}, AllowShellOperators: true}
+	if err := withOperators.checkCommand("/scripts/restore.sh | tee /tmp/log"); err != nil {
+		t.Errorf("checkCommand with allowShellOperators = %v, want allowed", err)
+	}
+	if err := withOperators.checkCommand("./restore.sh"); err == nil {
+		t.Errorf("checkCommand(./restore.sh) with allowShellOperators allowed, want rejected")
+	}
+	var none *CommandPolicy
+	if err := none.checkCommand("/tmp/evil/kubectl; id"); err != nil {
+		t.Errorf("checkCommand without policy = %v, want allowed", err)
+	}
+}
+
+func TestCheckDefinition(t *testing.T) {
+	policy := &CommandPolicy{
+		AllowedPrefixes:   []string{"/scripts/"},
+		AllowedImages:     []string{"registry.internal/ops/", "busybox:1.36"},
+		AllowedSSHSecrets: []string{"legacy-vm"},
+		AllowedSSHHosts:   []string{"vm-2.internal"},
+		AllowedUsers:      []string{"postgres", "999"},
+	}
+	tests := []struct {
+		name    string
+		def     ScriptDefinition
+		allowed bool
+	}{
+		{"allowed command", ScriptDefinition{Command: "/scripts/restore.sh"}, true},
+		{"step not allowed", ScriptDefinition{Steps: []ScriptStep{{Command: "/scripts/a.sh"}, {Command: "sh -c id"}}}, false},
+		{"image under allowed prefix", ScriptDefinition{Command: "/scripts/a.sh", Image: "registry.internal/ops/tools:1.2"}, true},
+		{"exact image", ScriptDefinition{Command: "/scripts/a.sh", Image: "busybox:1.36"}, true},
+		{"other image", ScriptDefinition{Command: "/scripts/a.sh", Image: "evil/tools:latest"}, false},
+		{"image sharing the prefix without slash", ScriptDefinition{Command: "/scripts/a.sh", Image: "registry.internal/ops-evil/tools"}, false},
+		{"other tag of exact image", ScriptDefinition{Command: "/scripts/a.sh", Image: "busybox:latest"}, false},
+		{"other debug image", ScriptDefinition{Command: "/scripts/a.sh", DebugImage: "nicolaka/netshoot"}, false},
+		{"node name", ScriptDefinition{Command: "/scripts/a.sh", NodeName: "${NODE}"}, false},
+		{"node selector", ScriptDefinition{Command: "/scripts/a.sh", NodeSelector: map[string]string{"role": "db"}}, false},
+		{"allowed SSH Secret", ScriptDefinition{Command: "/scripts/a.sh", SSH: &SSHTarget{Secret: "legacy-vm"}}, true},
+		{"allowed SSH host", ScriptDefinition{Command: "/scripts/a.sh", SSH: &SSHTarget{Secret: "legacy-vm", Host: "vm-2.internal"}}, true},
+		{"other SSH Secret", ScriptDefinition{Command: "/scripts/a.sh", SSH: &SSHTarget{Secret: "db-admin"}}, false},
+		{"other SSH host", ScriptDefinition{Command: "/scripts/a.sh", SSH: &SSHTarget{Secret: "legacy-vm", Host: "attacker.example"}}, false},
+		{"allowed user", ScriptDefinition{Command: "/scripts/a.sh", RunAsUser: "postgres"}, true},
+		{"allowed UID", ScriptDefinition{Command: "/scripts/a.sh", Image: "busybox:1.36", RunAsUser: "999"}, true},
+		{"other user", ScriptDefinition{Command: "/scripts/a.sh", RunAsUser: "root"}, false},
+		{"static env", ScriptDefinition{Command: "/scripts/a.sh", Env: map[string]string{"DB_HOST": "db"}}, true},
+		{"static BASH_ENV", ScriptDefinition{Command: "/scripts/a.sh", Env: map[string]string{"BASH_ENV": "/tmp/evil"}}, false},
+		{"static LD_ variable", ScriptDefinition{Command: "/scripts/a.sh", Env: map[string]string{"LD_LIBRARY_PATH": "/tmp"}}, false},
+		{"parameter", ScriptDefinition{Command: "/scripts/a.sh", Parameters: []InputParameterDef{{Name: "backup id"}}}, true},
+		{"parameter in PATH", ScriptDefinition{Command: "/scripts/a.sh", Parameters: []InputParameterDef{{Name: "path"}}}, false},
+		{"parameter in LD_PRELOAD", ScriptDefinition{Command: "/scripts/a.sh", Parameters: []InputParameterDef{{Name: "LD_PRELOAD"}}}, false},
+		{"parameter with envName", ScriptDefinition{Command: "/scripts/a.sh", Parameters: []InputParameterDef{{Name: "path", EnvName: "BACKUP_PATH"}}}, true},
+		{"parameter with envName PATH", ScriptDefinition{Command: "/scripts/a.sh", Parameters: []InputParameterDef{{Name: "dir", EnvName: "PATH"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.checkDefinition(&tt.def)
+			if (err == nil) != tt.allowed {
+				t.Errorf("checkDefinition(%+v) = %v, want allowed=%v", tt.def, err, tt.allowed)
+			}
+		})
+	}
+
+	nodes := &CommandPolicy{AllowedPrefixes: []string{"/scripts/"}, AllowNodeTargets: true}
+	if err := nodes.checkDefinition(&ScriptDefinition{Command: "/scripts/a.sh", NodeName: "worker-1"}); err != nil {
+		t.Errorf("checkDefinition of node-targeted script with allowNodeTargets = %v, want allowed", err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Outcomes of the pre-flight check of one script
+const (
+	preflightOK          = "ok"          // The binary exists in the target pod
+	preflightMissing     = "missing"     // `command -v` didn't find the binary
+	preflightUnreachable = "unreachable" // No pod matched, or the exec failed
+	preflightSkipped     = "skipped"     // The binary can't be checked: a placeholder or unusual name
+)
+
+// preflightBinaryPattern matches the binary names the check execs; others, e.g. placeholders, are skipped
+var preflightBinaryPattern = regexp.MustCompile(`^[A-Za-z0-9_./+-]+$`)
+
+// preflightResult is the pre-flight check of one script
+type preflightResult struct {
+	Script string `json:"script"`
+	Binary string `json:"binary"`
+	Pod    string `json:"pod,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// preflightReport is the outcome of the last pre-flight check, returned in /v1/status
+type preflightReport struct {
+	CheckedAt time.Time         `json:"checkedAt"`
+	Failed    int               `json:"failed"` // Commands whose binary is missing or couldn't be checked
+	Results   []preflightResult `json:"results"`
+}
+
+// lastPreflight is the report of the last pre-flight check; nil until one ran
+var lastPreflight atomic.Pointer[preflightReport]
+
+// preflightMu keeps on-demand and periodic checks from running at the same time
+var preflightMu sync.Mutex
+
+// startPreflightChecks runs the pre-flight check at startup and, with PREFLIGHT_INTERVAL_SECONDS,
+// periodically afterwards, so a definition whose binary is missing from the target image shows up
+// in /v1/status before its next scheduled run fails.
+func startPreflightChecks(config *Config) {
+	if !config.PreflightCheck {
+		return
+	}
+	go func() {
+		for {
+			runPreflightChecks(context.Background(), currentConfig())
+			if config.PreflightInterval <= 0 {
+				return
+			}
+			time.Sleep(config.PreflightInterval)
+		}
+	}()
+	logger.Info().Msgf("[Preflight] Command checks enabled (interval: %s).", config.PreflightInterval)
+}
+
+// preflightBinary returns the binary a command starts with, skipping leading VAR=value assignments.
+func preflightBinary(command string) string {
+	for _, field := range strings.Fields(command) {
+		if !strings.Contains(field, "=") {
+			return field
+		}
+	}
+	return ""
+}
+
+// runPreflightChecks checks, for every enabled script exec'd into a workload pod, that the first
+// token of its command (of each step, for multi-step scripts) resolves with `command -v` in a pod
+// matching its selectors. Other backends bring their own image or host and aren't checked. It
+// returns nil, keeping the previous report, when the definitions can't be loaded.
+func runPreflightChecks(ctx context.Context, config *Config) *preflightReport {
+	preflightMu.Lock()
+	defer preflightMu.Unlock()
+	report := &preflightReport{Results: []preflightResult{}}
+	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	if err != nil {
+		logger.Warn().Msgf("[Preflight] Skipped: failed to load script definitions: %v", err)
+		return nil
+	}
+
+	// Each binary is checked once per pod
+	checked := make(map[string]preflightResult)
+	for i := range definitions {
+		def := &definitions[i]
+		if def.Disabled || len(def.Pipeline) > 0 || backendOf(config, def) != backendPodExec {
+			continue
+		}
+		commands := []string{def.Command}
+		if len(def.Steps) > 0 {
+			commands = nil
+			for _, step := range def.Steps {
+				commands = append(commands, step.Command)
+			}
+		}
+		pod, _, podErr := findPod(config.Namespace, podSelectorsFor(def, config), true)
+		for _, command := range commands {
+			result := preflightResult{Script: def.Name, Binary: preflightBinary(command), Pod: pod}
+			switch {
+			case !preflightBinaryPattern.MatchString(result.Binary):
+				result.Status = preflightSkipped
+			case podErr != nil:
+				result.Status, result.Error = preflightUnreachable, podErr.Error()
+			default:
+				key := pod + "\x00" + result.Binary
+				previous, ok := checked[key]
+				if !ok {
+					previous = checkBinaryInPod(config, pod, result.Binary)
+					checked[key] = previous
+				}
+				result.Status, result.Error = previous.Status, previous.Error
+			}
+			if result.Status == preflightMissing || result.Status == preflightUnreachable {
+				report.Failed++
+				logger.Warn().Msgf("[Preflight] Script '%s': binary '%s' %s: %s", def.Name, result.Binary, result.Status, result.Error)
+			}
+			report.Results = append(report.Results, result)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	report.CheckedAt = time.Now().UTC()
+	lastPreflight.Store(report)
+	logger.Info().Msgf("[Preflight] Checked %d command(s), %d failed.", len(report.Results), report.Failed)
+	return report
+}
+
+// checkBinaryInPod runs `command -v` for a binary in a pod.
+func checkBinaryInPod(config *Config, pod, binary string) preflightResult {
+	output, err := execInPod(config.Namespace, pod, "command -v "+binary)
+	switch {
+	case err == nil:
+		return preflightResult{Status: preflightOK}
+	case isTransientExecFailure(output, err) || !strings.Contains(strings.ToLower(output), "command terminated with exit code"):
+		return preflightResult{Status: preflightUnreachable, Error: fmt.Sprintf("%v: %s", err, strings.TrimSpace(output))}
+	default:
+		return preflightResult{Status: preflightMissing, Error: fmt.Sprintf("'%s' not found in pod '%s'", binary, pod)}
+	}
+}
+
+// postPreflight handles POST /admin/preflight, running the pre-flight check now and returning its report.
+func postPreflight(c *gin.Context) {
+	logger.Info().Msgf("[Preflight] Check requested by %s.", callerFromContext(c).Name)
+	report := runPreflightChecks(c.Request.Context(), configFromContext(c))
+	if report == nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeDefinitionsInvalid, "Script definitions can't be loaded"))
+		return
+	}
+	writeJSON(c, http.StatusOK, report)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// problemContentType is the media type of error responses (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// Error codes of problem responses. They are stable: clients branch on them instead of matching
+// the human-readable detail, which may change.
+const (
+	codeInvalidRequest     = "INVALID_REQUEST"     // Malformed body or query parameters
+	codeScriptNotFound     = "SCRIPT_NOT_FOUND"    // No script (version) with the requested name
+	codeScriptDisabled     = "SCRIPT_DISABLED"     // The script exists but is disabled
+	codeParamMissing       = "PARAM_MISSING"       // A required script parameter is missing
+	codeParamInvalid       = "PARAM_INVALID"       // A parameter value can't be used
+	codePodNotFound        = "POD_NOT_FOUND"       // No (ready) pod matches the script's selectors
+	codeExecFailed         = "EXEC_FAILED"         // The script ran and failed
+	codeTrackingFailed     = "TRACKING_FAILED"     // The process tracking record couldn't be created
+	codeDefinitionsInvalid = "DEFINITIONS_INVALID" // The script definitions can't be loaded
+	codeUnauthenticated    = "UNAUTHENTICATED"     // Missing or invalid credentials
+	codeForbidden          = "FORBIDDEN"           // The caller may not use the endpoint or script
+	codePolicyDenied       = "POLICY_DENIED"       // Rejected by the command policy or OPA
+	codeQuotaExceeded      = "QUOTA_EXCEEDED"      // A script or caller quota is used up
+	codeRateLimited        = "RATE_LIMITED"        // Too many requests from the client
+	codePayloadTooLarge    = "PAYLOAD_TOO_LARGE"   // The request body exceeds MAX_REQUEST_BODY_BYTES
+	codeRequestTimeout     = "REQUEST_TIMEOUT"     // The request wasn't answered within its timeout
+	codeMaintenance        = "MAINTENANCE"         // Execution is paused (maintenance mode)
+	codeShuttingDown       = "SHUTTING_DOWN"       // The replica is draining
+	codeScriptLocked       = "SCRIPT_LOCKED"       // An exclusive script is already running
+	codePermissionsMissing = "PERMISSIONS_MISSING" // The executor lost the Kubernetes permissions a script needs
+	codeNotFound           = "NOT_FOUND"           // The requested resource doesn't exist
+	codeNotEnabled         = "NOT_ENABLED"         // The feature behind the endpoint is not configured
+	codeInternal           = "INTERNAL_ERROR"      // Anything else going wrong on the executor's side
+)
+
+// problem returns an RFC 7807 problem body with the error code as the "code" extension and as the
+// last segment of its type URI. "error" repeats the detail for clients of the former
+// {"error": "..."} bodies.
+func problem(status int, code, detail string) gin.H {
+	return gin.H{
+		"type":   "urn:k8s-script-executor:problem:" + strings.ToLower(strings.ReplaceAll(code, "_", "-")),
+		"title":  http.StatusText(status),
+		"status": status,
+		"code":   code,
+		"detail": detail,
+		"error":  detail,
+	}
+}
+
+// problemWith returns a problem body with extra members, e.g. the output of a failed execution.
+func problemWith(status int, code, detail string, members gin.H) gin.H {
+	body := problem(status, code, detail)
+	for key, value := range members {
+		body[key] = value
+	}
+	return body
+}
+
+// isProblem reports whether a response body was built by problem.
+func isProblem(v interface{}) bool {
+	body, ok := v.(gin.H)
+	if !ok {
+		return false
+	}
+	_, hasCode := body["code"]
+	_, hasType := body["type"]
+	return hasCode && hasType
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExecutionQuota limits how many executions may start within rolling windows.
+type ExecutionQuota struct {
+	PerHour int `json:"perHour,omitempty"`
+	PerDay  int `json:"perDay,omitempty"`
+}
+
+// quotaWindow is one rolling window of a quota
+type quotaWindow struct {
+	Name   string
+	Length time.Duration
+	Limit  int
+}
+
+// windows returns the configured windows of the quota.
+func (q *ExecutionQuota) windows() []quotaWindow {
+	if q == nil {
+		return nil
+	}
+	var windows []quotaWindow
+	if q.PerHour > 0 {
+		windows = append(windows, quotaWindow{Name: "hour", Length: time.Hour, Limit: q.PerHour})
+	}
+	if q.PerDay > 0 {
+		windows = append(windows, quotaWindow{Name: "day", Length: 24 * time.Hour, Limit: q.PerDay})
+	}
+	return windows
+}
+
+// quotaStatus reports the usage of one quota window, as returned by GET /v1/quotas
+type quotaStatus struct {
+	Scope  string `json:"scope"` // "script" or "caller"
+	Name   string `json:"name"`  // Script or caller name
+	Window string `json:"window"`
+	Limit  int    `json:"limit"`
+	Used   int    `json:"used"`
+}
+
+// quotaUsage counts executions for quota enforcement. With a history store the counts come from the
+// executions table, so quotas hold across restarts and replicas; otherwise starts are tracked in
+// memory for the last day.
+type quotaUsage struct {
+	mu     sync.Mutex // Held from quota check to recording the start, so concurrent requests can't both take the last slot
+	starts []quotaStart
+}
+
+// quotaStart is an execution start tracked in memory
+type quotaStart struct {
+	script  string
+	caller  string
+	started time.Time
+}
+
+var quotas = &quotaUsage{}
+
+// loadCallerQuotas reads the per-caller quotas file (CALLER_QUOTAS_PATH), a JSON object of caller
+// name to quota. The "*" entry applies to callers without their own entry.
+func loadCallerQuotas(quotasPath string) (map[string]ExecutionQuota, error) {
+	if quotasPath == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(quotasPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read caller quotas '%s': %v", quotasPath, err)
+	}
+	var callerQuotas map[string]ExecutionQuota
+	if err := json.Unmarshal(content, &callerQuotas); err != nil {
+		return nil, fmt.Errorf("failed to parse caller quotas '%s': %v", quotasPath, err)
+	}
+	return callerQuotas, nil
+}
+
+// callerQuota returns the quota of a caller, falling back to the "*" entry.
+func callerQuota(callerQuotas map[string]ExecutionQuota, caller string) *ExecutionQuota {
+	if quota, ok := callerQuotas[caller]; ok {
+		return &quota
+	}
+	if quota, ok := callerQuotas["*"]; ok {
+		return &quota
+	}
+	return nil
+}
+
+// count returns the number of executions since the given time, optionally filtered by script and/or caller.
+func (q *quotaUsage) count(script, caller string, since time.Time) (int, error) {
+	if executionStore != nil {
+		return executionStore.CountExecutions(script, caller, since)
+	}
+	count := 0
+	for _, start := range q.starts {
+		if start.started.After(since) && (script == "" || start.script == script) && (caller == "" || start.caller == caller) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// status returns the usage of every window of the script's and caller's quotas.
+func (q *quotaUsage) status(def *ScriptDefinition, caller string, callerQuotas map[string]ExecutionQuota) ([]quotaStatus, error) {
+	now := time.Now()
+	var statuses []quotaStatus
+	if def != nil {
+		for _, window := range def.Quota.windows() {
+			used, err := q.count(def.Name, "", now.Add(-window.Length))
+			if err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, quotaStatus{Scope: "script", Name: def.Name, Window: window.Name, Limit: window.Limit, Used: used})
+		}
+	}
+	if caller != "" {
+		for _, window := range callerQuota(callerQuotas, caller).windows() {
+			used, err := q.count("", caller, now.Add(-window.Length))
+			if err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, quotaStatus{Scope: "caller", Name: caller, Window: window.Name, Limit: window.Limit, Used: used})
+		}
+	}
+	return statuses, nil
+}
+
+// acquire checks the script's and caller's quotas and, if none is exhausted, records the start via
+// recordStart while still holding the lock. It returns an error naming the exhausted quota otherwise.
+// Scheduled runs are part of the script definition and only count against the script's quota.
+func (q *quotaUsage) acquire(config *Config, def *ScriptDefinition, caller Caller, scheduled bool, recordStart func()) error {
+	callerQuotas, err := loadCallerQuotas(config.CallerQuotasPath)
+	if err != nil {
+		logger.Error().Msgf("%v", err)
+		return err
+	}
+	callerName := caller.Name
+	if scheduled {
+		callerName = ""
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	statuses, err := q.status(def, callerName, callerQuotas)
+	if err != nil {
+		return fmt.Errorf("failed to check quotas: %v", err)
+	}
+	for _, status := range statuses {
+		if status.Used >= status.Limit {
+			return fmt.Errorf("%s '%s' has used its quota of %d executions per %s", status.Scope, status.Name, status.Limit, status.Window)
+		}
+	}
+
+	recordStart()
+	if executionStore == nil && (len(statuses) > 0 || callerQuotas != nil) {
+		now := time.Now()
+		kept := q.starts[:0]
+		for _, start := range q.starts {
+			if now.Sub(start.started) < 24*time.Hour {
+				kept = append(kept, start)
+			}
+		}
+		q.starts = append(kept, quotaStart{script: def.Name, caller: caller.Name, started: now})
+	}
+	return nil
+}
+
+// getQuotas handles GET /v1/quotas[?script=name]: the usage of the calling identity's quota and of
+// the quotas of every script visible to it (or only the given script).
+func getQuotas(c *gin.Context) {
+	config := configFromContext(c)
+	caller := callerFromContext(c)
+	callerQuotas, err := loadCallerQuotas(config.CallerQuotasPath)
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeDefinitionsInvalid, fmt.Sprintf("Failed to load script definitions: %v", err)))
+		return
+	}
+
+	statuses := []quotaStatus{}
+	callerStatuses, err := quotas.status(nil, caller.Name, callerQuotas)
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	statuses = append(statuses, callerStatuses...)
+
+	scriptFilter := c.Query("script")
+	for _, def := range latestScriptVersions(definitions) {
+		if def.Quota == nil || (scriptFilter != "" && def.Name != scriptFilter) || !callerMaySeeScript(caller, &def) {
+			continue
+		}
+		scriptStatuses, err := quotas.status(&def, "", nil)
+		if err != nil {
+			writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+			return
+		}
+		statuses = append(statuses, scriptStatuses...)
+	}
+	writeJSON(c, http.StatusOK, gin.H{"caller": caller.Name, "quotas": statuses})
+}
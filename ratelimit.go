@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTTL is how long an unused client's bucket is kept before it is dropped
+const rateLimiterIdleTTL = 10 * time.Minute
+
+// clientLimiter is the token bucket of one client
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// clientRateLimiter keeps a token bucket per client (authenticated caller, or remote IP).
+type clientRateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastPrune time.Time
+}
+
+var executeRateLimiter = &clientRateLimiter{clients: make(map[string]*clientLimiter)}
+
+// reserve takes a token from the client's bucket. It returns 0 when the request may proceed, or
+// how long the client has to wait for its next token.
+func (l *clientRateLimiter) reserve(client string, limit rate.Limit, burst int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastPrune) > rateLimiterIdleTTL {
+		for key, entry := range l.clients {
+			if now.Sub(entry.lastSeen) > rateLimiterIdleTTL {
+				delete(l.clients, key)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	entry, exists := l.clients[client]
+	if !exists || entry.limiter.Limit() != limit || entry.limiter.Burst() != burst {
+		entry = &clientLimiter{limiter: rate.NewLimiter(limit, burst)}
+		l.clients[client] = entry
+	}
+	entry.lastSeen = now
+
+	reservation := entry.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// Don't consume the token: rejected requests shouldn't push the client's next slot further out
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// rateLimitKey identifies the client of a request: the authenticated caller (API key name,
+// ServiceAccount, client certificate, trusted header), or the remote IP for anonymous requests.
+func rateLimitKey(c *gin.Context) string {
+	if caller := callerFromContext(c); caller.Name != anonymousCaller.Name {
+		return "caller:" + caller.Name
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimitExecute is middleware for the execute endpoints applying a per-client token bucket of
+// RATE_LIMIT_EXECUTE_PER_MINUTE requests with bursts of RATE_LIMIT_EXECUTE_BURST. Requests over the
+// limit get 429 with Retry-After, so a misbehaving retry loop can't flood script executions. With
+// REDIS_URL the bucket is kept in Redis, so the limit holds across replicas.
+func rateLimitExecute(c *gin.Context) {
+	config := configFromContext(c)
+	if config.RateLimitExecutePerMinute <= 0 {
+		c.Next()
+		return
+	}
+	burst := config.RateLimitExecuteBurst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	client := rateLimitKey(c)
+	var delay time.Duration
+	var err error
+	if sharedState != nil {
+		// Shared by all replicas; if Redis fails, this replica's own bucket still limits the client
+		if delay, err = reserveShared(client, config.RateLimitExecutePerMinute, burst); err != nil {
+			logger.Warn().Msgf("Shared rate limiter unavailable, using this replica's: %v", err)
+		}
+	}
+	if sharedState == nil || err != nil {
+		delay = executeRateLimiter.reserve(client, rate.Limit(float64(config.RateLimitExecutePerMinute)/60), burst)
+	}
+	if delay > 0 {
+		retryAfter := int(math.Ceil(delay.Seconds()))
+		logger.Warn().Msgf("Rate limit exceeded for %s on %s (limit: %d/min, burst: %d); retry after %ds.", client, c.FullPath(), config.RateLimitExecutePerMinute, burst, retryAfter)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		writeJSON(c, http.StatusTooManyRequests, problem(http.StatusTooManyRequests, codeRateLimited, fmt.Sprintf("Rate limit exceeded, retry after %d seconds", retryAfter)))
+		c.Abort()
+		return
+	}
+	c.Next()
+}
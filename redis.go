@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sharedState is the Redis client holding the state replicas share (REDIS_URL). When nil, every
+// replica keeps that state in memory, which is only correct with a single replica.
+var sharedState *redis.Client
+
+// sharedStateTimeout bounds every Redis call, so a slow Redis can't stall executions
+const sharedStateTimeout = 2 * time.Second
+
+// newSharedState connects to REDIS_URL (redis://[:password@]host:port/db, or rediss:// for TLS).
+func newSharedState(config *Config) (*redis.Client, error) {
+	options, err := redis.ParseURL(config.RedisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %v", err)
+	}
+	options.DialTimeout = sharedStateTimeout
+	options.ReadTimeout = sharedStateTimeout
+	options.WriteTimeout = sharedStateTimeout
+	client := redis.NewClient(options)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis at '%s': %v", options.Addr, err)
+	}
+	return client, nil
+}
+
+// sharedKey builds a Redis key under REDIS_KEY_PREFIX.
+func sharedKey(parts ...string) string {
+	return currentConfig().RedisKeyPrefix + strings.Join(parts, ":")
+}
+
+// tokenBucketScript takes a token from a client's bucket atomically, using the Redis clock so
+// replicas agree on time. It returns 0 when a token was taken, or the milliseconds until the next
+// one; like the in-memory limiter, a rejected request doesn't consume a token.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local delay = 0
+if tokens < 1 then
+  delay = math.ceil((1 - tokens) / rate)
+else
+  tokens = tokens - 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+return delay
+`)
+
+// reserveShared takes a token from the client's bucket in Redis, shared by all replicas.
+func reserveShared(client string, perMinute, burst int) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+	perMillisecond := float64(perMinute) / 60000
+	delay, err := tokenBucketScript.Run(ctx, sharedState, []string{sharedKey("ratelimit", client)}, perMillisecond, burst).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(delay) * time.Millisecond, nil
+}
+
+// getSharedPin returns the pod pinned to an affinity key in Redis.
+func getSharedPin(key string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+	pod, err := sharedState.Get(ctx, sharedKey("affinity", key)).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	return pod, err == nil, err
+}
+
+// setSharedPin pins an affinity key to a pod in Redis for ttl.
+func setSharedPin(key, pod string, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+	return sharedState.Set(ctx, sharedKey("affinity", key), pod, ttl).Err()
+}
+
+// checkSharedState verifies Redis answers, for the readiness probe.
+func checkSharedState(ctx context.Context) error {
+	return withTimeout(ctx, func(ctx context.Context) error {
+		return sharedState.Ping(ctx).Err()
+	})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// scriptRegistry is a loaded set of script definitions, indexed by name and ID. Registries are
+// never modified once built; a reload builds a new one and swaps it in, so a request keeps using
+// the set it started with.
+type scriptRegistry struct {
+	definitions []ScriptDefinition
+	latest      []ScriptDefinition             // Latest version of each script, see latestScriptVersions
+	byName      map[string][]*ScriptDefinition // Every version of each script
+	byID        map[string]*ScriptDefinition   // First definition with each ID
+	policy      *CommandPolicy                 // Command policy the definitions were checked against; nil without one
+}
+
+// newScriptRegistry indexes a validated definitions set.
+func newScriptRegistry(definitions []ScriptDefinition, policy *CommandPolicy) *scriptRegistry {
+	registry := &scriptRegistry{
+		definitions: definitions,
+		policy:      policy,
+		latest:      latestScriptVersions(definitions),
+		byName:      make(map[string][]*ScriptDefinition),
+		byID:        make(map[string]*ScriptDefinition),
+	}
+	for i := range definitions {
+		def := &definitions[i]
+		registry.byName[def.Name] = append(registry.byName[def.Name], def)
+		if _, ok := registry.byID[def.ID]; !ok {
+			registry.byID[def.ID] = def
+		}
+	}
+	return registry
+}
+
+// find returns the definition of the named script with the given version, or its latest version
+// when version is empty, like findScriptVersion. It returns nil if there is no such script or version.
+func (r *scriptRegistry) find(name, version string) *ScriptDefinition {
+	var found *ScriptDefinition
+	for _, def := range r.byName[name] {
+		if version != "" {
+			if def.Version == version {
+				return def
+			}
+			continue
+		}
+		if found == nil || compareScriptVersions(def.Version, found.Version) > 0 {
+			found = def
+		}
+	}
+	return found
+}
+
+// findByID returns the first definition with the ID, or nil.
+func (r *scriptRegistry) findByID(id string) *ScriptDefinition {
+	return r.byID[id]
+}
+
+// scriptsStamp identifies a version of the definitions file: a ConfigMap update replaces the file,
+// changing its modification time
+type scriptsStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+// scriptsState is the outcome of loading one version of the definitions file
+type scriptsState struct {
+	stamp    scriptsStamp
+	registry *scriptRegistry // The last valid registry when the file is broken; nil without one
+	err      error           // Load error of a broken file without fallback
+}
+
+var (
+	// activeScripts is the outcome of the last load of the definitions file
+	activeScripts atomic.Pointer[scriptsState]
+	// lastValidScripts is the last registry built from a file that loaded without errors
+	lastValidScripts atomic.Pointer[scriptRegistry]
+	// scriptsLoadMu keeps concurrent requests from loading the same file version more than once
+	scriptsLoadMu sync.Mutex
+)
+
+// statScripts returns the stamp of the definitions file's current version.
+func statScripts(filePath string) (scriptsStamp, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return scriptsStamp{}, err
+	}
+	return scriptsStamp{path: filePath, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// currentScripts returns the registry of the definitions file, loading the file only when it
+// changed since the last load (or was never loaded). A request costs a stat of the file instead of
+// reading, parsing and validating it. Errors and fallbacks are those of loadScriptDefinitions.
+func currentScripts(filePath string) (*scriptRegistry, error) {
+	stamp, statErr := statScripts(filePath)
+	if statErr == nil {
+		if state := activeScripts.Load(); state != nil && state.stamp == stamp {
+			return state.registry, state.err
+		}
+	}
+
+	scriptsLoadMu.Lock()
+	defer scriptsLoadMu.Unlock()
+	if state := activeScripts.Load(); statErr == nil && state != nil && state.stamp == stamp {
+		return state.registry, state.err // Loaded by a concurrent request meanwhile
+	}
+	state := loadScriptsState(filePath, stamp)
+	if statErr == nil {
+		activeScripts.Store(state)
+	}
+	return state.registry, state.err
+}
+
+// reloadScripts loads the definitions file even if it didn't change, e.g. to apply an updated
+// command policy or signing key.
+func reloadScripts(filePath string) (*scriptRegistry, error) {
+	scriptsLoadMu.Lock()
+	defer scriptsLoadMu.Unlock()
+	stamp, statErr := statScripts(filePath)
+	state := loadScriptsState(filePath, stamp)
+	if statErr == nil {
+		activeScripts.Store(state)
+	}
+	return state.registry, state.err
+}
+
+// loadScriptsState reads and validates the definitions file. When the file can't be read or holds
+// an invalid definition, the error is logged and the last valid registry is used instead, so a
+// broken edit of the ConfigMap doesn't stop every script; without one the error is kept. A
+// definition rejected by the command policy fails closed instead: the earlier set may hold the very
+// command the policy now forbids.
+func loadScriptsState(filePath string, stamp scriptsStamp) *scriptsState {
+	definitions, policy, invalid, err := readScriptDefinitions(filePath)
+	fallback := true
+	if err == nil && len(invalid) > 0 {
+		err = fmt.Errorf("%s (%d invalid definition(s))", invalid[0].Error, len(invalid))
+		for _, definitionErr := range invalid {
+			fallback = fallback && !definitionErr.rejectedByPolicy
+		}
+	}
+	if err != nil {
+		if previous := lastValidScripts.Load(); previous != nil && fallback {
+			logger.Error().Msgf("Keeping the last valid script definitions: %v", err)
+			return &scriptsState{stamp: stamp, registry: previous}
+		}
+		return &scriptsState{stamp: stamp, err: err}
+	}
+	registry := newScriptRegistry(definitions, policy)
+	lastValidScripts.Store(registry)
+	logger.Debug().Msgf("Loaded %d script definition(s) from '%s'.", len(definitions), filePath)
+	return &scriptsState{stamp: stamp, registry: registry}
+}
+
+// useScripts makes a validated definitions set the active one, as read by POST /admin/scripts/reload.
+func useScripts(filePath string, definitions []ScriptDefinition, policy *CommandPolicy) {
+	scriptsLoadMu.Lock()
+	defer scriptsLoadMu.Unlock()
+	registry := newScriptRegistry(definitions, policy)
+	lastValidScripts.Store(registry)
+	if stamp, err := statScripts(filePath); err == nil {
+		activeScripts.Store(&scriptsState{stamp: stamp, registry: registry})
+	}
+}
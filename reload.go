@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configOverrides holds the KEY=VALUE settings of CONFIG_OVERRIDES_PATH, which take precedence
+// over the environment. Unlike the environment of a running process, the file can change.
+var (
+	configOverridesMu sync.RWMutex
+	configOverrides   map[string]string
+)
+
+// reloadMu serializes reloads
+var reloadMu sync.Mutex
+
+// lookupEnv returns the value of a setting from CONFIG_OVERRIDES_PATH or else the environment.
+func lookupEnv(key string) string {
+	configOverridesMu.RLock()
+	value, ok := configOverrides[key]
+	configOverridesMu.RUnlock()
+	if ok {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+// readConfigOverrides parses an env-file: KEY=VALUE lines, ignoring blank lines and # comments.
+func readConfigOverrides(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config overrides '%s': %v", path, err)
+	}
+	defer file.Close()
+
+	overrides := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("config overrides '%s' line %d is not KEY=VALUE", path, lineNumber)
+		}
+		overrides[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config overrides '%s': %v", path, err)
+	}
+	return overrides, nil
+}
+
+// loadConfigOverrides (re)reads CONFIG_OVERRIDES_PATH, if set.
+func loadConfigOverrides() error {
+	path := os.Getenv("CONFIG_OVERRIDES_PATH")
+	if path == "" {
+		return nil
+	}
+	overrides, err := readConfigOverrides(path)
+	if err != nil {
+		return err
+	}
+	configOverridesMu.Lock()
+	configOverrides = overrides
+	configOverridesMu.Unlock()
+	return nil
+}
+
+// reloadSetting sets a setting, reporting whether its value changed.
+func reloadSetting[T comparable](dst *T, value T) bool {
+	changed := *dst != value
+	*dst = value
+	return changed
+}
+
+// reloadableSettings are the settings a reload applies. Everything else (listen address, TLS,
+// history store, authentication, sinks) is wired up at startup and needs a restart.
+var reloadableSettings = []struct {
+	env   string
+	apply func(dst, src *Config) bool
+}{
+	{"POD_LABEL_SELECTOR", func(dst, src *Config) bool { return reloadSetting(&dst.PodLabelSelector, src.PodLabelSelector) }},
+	{"PROCESS_TRACKING_SERVICE_URL", func(dst, src *Config) bool { return reloadSetting(&dst.ProcessTrackingURL, src.ProcessTrackingURL) }},
+	{"PROCESS_TRACKING_STAGE", func(dst, src *Config) bool { return reloadSetting(&dst.ProcessTrackingStage, src.ProcessTrackingStage) }},
+	{"PROCESS_TRACKING_MAX_ATTEMPTS", func(dst, src *Config) bool {
+		return reloadSetting(&dst.ProcessTrackingMaxAttempts, src.ProcessTrackingMaxAttempts)
+	}},
+	{"PROCESS_TRACKING_RETRY_BASE_MS", func(dst, src *Config) bool {
+		return reloadSetting(&dst.ProcessTrackingRetryBase, src.ProcessTrackingRetryBase)
+	}},
+	{"PROCESS_TRACKING_RETRY_MAX_MS", func(dst, src *Config) bool {
+		return reloadSetting(&dst.ProcessTrackingRetryMax, src.ProcessTrackingRetryMax)
+	}},
+	{"PROCESS_TRACKING_MAX_UPDATES_PER_MINUTE", func(dst, src *Config) bool {
+		return reloadSetting(&dst.ProcessTrackingMaxUpdatesPerMinute, src.ProcessTrackingMaxUpdatesPerMinute)
+	}},
+	{"MONITOR_PROCESS_DEFAULT", func(dst, src *Config) bool {
+		return reloadSetting(&dst.MonitorProcessDefault, src.MonitorProcessDefault)
+	}},
+	{"TRACKING_OUTPUT_MODE", func(dst, src *Config) bool { return reloadSetting(&dst.TrackingOutputMode, src.TrackingOutputMode) }},
+	{"EXEC_TRANSIENT_RETRIES", func(dst, src *Config) bool { return reloadSetting(&dst.ExecTransientRetries, src.ExecTransientRetries) }},
+	{"RATE_LIMIT_EXECUTE_PER_MINUTE", func(dst, src *Config) bool {
+		return reloadSetting(&dst.RateLimitExecutePerMinute, src.RateLimitExecutePerMinute)
+	}},
+	{"RATE_LIMIT_EXECUTE_BURST", func(dst, src *Config) bool {
+		return reloadSetting(&dst.RateLimitExecuteBurst, src.RateLimitExecuteBurst)
+	}},
+	{"DEDICATED_POD_TIMEOUT_SECONDS", func(dst, src *Config) bool { return reloadSetting(&dst.DedicatedPodTimeout, src.DedicatedPodTimeout) }},
+	{"TEKTON_TIMEOUT_SECONDS", func(dst, src *Config) bool { return reloadSetting(&dst.TektonTimeout, src.TektonTimeout) }},
+	{"LOG_LEVEL", func(dst, src *Config) bool { return reloadSetting(&dst.LogLevel, src.LogLevel) }},
+	{"MAINTENANCE_MODE", func(dst, src *Config) bool { return reloadSetting(&dst.MaintenanceMode, src.MaintenanceMode) }},
+	{"MAINTENANCE_MESSAGE", func(dst, src *Config) bool {
+		return reloadSetting(&dst.MaintenanceMessage, src.MaintenanceMessage)
+	}},
+}
+
+// reloadConfig re-reads CONFIG_OVERRIDES_PATH and applies the reloadable settings to the active
+// configuration, returning the names of those that changed. The API keys file is re-read too. An invalid result is rejected and the
+// active configuration kept; executions already running finish with the configuration they started with.
+func reloadConfig() ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	configOverridesMu.RLock()
+	previousOverrides := configOverrides
+	configOverridesMu.RUnlock()
+	if err := loadConfigOverrides(); err != nil {
+		return nil, err
+	}
+
+	fresh := loadConfig()
+	reloaded := *currentConfig()
+	changed := []string{}
+	for _, setting := range reloadableSettings {
+		if setting.apply(&reloaded, fresh) {
+			changed = append(changed, setting.env)
+		}
+	}
+	if err := validateConfig(&reloaded); err != nil {
+		configOverridesMu.Lock()
+		configOverrides = previousOverrides
+		configOverridesMu.Unlock()
+		return nil, fmt.Errorf("reloaded configuration is invalid, keeping the active one:\n%v", err)
+	}
+
+	activeConfig.Store(&reloaded)
+	if err := reloadAPIKeys(&reloaded); err != nil {
+		logger.Error().Msgf("API keys reload failed, keeping the active keys: %v", err)
+	}
+	if containsString(changed, "LOG_LEVEL") {
+		setLogLevel(reloaded.LogLevel)
+	}
+	if containsString(changed, "MAINTENANCE_MODE") {
+		setMaintenance(&reloaded, reloaded.MaintenanceMode, "", "config")
+	}
+	logger.Info().Strs("changed", changed).Msgf("Configuration reloaded; %d setting(s) changed.", len(changed))
+	return changed, nil
+}
+
+// reloadConfigOnSIGHUP reloads the configuration and the script definitions whenever the process
+// receives SIGHUP.
+func reloadConfigOnSIGHUP() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			logger.Info().Msg("Received SIGHUP; reloading configuration and script definitions.")
+			if _, err := reloadConfig(); err != nil {
+				logger.Error().Msgf("Configuration reload failed: %v", err)
+			}
+			if _, err := reloadScripts(currentConfig().ScriptsPath); err != nil {
+				logger.Error().Msgf("Script definitions reload failed: %v", err)
+			}
+		}
+	}()
+}
+
+// postReload handles POST /admin/reload, reloading the configuration like SIGHUP and returning the
+// settings that changed.
+func postReload(c *gin.Context) {
+	caller := callerFromContext(c)
+	changed, err := reloadConfig()
+	if err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, err.Error()))
+		return
+	}
+	logger.Info().Msgf("Configuration reloaded by %s.", caller.Name)
+	writeJSON(c, http.StatusOK, gin.H{"changed": changed})
+}
+
+// postScriptsReload handles POST /admin/scripts/reload: it re-reads SCRIPTS_PATH and validates
+// every definition. A valid set becomes the one executions fall back to; otherwise the errors of
+// all invalid definitions are returned with 422 and the active set stays in use.
+func postScriptsReload(c *gin.Context) {
+	config := configFromContext(c)
+	caller := callerFromContext(c)
+	definitions, policy, invalid, err := readScriptDefinitions(config.ScriptsPath)
+	if err != nil {
+		logger.Warn().Msgf("Script definitions reload by %s failed: %v", caller.Name, err)
+		writeJSON(c, http.StatusUnprocessableEntity, problemWith(http.StatusUnprocessableEntity, codeDefinitionsInvalid, err.Error(), gin.H{"applied": false}))
+		return
+	}
+	if len(invalid) > 0 {
+		logger.Warn().Msgf("Script definitions reload by %s rejected: %d of %d definition(s) invalid.", caller.Name, len(invalid), len(definitions))
+		writeJSON(c, http.StatusUnprocessableEntity, problemWith(http.StatusUnprocessableEntity, codeDefinitionsInvalid,
+			fmt.Sprintf("%d of %d script definition(s) are invalid", len(invalid), len(definitions)),
+			gin.H{"applied": false, "definitions": len(definitions), "errors": invalid}))
+		return
+	}
+	useScripts(config.ScriptsPath, definitions, policy)
+	logger.Info().Msgf("Script definitions reloaded by %s: %d definition(s).", caller.Name, len(definitions))
+	writeJSON(c, http.StatusOK, gin.H{"applied": true, "definitions": len(definitions)})
+}
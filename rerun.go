@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rerunRequest is the optional body of POST /v1/executions/:id/rerun
+type rerunRequest struct {
+	TaskData   map[string]interface{} `json:"taskData,omitempty"`   // Parameters overriding the original ones
+	TrackingID string                 `json:"trackingId,omitempty"` // Defaults to the new execution ID, like for executes
+	Version    string                 `json:"version,omitempty"`    // Pin another script version than the original one
+}
+
+// sameParamName reports whether two parameter names match the way runTask matches taskData keys
+// to definitions: case-insensitively, spaces and underscores alike.
+func sameParamName(a, b string) bool {
+	normalize := func(name string) string { return strings.ReplaceAll(strings.ToUpper(name), " ", "_") }
+	return normalize(a) == normalize(b)
+}
+
+// rewriteTaskData returns a copy of taskData with each parameter passed through rewrite, in each of
+// the forms runTask reads them: top-level keys, {name, value} items and objects of a "parameters"
+// array, and keys of a "parameters" object. Parameters for which rewrite returns false are dropped.
+func rewriteTaskData(taskData map[string]interface{}, rewrite func(name string, value interface{}) (interface{}, bool)) map[string]interface{} {
+	rewriteObject := func(object map[string]interface{}) map[string]interface{} {
+		rewritten := make(map[string]interface{}, len(object))
+		for name, value := range object {
+			if value, keep := rewrite(name, value); keep {
+				rewritten[name] = value
+			}
+		}
+		return rewritten
+	}
+
+	rewritten := make(map[string]interface{}, len(taskData))
+	for key, value := range taskData {
+		switch {
+		case key == "name":
+			rewritten[key] = value
+		case key != "parameters":
+			if value, keep := rewrite(key, value); keep {
+				rewritten[key] = value
+			}
+		default:
+			switch parameters := value.(type) {
+			case map[string]interface{}:
+				rewritten[key] = rewriteObject(parameters)
+			case []interface{}:
+				items := make([]interface{}, 0, len(parameters))
+				for _, item := range parameters {
+					object, isObject := item.(map[string]interface{})
+					if !isObject {
+						items = append(items, item)
+						continue
+					}
+					name, hasName := object["name"].(string)
+					paramValue, hasValue := object["value"]
+					if !hasName {
+						items = append(items, rewriteObject(object))
+						continue
+					}
+					if !hasValue {
+						items = append(items, object)
+						continue
+					}
+					if paramValue, keep := rewrite(name, paramValue); keep {
+						copied := make(map[string]interface{}, len(object))
+						for k, v := range object {
+							copied[k] = v
+						}
+						copied["value"] = paramValue
+						items = append(items, copied)
+					}
+				}
+				rewritten[key] = items
+			default:
+				rewritten[key] = value
+			}
+		}
+	}
+	return rewritten
+}
+
+// sensitiveParameters returns the names of a script's sensitive parameters; for a pipeline, those of
+// the scripts its nodes run, which share its taskData.
+func sensitiveParameters(definitions []ScriptDefinition, def *ScriptDefinition) []string {
+	scripts := []*ScriptDefinition{def}
+	for _, node := range def.Pipeline {
+		if nodeDef := findScriptVersion(definitions, node.Script, node.Version); nodeDef != nil {
+			scripts = append(scripts, nodeDef)
+		}
+	}
+	var names []string
+	for _, script := range scripts {
+		for _, param := range script.Parameters {
+			if param.Sensitive {
+				names = append(names, param.Name)
+			}
+		}
+	}
+	return names
+}
+
+// redactedRequest returns the request to record for re-runs, the values of the given sensitive
+// parameters replaced by auditRedacted.
+func redactedRequest(request TaskServiceRequest, sensitive []string) TaskServiceRequest {
+	request.TaskData = rewriteTaskData(request.TaskData, func(name string, value interface{}) (interface{}, bool) {
+		for _, sensitiveName := range sensitive {
+			if sameParamName(name, sensitiveName) {
+				return auditRedacted, true
+			}
+		}
+		return value, true
+	})
+	return request
+}
+
+// rerunExecution handles POST /v1/executions/:id/rerun, running a recorded execution again with the
+// same request, optionally overriding parameters, tracking ID or version. The new execution records
+// the one it re-runs. Sensitive values aren't stored, so they must be passed again as overrides.
+func rerunExecution(c *gin.Context) {
+	config := configFromContext(c)
+	if executionStore == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotEnabled, "Execution history is not enabled (HISTORY_DB_DSN)"))
+		return
+	}
+	var body rerunRequest
+	if c.Request.ContentLength != 0 {
+		if err := bindJSONWithNaming(c, &body); err != nil {
+			writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, "Invalid request body: "+err.Error()))
+			return
+		}
+	}
+
+	if _, ok := body.TaskData["name"]; ok {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, "A re-run runs the original script; 'taskData.name' can't be overridden"))
+		return
+	}
+
+	record, err := visibleExecution(c, c.Param("id"))
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	if record == nil {
+		writeJSON(c, http.StatusNotFound, problem(http.StatusNotFound, codeNotFound, fmt.Sprintf("Execution '%s' not found", c.Param("id"))))
+		return
+	}
+	request, err := executionStore.ExecutionRequest(record.ID)
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+	if request == nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Execution '%s' has no recorded request and can't be re-run", record.ID)))
+		return
+	}
+
+	// Overrides replace the original parameter in whichever form it was passed
+	request.TaskData = rewriteTaskData(request.TaskData, func(name string, value interface{}) (interface{}, bool) {
+		for overridden := range body.TaskData {
+			if sameParamName(name, overridden) {
+				return nil, false
+			}
+		}
+		return value, true
+	})
+	for name, value := range body.TaskData {
+		request.TaskData[name] = value
+	}
+	var redacted []string
+	rewriteTaskData(request.TaskData, func(name string, value interface{}) (interface{}, bool) {
+		if value == auditRedacted {
+			redacted = append(redacted, name)
+		}
+		return value, true
+	})
+	if len(redacted) > 0 {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeParamMissing,
+			fmt.Sprintf("Sensitive parameters %v of execution '%s' aren't stored; pass them again in taskData", redacted, record.ID)))
+		return
+	}
+
+	// A re-run is a new execution: it gets its own tracking ID unless one is given
+	request.TrackingID = body.TrackingID
+	if body.Version != "" {
+		request.Version = body.Version
+	}
+	writeOutcome(c, runTask(c.Request.Context(), config, *request, executionOptions{Caller: callerFromContext(c), ClientIP: c.ClientIP(), RerunOf: record.ID}))
+}
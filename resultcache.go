@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cachedResult is the output of a successful execution, reused for identical requests of a
+// script with cacheTtlSeconds set.
+type cachedResult struct {
+	ExecutionID string    `json:"executionId"`
+	Pod         string    `json:"pod"`
+	Output      string    `json:"output"`
+	Finished    time.Time `json:"finished"`
+	Expires     time.Time `json:"expires"`
+}
+
+// resultCache holds the cached results of this replica, keyed by resultCacheKey.
+type resultCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+// cachedResults is the process-wide result cache
+var cachedResults = &resultCache{entries: make(map[string]cachedResult)}
+
+// resultCacheKey identifies identical requests: the same script definition with the same resolved
+// parameters and taskData values. Task context (tracking ID, process ID) differs per request and
+// isn't part of the key.
+func resultCacheKey(def *ScriptDefinition, params, taskData map[string]string) string {
+	digest := sha256.New()
+	definition, _ := json.Marshal(def)
+	digest.Write(definition)
+	for _, values := range []map[string]string{params, taskData} {
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		digest.Write([]byte{0})
+		for _, name := range names {
+			digest.Write([]byte(name + "=" + values[name]))
+			digest.Write([]byte{0})
+		}
+	}
+	return def.Name + ":" + hex.EncodeToString(digest.Sum(nil))
+}
+
+// Get returns the unexpired result for a key. With REDIS_URL, results are shared by all replicas;
+// if Redis fails, this replica's own results are used.
+func (c *resultCache) Get(key string) (cachedResult, bool) {
+	if sharedState != nil {
+		result, ok, err := getSharedResult(key)
+		if err == nil {
+			return result, ok
+		}
+		logger.Warn().Msgf("Shared result cache unavailable, using this replica's results: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.entries[key]
+	if !ok || time.Now().After(result.Expires) {
+		delete(c.entries, key)
+		return cachedResult{}, false
+	}
+	return result, true
+}
+
+// Set caches a result until result.Expires, pruning expired results along the way. The result is
+// kept in memory as well, for when Redis fails.
+func (c *resultCache) Set(key string, result cachedResult) {
+	if sharedState != nil {
+		if err := setSharedResult(key, result); err != nil {
+			logger.Warn().Msgf("Failed to share the cached result of '%s': %v", key, err)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.Expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = result
+}
+
+// getSharedResult returns the result cached under a key in Redis.
+func getSharedResult(key string) (cachedResult, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+	data, err := sharedState.Get(ctx, sharedKey("result", key)).Bytes()
+	if err == redis.Nil {
+		return cachedResult{}, false, nil
+	}
+	if err != nil {
+		return cachedResult{}, false, err
+	}
+	var result cachedResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return cachedResult{}, false, err
+	}
+	return result, true, nil
+}
+
+// setSharedResult caches a result in Redis until it expires.
+func setSharedResult(key string, result cachedResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sharedStateTimeout)
+	defer cancel()
+	return sharedState.Set(ctx, sharedKey("result", key), data, time.Until(result.Expires)).Err()
+}
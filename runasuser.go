@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// runAsUserNamePattern matches the user names runAsUser accepts for wrapped commands
+var runAsUserNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// runAsUserInContainerSpec reports whether a backend creates the container it runs in, so runAsUser
+// becomes the container's securityContext.runAsUser (a numeric UID) instead of wrapping the command.
+func runAsUserInContainerSpec(backend string) bool {
+	return backend == backendJob || backend == backendEphemeralContainer
+}
+
+// validateRunAsUser checks the "runAsUser" field of a script definition against its backend.
+func validateRunAsUser(def *ScriptDefinition) error {
+	if def.RunAsUser == "" {
+		return nil
+	}
+	backend := inferBackend(def)
+	switch {
+	case backend == backendTekton:
+		return fmt.Errorf("backend 'tekton' doesn't support 'runAsUser'")
+	case runAsUserInContainerSpec(backend):
+		if uid, err := strconv.ParseInt(def.RunAsUser, 10, 64); err != nil || uid < 0 {
+			return fmt.Errorf("backend '%s' needs a numeric UID as 'runAsUser', not '%s'", backend, def.RunAsUser)
+		}
+	case !runAsUserNamePattern.MatchString(def.RunAsUser):
+		return fmt.Errorf("'%s' is not a user name; backend '%s' runs the command through runuser/su, which need one", def.RunAsUser, backend)
+	}
+	return nil
+}
+
+// runAsUserCommand wraps a command so it runs as the script's runAsUser, with runuser or, where the
+// target lacks it, su. The command travels base64-encoded, so it survives the quoting of every
+// backend unchanged; the environment prefix inside it is applied by the user's own shell.
+func runAsUserCommand(def *ScriptDefinition, backend, command string) string {
+	if def.RunAsUser == "" || runAsUserInContainerSpec(backend) || backend == backendTekton {
+		return command
+	}
+	decoded := fmt.Sprintf(`"$(echo %s | base64 -d)"`, base64.StdEncoding.EncodeToString([]byte(command)))
+	return fmt.Sprintf(`if command -v runuser >/dev/null 2>&1; then exec runuser -u %s -- /bin/bash -c %s; else exec su -s /bin/bash -c %s %s; fi`,
+		def.RunAsUser, decoded, decoded, def.RunAsUser)
+}
+
+// runAsUserSecurityContext returns the securityContext of a container a backend creates for the
+// script, or nil without runAsUser.
+func runAsUserSecurityContext(def *ScriptDefinition) *corev1.SecurityContext {
+	uid, err := strconv.ParseInt(def.RunAsUser, 10, 64)
+	if def.RunAsUser == "" || err != nil {
+		return nil
+	}
+	return &corev1.SecurityContext{RunAsUser: &uid}
+}
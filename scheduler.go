@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Scheduler mode for the in-process cron scheduler
+const schedulerModeInternal = "internal"
+
+// internalScheduler fires executions for scripts with a `schedule` from inside the executor,
+// independent of the Kubernetes CronJob backend. Runs go through the same execute flow as
+// /v1/execute, so they are reported to process tracking and recorded in history.
+type internalScheduler struct {
+	cron      *cron.Cron
+	slots     chan struct{} // Bounds concurrently running scheduled executions (SCHEDULER_MAX_CONCURRENT)
+	entries   map[string]cron.EntryID
+	schedules map[string]string // Script name -> schedule currently registered
+}
+
+// newInternalScheduler creates a scheduler allowing at most maxConcurrent scheduled runs at once.
+func newInternalScheduler(maxConcurrent int) *internalScheduler {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &internalScheduler{
+		cron:      cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger))),
+		slots:     make(chan struct{}, maxConcurrent),
+		entries:   make(map[string]cron.EntryID),
+		schedules: make(map[string]string),
+	}
+}
+
+// sync registers, re-registers or removes cron entries to match the current script definitions.
+func (s *internalScheduler) sync(config *Config) error {
+	definitions, err := loadScriptDefinitions(config.ScriptsPath)
+	if err != nil {
+		return fmt.Errorf("failed to load script definitions: %v", err)
+	}
+
+	definitions = latestScriptVersions(definitions)
+	wanted := make(map[string]string)
+	for _, def := range definitions {
+		if def.Schedule != "" && !def.Disabled {
+			wanted[def.Name] = def.Schedule
+		}
+	}
+
+	for name, entryID := range s.entries {
+		if schedule, ok := wanted[name]; ok && schedule == s.schedules[name] {
+			continue
+		}
+		logger.Info().Msgf("[Scheduler] Removing schedule '%s' for script '%s'.", s.schedules[name], name)
+		s.cron.Remove(entryID)
+		delete(s.entries, name)
+		delete(s.schedules, name)
+	}
+
+	names := make([]string, 0, len(wanted))
+	for name := range wanted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, registered := s.entries[name]; registered {
+			continue
+		}
+		scriptName, schedule := name, wanted[name]
+		// SkipIfStillRunning: a slow run is never overlapped by the next tick of the same script
+		job := cron.NewChain(cron.SkipIfStillRunning(cron.DefaultLogger)).Then(cron.FuncJob(func() { s.run(scriptName) }))
+		entryID, err := s.cron.AddJob(schedule, job)
+		if err != nil {
+			logger.Error().Msgf("[Scheduler] Failed to register schedule '%s' for script '%s': %v", schedule, scriptName, err)
+			continue
+		}
+		logger.Info().Msgf("[Scheduler] Registered schedule '%s' for script '%s'.", schedule, scriptName)
+		s.entries[scriptName] = entryID
+		s.schedules[scriptName] = schedule
+	}
+	return nil
+}
+
+// run executes one scheduled run of a script, unless the global concurrency limit is reached.
+func (s *internalScheduler) run(scriptName string) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		logger.Info().Msgf("[Scheduler] Skipping scheduled run of '%s': %d scheduled executions already running.", scriptName, cap(s.slots))
+		return
+	}
+
+	request := TaskServiceRequest{
+		TaskName:    "scheduled:" + scriptName,
+		LastRunTime: time.Now().Unix(),
+		TaskData:    map[string]interface{}{"name": scriptName},
+	}
+	logger.Info().Msgf("[Scheduler] Starting scheduled run of script '%s'.", scriptName)
+	outcome := runTask(context.Background(), currentConfig(), request, executionOptions{Caller: schedulerCaller, Scheduled: true})
+	logger.Info().Msgf("[Scheduler] Scheduled run of script '%s' finished with status %d (ProcessID: %d).", scriptName, outcome.StatusCode, outcome.ProcessID)
+}
+
+// startInternalScheduler starts the cron loop and periodically re-syncs it with the definitions.
+func startInternalScheduler(config *Config) {
+	scheduler := newInternalScheduler(config.SchedulerMaxConcurrent)
+	if err := scheduler.sync(config); err != nil {
+		logger.Error().Msgf("[Scheduler] Initial schedule sync failed: %v", err)
+	}
+	scheduler.cron.Start()
+
+	go func() {
+		for {
+			time.Sleep(config.SchedulerReconcileInterval)
+			if err := scheduler.sync(currentConfig()); err != nil {
+				logger.Error().Msgf("[Scheduler] Schedule sync failed: %v", err)
+			}
+		}
+	}()
+	logger.Info().Msgf("[Scheduler] Internal scheduler started (max concurrent: %d, sync interval: %s).", cap(scheduler.slots), config.SchedulerReconcileInterval)
+}
+
+// validateSchedule validates a cron expression: 5 standard fields or a descriptor such as @daily.
+// "@every <duration>" is accepted too, but only by the internal scheduler.
+func validateSchedule(schedule string) error {
+	_, err := cron.ParseStandard(strings.TrimSpace(schedule))
+	return err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// exclusiveRetryInterval is how often a serialized, exclusive script retries a Lease held elsewhere
+const exclusiveRetryInterval = 2 * time.Second
+
+// scriptQueue runs the executions of each serialized script one at a time, in arrival order.
+type scriptQueue struct {
+	mu     sync.Mutex
+	queues map[string]*serialQueue
+}
+
+// serialQueue is the running flag and the waiting executions of one script
+type serialQueue struct {
+	running bool
+	waiters []chan struct{}
+}
+
+// serializedScripts queues the executions of scripts with "serialize": true
+var serializedScripts = &scriptQueue{queues: make(map[string]*serialQueue)}
+
+// errQueueTimeout rejects an execution that waited SERIALIZE_MAX_WAIT_SECONDS without its turn
+var errQueueTimeout = errors.New("timed out waiting for the running execution to finish")
+
+// acquire waits until no other execution of the script runs on this replica, for at most
+// maxWait (0 waits as long as ctx lasts). It returns how many executions were ahead and the
+// function ending the turn, which hands it to the next waiting execution.
+func (q *scriptQueue) acquire(ctx context.Context, script string, maxWait time.Duration) (func(), int, error) {
+	q.mu.Lock()
+	queue, ok := q.queues[script]
+	if !ok {
+		queue = &serialQueue{}
+		q.queues[script] = queue
+	}
+	release := func() { q.release(script) }
+	if !queue.running {
+		queue.running = true
+		q.mu.Unlock()
+		return release, 0, nil
+	}
+	ahead := len(queue.waiters) + 1
+	turn := make(chan struct{})
+	queue.waiters = append(queue.waiters, turn)
+	q.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var err error
+	select {
+	case <-turn:
+		return release, ahead, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = errQueueTimeout
+	}
+
+	// Leave the queue; if the turn came meanwhile, pass it on
+	q.mu.Lock()
+	for i, waiter := range queue.waiters {
+		if waiter == turn {
+			queue.waiters = append(queue.waiters[:i], queue.waiters[i+1:]...)
+			q.mu.Unlock()
+			return nil, ahead, err
+		}
+	}
+	q.mu.Unlock()
+	release()
+	return nil, ahead, err
+}
+
+// release ends the running execution's turn.
+func (q *scriptQueue) release(script string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	queue := q.queues[script]
+	if len(queue.waiters) > 0 {
+		next := queue.waiters[0]
+		queue.waiters = queue.waiters[1:]
+		close(next)
+		return
+	}
+	delete(q.queues, script)
+}
+
+// acquireScriptLockWaiting takes the Lease of a serialized, exclusive script, waiting while
+// another replica holds it instead of rejecting the execution.
+func acquireScriptLockWaiting(ctx context.Context, config *Config, def *ScriptDefinition, executionID string, deadline time.Time) (func(), error) {
+	for {
+		release, err := acquireScriptLock(ctx, config, def, executionID)
+		var locked *scriptLockedError
+		if !errors.As(err, &locked) {
+			return release, err
+		}
+		if !deadline.IsZero() && time.Now().Add(exclusiveRetryInterval).After(deadline) {
+			return nil, fmt.Errorf("%w: %v", errQueueTimeout, err)
+		}
+		executionLog(ctx).Info().Msgf("Waiting for the Lease of script '%s', held by '%s'...", def.Name, locked.Holder)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(exclusiveRetryInterval):
+		}
+	}
+}
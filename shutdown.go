@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// executionTracker keeps the executions in flight, so a shutdown can refuse new ones, wait for the
+// running ones and report those that didn't finish in time, and a cancel request can stop one.
+type executionTracker struct {
+	mu       sync.Mutex
+	draining bool
+	running  map[string]int64            // Execution ID -> process tracking ID (0 until created)
+	controls map[string]executionControl // Execution ID -> what a cancel request needs
+	idle     chan struct{}               // Closed when draining and nothing runs anymore
+}
+
+// executionControl is the script of an execution in flight and the function cancelling its context
+type executionControl struct {
+	definition *ScriptDefinition
+	cancel     context.CancelCauseFunc
+}
+
+// inFlightExecutions tracks every execution of this executor
+var inFlightExecutions = &executionTracker{running: make(map[string]int64), controls: make(map[string]executionControl), idle: make(chan struct{})}
+
+// errShutdownStop is the cause of executions stopped because they outlasted the shutdown grace period
+var errShutdownStop = errors.New("the executor shut down before the script finished")
+
+// shutdownStopSlack is the time stopped executions get on top of TERMINATION_GRACE_SECONDS to send
+// their final tracking update
+const shutdownStopSlack = 10 * time.Second
+
+// errShuttingDown rejects executions requested while the executor drains
+var errShuttingDown = errors.New("the executor is shutting down, retry on another replica")
+
+// begin registers a starting execution of a script, cancelled through cancel; it fails once the
+// executor drains.
+func (t *executionTracker) begin(executionID string, def *ScriptDefinition, cancel context.CancelCauseFunc) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return errShuttingDown
+	}
+	t.running[executionID] = 0
+	t.controls[executionID] = executionControl{definition: def, cancel: cancel}
+	return nil
+}
+
+// lookup returns the ID and control of an execution in flight, given its execution ID or process
+// tracking ID.
+func (t *executionTracker) lookup(id string) (string, executionControl, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for executionID, processID := range t.running {
+		if executionID == id || (processID > 0 && strconv.FormatInt(processID, 10) == id) {
+			control, ok := t.controls[executionID]
+			return executionID, control, ok
+		}
+	}
+	return "", executionControl{}, false
+}
+
+// setProcessID records the process tracking ID of a running execution.
+func (t *executionTracker) setProcessID(executionID string, processID int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.running[executionID]; ok {
+		t.running[executionID] = processID
+	}
+}
+
+// end unregisters a finished execution.
+func (t *executionTracker) end(executionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.running, executionID)
+	delete(t.controls, executionID)
+	if t.draining && len(t.running) == 0 {
+		t.closeIdle()
+	}
+}
+
+// stopAll cancels every execution in flight with cause.
+func (t *executionTracker) stopAll(cause error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, control := range t.controls {
+		control.cancel(cause)
+	}
+}
+
+// closeIdle signals that draining is complete. The caller holds mu.
+func (t *executionTracker) closeIdle() {
+	select {
+	case <-t.idle:
+	default:
+		close(t.idle)
+	}
+}
+
+// drain refuses new executions and waits until the running ones finished or ctx is done. It returns
+// the executions still running then.
+func (t *executionTracker) drain(ctx context.Context) map[string]int64 {
+	t.mu.Lock()
+	t.draining = true
+	if len(t.running) == 0 {
+		t.closeIdle()
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.idle:
+		return nil
+	case <-ctx.Done():
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	remaining := make(map[string]int64, len(t.running))
+	for executionID, processID := range t.running {
+		remaining[executionID] = processID
+	}
+	return remaining
+}
+
+// serveUntilShutdown runs the HTTP server until SIGTERM or SIGINT, then shuts down gracefully:
+// new executions are refused, the listener closes, and in-flight executions get up to
+// SHUTDOWN_GRACE_SECONDS to finish with their tracking updates. Executions still running after
+// that are stopped like cancelled ones, within TERMINATION_GRACE_SECONDS; those that don't end even
+// then are reported as FAILED before the process exits, instead of staying in PROGRESS. The other
+// servers (the admin listener) are shut down alongside.
+func serveUntilShutdown(config *Config, server *http.Server, listen func() error, others ...*http.Server) {
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- listen()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case err := <-serverErrors:
+		logger.Fatal().Msgf("Failed to start server: %v", err)
+	case sig := <-signals:
+		logger.Info().Msgf("Received %s, shutting down (grace period: %s)...", sig, config.ShutdownGracePeriod)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownGracePeriod)
+	defer cancel()
+	drained := make(chan map[string]int64, 1)
+	go func() {
+		drained <- inFlightExecutions.drain(ctx)
+	}()
+	// Shutdown waits for running requests, i.e. synchronous executions, to be answered
+	for _, s := range append([]*http.Server{server}, others...) {
+		if err := s.Shutdown(ctx); err != nil {
+			logger.Warn().Msgf("HTTP server on %s did not shut down cleanly: %v", s.Addr, err)
+		}
+	}
+	remaining := <-drained
+	if len(remaining) > 0 {
+		// Stopped like a cancelled execution, each records its own failure and termination
+		logger.Warn().Msgf("%d execution(s) still running after the grace period, stopping them.", len(remaining))
+		inFlightExecutions.stopAll(errShutdownStop)
+		stopCtx, cancelStop := context.WithTimeout(context.Background(), config.TerminationGracePeriod+shutdownStopSlack)
+		defer cancelStop()
+		remaining = inFlightExecutions.drain(stopCtx)
+	}
+	if len(remaining) > 0 {
+		logger.Error().Msgf("%d execution(s) still running after they were stopped, reporting them as failed.", len(remaining))
+		failAbandonedExecutions(currentConfig(), remaining)
+	}
+
+	if eventBus != nil {
+		if err := eventBus.Close(); err != nil {
+			logger.Warn().Msgf("Failed to close event publisher: %v", err)
+		}
+	}
+	if sharedState != nil {
+		sharedState.Close()
+	}
+	logger.Info().Msg("Shutdown complete.")
+}
+
+// failAbandonedExecutions records executions interrupted by the shutdown as failed, in the
+// history store and in Process Tracking.
+func failAbandonedExecutions(config *Config, remaining map[string]int64) {
+	const reason = "Executor shut down before the script finished"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for executionID, processID := range remaining {
+		executionStore.RecordFinish(executionID, "", executionStatusFailed, "", reason)
+		notifyProcessTrackingUpdate(ctx, config, processID, ProcessTrackingUpdatePayload{
+			Status:  "FAILED",
+			Message: reason,
+		})
+		inFlightJournal.remove(executionID)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadSigningPublicKey parses SCRIPT_SIGNING_PUBKEY, which holds either a PEM-encoded public key
+// or the path of a file containing one (e.g. a mounted Secret).
+func loadSigningPublicKey(value string) (interface{}, error) {
+	pemData := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		content, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing public key '%s': %v", value, err)
+		}
+		pemData = content
+	}
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, fmt.Errorf("signing public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing public key: %v", err)
+	}
+	return key, nil
+}
+
+// verifyDefinitionsSignature checks the detached signature of the script definitions file when
+// SCRIPT_SIGNING_PUBKEY is configured. The signature file (SCRIPT_SIGNATURE_PATH, default
+// <SCRIPTS_PATH>.sig) holds the base64-encoded signature of the file's exact bytes, as produced by
+// `cosign sign-blob` (ECDSA P-256 / RSA over SHA-256) or an Ed25519 key. Since the scripts run with
+// production access, unsigned or tampered definitions are refused.
+func verifyDefinitionsSignature(config *Config, filePath string, content []byte) error {
+	if config.ScriptSigningPubKey == "" {
+		return nil
+	}
+	key, err := loadSigningPublicKey(config.ScriptSigningPubKey)
+	if err != nil {
+		return err
+	}
+
+	signaturePath := config.ScriptSignaturePath
+	if signaturePath == "" {
+		signaturePath = filePath + ".sig"
+	}
+	encoded, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return fmt.Errorf("script definitions '%s' are not signed: failed to read signature '%s': %v", filePath, signaturePath, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("signature '%s' is not valid base64: %v", signaturePath, err)
+	}
+
+	digest := sha256.Sum256(content)
+	valid := false
+	switch publicKey := key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(publicKey, content, signature)
+	default:
+		return fmt.Errorf("unsupported signing public key type %T", key)
+	}
+	if !valid {
+		return fmt.Errorf("signature '%s' does not match script definitions '%s': refusing to load tampered definitions", signaturePath, filePath)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSigner signs content the way the executor verifies it for one key type
+type testSigner struct {
+	publicKeyPEM string
+	sign         func(content []byte) []byte
+}
+
+func newTestSigners(t *testing.T) map[string]testSigner {
+	t.Helper()
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Public, ed25519Private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(key interface{}) string {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+	return map[string]testSigner{
+		"ecdsa": {encode(&ecdsaKey.PublicKey), func(content []byte) []byte {
+			digest := sha256.Sum256(content)
+			signature, err := ecdsa.SignASN1(rand.Reader, ecdsaKey, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signature
+		}},
+		"rsa": {encode(&rsaKey.PublicKey), func(content []byte) []byte {
+			digest := sha256.Sum256(content)
+			signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signature
+		}},
+		"ed25519": {encode(ed25519Public), func(content []byte) []byte {
+			return ed25519.Sign(ed25519Private, content)
+		}},
+	}
+}
+
+func TestVerifyDefinitionsSignature(t *testing.T) {
+	signers := newTestSigners(t)
+	content := []byte(`[{"id": "restore", "name": "restore", "command": "/scripts/restore.sh"}]`)
+	tampered := []byte(`[{"id": "restore", "name": "restore", "command": "/scripts/restore.sh; id"}]`)
+
+	tests := []struct {
+		name      string
+		key       string // Key type configured as SCRIPT_SIGNING_PUBKEY
+		signer    string // Key type that signed the original content, empty to use signature
+		file      []byte // Content of the definitions file when verified
+		signature string // Raw contents of the signature file when signer is empty
+		valid     bool
+	}{
+		{"ecdsa valid", "ecdsa", "ecdsa", content, "", true},
+		{"ecdsa tampered content", "ecdsa", "ecdsa", tampered, "", false},
+		{"ecdsa signed with rsa", "ecdsa", "rsa", content, "", false},
+		{"ecdsa malformed signature", "ecdsa", "", content, base64.StdEncoding.EncodeToString([]byte("not a signature")), false},
+		{"rsa valid", "rsa", "rsa", content, "", true},
+		{"rsa tampered content", "rsa", "rsa", tampered, "", false},
+		{"rsa signed with ed25519", "rsa", "ed25519", content, "", false},
+		{"rsa malformed signature", "rsa", "", content, "%%% not base64 %%%", false},
+		{"ed25519 valid", "ed25519", "ed25519", content, "", true},
+		{"ed25519 tampered content", "ed25519", "ed25519", tampered, "", false},
+		{"ed25519 signed with ecdsa", "ed25519", "ecdsa", content, "", false},
+		{"ed25519 malformed signature", "ed25519", "", content, base64.StdEncoding.EncodeToString([]byte{0x01, 0x02}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scriptsPath := filepath.Join(t.TempDir(), "scripts.json")
+			signature := tt.signature
+			if tt.signer != "" {
+				signature = base64.StdEncoding.EncodeToString(signers[tt.signer].sign(content))
+			}
+			if err := os.WriteFile(scriptsPath+".sig", []byte(signature+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			config := &Config{ScriptSigningPubKey: signers[tt.key].publicKeyPEM}
+			err := verifyDefinitionsSignature(config, scriptsPath, tt.file)
+			if (err == nil) != tt.valid {
+				t.Errorf("verifyDefinitionsSignature(%s) = %v, want valid=%v", tt.name, err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// backendSSH runs scripts on a VM over SSH
+const backendSSH = "ssh"
+
+// sshDialTimeout bounds connecting and the SSH handshake
+const sshDialTimeout = 15 * time.Second
+
+// SSHTarget is the VM an SSH-backend script runs on. Connection details and credentials come from
+// a Secret in NAMESPACE with the keys host, port (default 22), username, privateKey (with an
+// optional passphrase) or password, and knownHosts (known_hosts lines of the host).
+type SSHTarget struct {
+	Secret string `json:"secret"`         // Name of the Secret
+	Host   string `json:"host,omitempty"` // Overrides the Secret's host
+	Port   int    `json:"port,omitempty"` // Overrides the Secret's port
+	User   string `json:"user,omitempty"` // Overrides the Secret's username
+}
+
+// validateSSHTarget checks the "ssh" field of a script definition.
+func validateSSHTarget(def *ScriptDefinition) error {
+	if def.SSH.Secret == "" {
+		return fmt.Errorf("'ssh' is missing required 'secret' field")
+	}
+	if def.Image != "" || def.DebugImage != "" || def.TektonPipeline != "" || isNodeTargeted(def) {
+		return fmt.Errorf("'ssh' can't be combined with image, debugImage, nodeName/nodeSelector or tektonPipeline")
+	}
+	if def.SSH.Port < 0 || def.SSH.Port > 65535 {
+		return fmt.Errorf("'ssh' has an invalid 'port' %d", def.SSH.Port)
+	}
+	return nil
+}
+
+// sshConnection is the resolved address and client configuration of an SSH target
+type sshConnection struct {
+	address string
+	config  *ssh.ClientConfig
+}
+
+// resolveSSHTarget reads the Secret of an SSH target. Host keys are always verified, against the
+// Secret's knownHosts or else SSH_KNOWN_HOSTS_PATH; a target with neither is refused.
+func resolveSSHTarget(ctx context.Context, config *Config, target *SSHTarget) (*sshConnection, error) {
+	if kubeClient == nil {
+		return nil, fmt.Errorf("kubernetes client not initialized")
+	}
+	secret, err := kubeClient.CoreV1().Secrets(config.Namespace).Get(ctx, target.Secret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH secret '%s': %v", target.Secret, err)
+	}
+	value := func(key, override string) string {
+		if override != "" {
+			return override
+		}
+		return strings.TrimSpace(string(secret.Data[key]))
+	}
+
+	host, user := value("host", target.Host), value("username", target.User)
+	if host == "" || user == "" {
+		return nil, fmt.Errorf("SSH secret '%s' is missing 'host' or 'username'", target.Secret)
+	}
+	port := 22
+	if target.Port > 0 {
+		port = target.Port
+	} else if raw := value("port", ""); raw != "" {
+		if port, err = strconv.Atoi(raw); err != nil {
+			return nil, fmt.Errorf("SSH secret '%s' has an invalid 'port' '%s'", target.Secret, raw)
+		}
+	}
+
+	var auth []ssh.AuthMethod
+	if privateKey := secret.Data["privateKey"]; len(privateKey) > 0 {
+		var signer ssh.Signer
+		if passphrase := secret.Data["passphrase"]; len(passphrase) > 0 {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, passphrase)
+		} else {
+			signer, err = ssh.ParsePrivateKey(privateKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SSH secret '%s' has an invalid 'privateKey': %v", target.Secret, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if password := secret.Data["password"]; len(password) > 0 {
+		auth = append(auth, ssh.Password(string(password)))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("SSH secret '%s' has neither 'privateKey' nor 'password'", target.Secret)
+	}
+
+	hostKeyCallback, err := sshHostKeyCallback(config, secret.Data["knownHosts"])
+	if err != nil {
+		return nil, fmt.Errorf("no host key verification for SSH secret '%s': %v", target.Secret, err)
+	}
+	return &sshConnection{
+		address: net.JoinHostPort(host, strconv.Itoa(port)),
+		config: &ssh.ClientConfig{
+			User:            user,
+			Auth:            auth,
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         sshDialTimeout,
+		},
+	}, nil
+}
+
+// sshHostKeyCallback verifies host keys against known_hosts lines, or SSH_KNOWN_HOSTS_PATH when
+// the Secret has none.
+func sshHostKeyCallback(config *Config, knownHostsData []byte) (ssh.HostKeyCallback, error) {
+	if len(knownHostsData) == 0 {
+		if config.SSHKnownHostsPath == "" {
+			return nil, errors.New("the secret has no 'knownHosts' and SSH_KNOWN_HOSTS_PATH is not set")
+		}
+		return knownhosts.New(config.SSHKnownHostsPath)
+	}
+	// knownhosts only reads files
+	file, err := os.CreateTemp("", "known_hosts-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(knownHostsData)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return knownhosts.New(file.Name())
+}
+
+// sshExecutor runs the command, or each step over the same connection, on a VM.
+type sshExecutor struct{}
+
+func (sshExecutor) UsesWorkloadPod() bool { return false }
+
+func (sshExecutor) Run(ctx context.Context, run *executorRun) (string, string, error) {
+	connection, err := resolveSSHTarget(ctx, run.Config, run.Definition.SSH)
+	if err != nil {
+		return "", "", err
+	}
+	target := connection.config.User + "@" + connection.address
+	executionLog(ctx).Info().Msgf("Executing command for script '%s' on '%s' over SSH...", run.Definition.Name, target)
+
+	dialer := net.Dialer{Timeout: sshDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", connection.address)
+	if err != nil {
+		return target, "", fmt.Errorf("failed to connect to '%s': %v", connection.address, err)
+	}
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, connection.address, connection.config)
+	if err != nil {
+		conn.Close()
+		return target, "", fmt.Errorf("SSH handshake with '%s' failed: %v", connection.address, err)
+	}
+	client := ssh.NewClient(sshConn, channels, requests)
+	defer client.Close()
+	// Cancelling the execution closes the connection, ending the remote command's session
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	exec := func(command string) (string, error) {
+		session, err := client.NewSession()
+		if err != nil {
+			return "", err
+		}
+		defer session.Close()
+		output, err := session.CombinedOutput("/bin/bash -c " + shellQuote(command))
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			// Same wording as local processes, so exit codes are reported alike
+			err = fmt.Errorf("exit status %d", exitErr.ExitStatus())
+		}
+		return string(output), err
+	}
+	write := func(file paramFile) error {
+		session, err := client.NewSession()
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+		session.Stdin = strings.NewReader(file.Content)
+		if output, err := session.CombinedOutput(paramFileWriteCommand(run.Definition, file.Path)); err != nil {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+	cleanup, err := deliverParamFiles(ctx, run, write, exec)
+	if err != nil {
+		return target, "", err
+	}
+	defer cleanup()
+	if len(run.Steps) > 0 {
+		output, err := runSteps(ctx, run, exec)
+		return target, output, err
+	}
+	output, err := exec(run.Command)
+	return target, output, err
+}
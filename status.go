@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusHandler handles GET /v1/status: an operator overview of the executor beyond what the
+// probes report, e.g. scripts whose command is missing from their target image (PREFLIGHT_CHECK),
+// and the permissions in every namespace scripts use. The status is "degraded" when a check failed;
+// the replica keeps serving either way.
+func statusHandler(c *gin.Context) {
+	config := configFromContext(c)
+	status := gin.H{"status": "ok", "version": version}
+	if definitions, err := loadScriptDefinitions(config.ScriptsPath); err != nil {
+		status["status"] = "degraded"
+		status["definitionsError"] = err.Error()
+	} else {
+		status["definitions"] = len(definitions)
+	}
+	if state := maintenance.Load(); state != nil && state.Paused {
+		status["maintenance"] = state
+	}
+	if kubeClient != nil && !config.SkipPermissionCheck {
+		permissions := gin.H{}
+		for _, result := range currentPermissions(c.Request.Context(), config) {
+			permissions[result.Namespace] = "ok"
+			if result.Err != nil {
+				permissions[result.Namespace] = result.Err.Error()
+				status["status"] = "degraded"
+			}
+		}
+		status["permissions"] = permissions
+	}
+	if report := lastPreflight.Load(); report != nil {
+		if report.Failed > 0 {
+			status["status"] = "degraded"
+		}
+		status["preflight"] = report
+	}
+	writeJSON(c, http.StatusOK, status)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ScriptStep is one command of a multi-step script. Steps run in order in the same pod.
+type ScriptStep struct {
+	Name    string `json:"name,omitempty"` // Shown in progress updates; defaults to "step N"
+	Command string `json:"command"`        // Required; supports ${VAR_NAME} placeholders like a script command
+}
+
+// stepName returns the display name of the step at index i.
+func stepName(step ScriptStep, i int) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	return fmt.Sprintf("step %d", i+1)
+}
+
+// validateSteps checks the steps of a script definition. Steps are exec'd one by one into the selected
+// workload pod (or run locally), so they can't be combined with a single command or with backends that create their own pod.
+func validateSteps(def *ScriptDefinition) error {
+	if len(def.Steps) == 0 {
+		return nil
+	}
+	if def.Command != "" {
+		return fmt.Errorf("'command' and 'steps' are mutually exclusive")
+	}
+	if def.Image != "" || def.DebugImage != "" || def.TektonPipeline != "" || isNodeTargeted(def) {
+		return fmt.Errorf("'steps' are only supported for scripts exec'd into a workload pod")
+	}
+	for i, step := range def.Steps {
+		if strings.TrimSpace(step.Command) == "" {
+			return fmt.Errorf("step %d is missing required 'command' field", i+1)
+		}
+	}
+	return nil
+}
+
+// runSteps executes the steps of a script in order in the same pod, sending a PROGRESS update to
+// process tracking before each step and aborting on the first failing step. run.Steps holds the
+// full shell command of each step (environment prefix and expanded placeholders), which exec runs
+// on the backend's target. The returned output contains the output of every step that ran, each under a header line.
+func runSteps(ctx context.Context, run *executorRun, exec func(command string) (string, error)) (string, error) {
+	xlog := executionLog(ctx)
+	def := run.Definition
+	var output strings.Builder
+	for i, step := range def.Steps {
+		name := stepName(step, i)
+		xlog.Info().Msgf("Executing step %d/%d '%s' of script '%s'...", i+1, len(def.Steps), name, def.Name)
+		if run.ProcessID > 0 {
+			notifyProcessTrackingUpdate(ctx, run.Config, run.ProcessID, ProcessTrackingUpdatePayload{
+				Status:  "PROGRESS",
+				Message: fmt.Sprintf("Running step %d/%d: %s", i+1, len(def.Steps), name),
+			})
+		}
+
+		stepOutput, err := exec(run.Steps[i])
+		fmt.Fprintf(&output, "=== Step %d/%d: %s ===\n%s", i+1, len(def.Steps), name, stepOutput)
+		if !strings.HasSuffix(stepOutput, "\n") {
+			output.WriteString("\n")
+		}
+		if err != nil {
+			xlog.Error().Msgf("Step %d/%d '%s' of script '%s' failed, aborting remaining steps: %v", i+1, len(def.Steps), name, def.Name, err)
+			return output.String(), fmt.Errorf("step %d/%d '%s' failed: %v", i+1, len(def.Steps), name, err)
+		}
+	}
+	return output.String(), nil
+}
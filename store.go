@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	// Postgres driver, for multi-replica deployments sharing one history database
+	_ "github.com/lib/pq"
+	// SQLite driver (pure Go, works with CGO_ENABLED=0)
+	_ "modernc.org/sqlite"
+)
+
+// migrationLockID is the Postgres advisory lock key held while migrations run,
+// so replicas starting at the same time don't apply the same migration twice.
+const migrationLockID = 7305130513
+
+// supportedHistoryDBDrivers lists the database/sql drivers linked into the binary
+var supportedHistoryDBDrivers = []string{"sqlite", "postgres"}
+
+// Execution statuses recorded in the execution history
+const (
+	executionStatusRunning    = "RUNNING"
+	executionStatusSuccessful = "SUCCESSFUL"
+	executionStatusFailed     = "FAILED"
+	executionStatusWarning    = "WARNING" // The script exited with a code its definition maps to WARNING
+	executionStatusSkipped    = "SKIPPED" // The script exited with a code its definition maps to SKIPPED
+)
+
+// newRecordID returns a UUIDv7, which is unique across replicas and sorts by creation time like the
+// timestamp IDs it replaced. It identifies executions, audit events and execution events.
+func newRecordID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// ExecutionStore persists execution history. A nil *ExecutionStore is valid and records nothing,
+// so callers don't need to check whether history persistence is enabled.
+type ExecutionStore struct {
+	db      *sql.DB
+	dialect string
+}
+
+// executionStore is the process-wide history store, nil when HISTORY_DB_DSN is not configured
+var executionStore *ExecutionStore
+
+// openHistoryDB opens (but does not migrate) the configured history database.
+func openHistoryDB(config *Config) (*sql.DB, error) {
+	supported := false
+	for _, driver := range supportedHistoryDBDrivers {
+		if config.HistoryDBDriver == driver {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, fmt.Errorf("unsupported HISTORY_DB_DRIVER '%s' (supported: %s)", config.HistoryDBDriver, strings.Join(supportedHistoryDBDrivers, ", "))
+	}
+
+	db, err := sql.Open(config.HistoryDBDriver, config.HistoryDBDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s history database: %v", config.HistoryDBDriver, err)
+	}
+	if config.HistoryDBDriver == "sqlite" {
+		// SQLite only supports a single writer; serialize access instead of failing with SQLITE_BUSY
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s history database: %v", config.HistoryDBDriver, err)
+	}
+	return db, nil
+}
+
+// newExecutionStore opens the history database and brings its schema up to date.
+// When auto-migration is disabled the schema must already be current (see the `migrate` subcommand).
+func newExecutionStore(config *Config) (*ExecutionStore, error) {
+	db, err := openHistoryDB(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var version int
+	if config.HistoryDBAutoMigrate {
+		version, err = migrateUpLocked(db, config.HistoryDBDriver)
+	} else {
+		version, err = currentSchemaVersion(db)
+		if err == nil && version < latestSchemaVersion() {
+			err = fmt.Errorf("history database schema is at version %d but version %d is required; run the 'migrate' subcommand", version, latestSchemaVersion())
+		}
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	logger.Info().Msgf("Execution history store ready (driver: %s, schema version: %d).", config.HistoryDBDriver, version)
+	return &ExecutionStore{db: db, dialect: config.HistoryDBDriver}, nil
+}
+
+// migrateUpLocked runs migrateUp while holding the Postgres advisory migration lock.
+// SQLite databases are local to a single replica and need no lock.
+func migrateUpLocked(db *sql.DB, dialect string) (int, error) {
+	if dialect != "postgres" {
+		return migrateUp(db, dialect)
+	}
+
+	// Advisory locks are held per session, so pin one connection for the lock's lifetime
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire connection for migration lock: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return 0, fmt.Errorf("failed to acquire migration lock: %v", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			logger.Error().Msgf("[Migrate] Failed to release migration lock: %v", err)
+		}
+	}()
+
+	return migrateUp(db, dialect)
+}
+
+// rebindQuery rewrites '?' placeholders into the positional form required by the dialect.
+func rebindQuery(dialect, query string) string {
+	if dialect != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// SchemaVersion returns the applied schema version, or 0 if history persistence is disabled.
+func (s *ExecutionStore) SchemaVersion() int {
+	if s == nil {
+		return 0
+	}
+	version, err := currentSchemaVersion(s.db)
+	if err != nil {
+		logger.Error().Msgf("[History] Failed to read schema version: %v", err)
+		return 0
+	}
+	return version
+}
+
+// RecordStart inserts a RUNNING execution record, labeled like the resources the execution creates.
+func (s *ExecutionStore) RecordStart(executionID, trackingID, taskName, caller string, def *ScriptDefinition) {
+	if s == nil {
+		return
+	}
+	labels, _ := json.Marshal(executionLabels(def, executionID, trackingID, caller))
+	_, err := s.db.Exec(rebindQuery(s.dialect,
+		`INSERT INTO executions (id, tracking_id, script_id, script_name, script_version, task_name, caller, labels, status, started_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		executionID, trackingID, def.ID, def.Name, def.Version, taskName, caller, string(labels), executionStatusRunning, time.Now().UnixMilli())
+	if err != nil {
+		logger.Error().Str("trackingId", trackingID).Msgf("[History] Failed to record start of execution %s: %v", executionID, err)
+	}
+}
+
+// CountExecutions counts executions started since the given time, filtered by script name and/or
+// caller when they are non-empty. Used to enforce quotas across restarts and replicas.
+func (s *ExecutionStore) CountExecutions(scriptName, caller string, since time.Time) (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+	query := `SELECT COUNT(*) FROM executions WHERE started_at >= ?`
+	args := []interface{}{since.UnixMilli()}
+	if scriptName != "" {
+		query += ` AND script_name = ?`
+		args = append(args, scriptName)
+	}
+	if caller != "" {
+		query += ` AND caller = ?`
+		args = append(args, caller)
+	}
+	var count int
+	if err := s.db.QueryRow(rebindQuery(s.dialect, query), args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count executions: %v", err)
+	}
+	return count, nil
+}
+
+// LastSuccessfulExecution returns the most recent successful execution of a script that finished
+// at or after since, or nil if there is none.
+func (s *ExecutionStore) LastSuccessfulExecution(scriptName string, since time.Time) (*previousExecution, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var previous previousExecution
+	var finishedAt int64
+	err := s.db.QueryRow(rebindQuery(s.dialect,
+		`SELECT id, process_id, finished_at FROM executions WHERE script_name = ? AND status = ? AND finished_at >= ? ORDER BY finished_at DESC LIMIT 1`),
+		scriptName, executionStatusSuccessful, since.UnixMilli()).Scan(&previous.ExecutionID, &previous.ProcessID, &finishedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	previous.FinishedAt = time.UnixMilli(finishedAt).UTC()
+	return &previous, nil
+}
+
+// RecordProcessID stores the numeric Process Tracking ID once it is known.
+func (s *ExecutionStore) RecordProcessID(executionID string, processID int64) {
+	if s == nil {
+		return
+	}
+	_, err := s.db.Exec(rebindQuery(s.dialect, `UPDATE executions SET process_id = ? WHERE id = ?`), processID, executionID)
+	if err != nil {
+		logger.Error().Msgf("[History] Failed to record ProcessID %d for execution %s: %v", processID, executionID, err)
+	}
+}
+
+// RecordEnvReport stores the debug-mode environment report of an execution.
+func (s *ExecutionStore) RecordEnvReport(executionID, reportJSON string) {
+	if s == nil {
+		return
+	}
+	_, err := s.db.Exec(rebindQuery(s.dialect, `UPDATE executions SET env_report = ? WHERE id = ?`), reportJSON, executionID)
+	if err != nil {
+		logger.Error().Msgf("[History] Failed to record environment report for execution %s: %v", executionID, err)
+	}
+}
+
+// RecordRequest stores the request an execution was started with, sensitive values redacted, and
+// the execution it re-runs, if any, so it can be re-run in turn.
+func (s *ExecutionStore) RecordRequest(executionID string, request TaskServiceRequest, rerunOf string) {
+	if s == nil {
+		return
+	}
+	requestJSON, err := json.Marshal(request)
+	if err == nil {
+		_, err = s.db.Exec(rebindQuery(s.dialect, `UPDATE executions SET request = ?, rerun_of = ? WHERE id = ?`), string(requestJSON), rerunOf, executionID)
+	}
+	if err != nil {
+		logger.Error().Msgf("[History] Failed to record request of execution %s: %v", executionID, err)
+	}
+}
+
+// ExecutionRequest returns the stored request of an execution, or nil if none was recorded
+// (executions recorded before requests were).
+func (s *ExecutionStore) ExecutionRequest(executionID string) (*TaskServiceRequest, error) {
+	if s == nil {
+		return nil, nil
+	}
+	var requestJSON string
+	err := s.db.QueryRow(rebindQuery(s.dialect, `SELECT request FROM executions WHERE id = ?`), executionID).Scan(&requestJSON)
+	if err == sql.ErrNoRows || (err == nil && requestJSON == "") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request of execution '%s': %v", executionID, err)
+	}
+	var request TaskServiceRequest
+	if err := json.Unmarshal([]byte(requestJSON), &request); err != nil {
+		return nil, fmt.Errorf("failed to decode request of execution '%s': %v", executionID, err)
+	}
+	return &request, nil
+}
+
+// RecordFinish sets the final status, output and error of an execution.
+func (s *ExecutionStore) RecordFinish(executionID, targetPod, status, output, errMsg string) {
+	if s == nil {
+		return
+	}
+	_, err := s.db.Exec(rebindQuery(s.dialect,
+		`UPDATE executions SET target_pod = ?, status = ?, output = ?, error = ?, finished_at = ? WHERE id = ?`),
+		targetPod, status, output, errMsg, time.Now().UnixMilli(), executionID)
+	if err != nil {
+		logger.Error().Msgf("[History] Failed to record finish of execution %s: %v", executionID, err)
+	}
+}
+
+// RecordTermination stores how a stopped execution's script was terminated, one of the
+// termination* values.
+func (s *ExecutionStore) RecordTermination(executionID, termination string) {
+	if s == nil {
+		return
+	}
+	_, err := s.db.Exec(rebindQuery(s.dialect, `UPDATE executions SET termination = ? WHERE id = ?`), termination, executionID)
+	if err != nil {
+		logger.Error().Msgf("[History] Failed to record termination of execution %s: %v", executionID, err)
+	}
+}
+
+// RecordFailureCategory stores the category of a failed execution, one of the failure* values.
+func (s *ExecutionStore) RecordFailureCategory(executionID, category string) {
+	if s == nil {
+		return
+	}
+	_, err := s.db.Exec(rebindQuery(s.dialect, `UPDATE executions SET failure_category = ? WHERE id = ?`), category, executionID)
+	if err != nil {
+		logger.Error().Msgf("[History] Failed to record failure category of execution %s: %v", executionID, err)
+	}
+}
+
+// RecordTrackingCreate makes an execution its own process record (built-in process tracking,
+// used when PROCESS_TRACKING_SERVICE_URL is not set).
+func (s *ExecutionStore) RecordTrackingCreate(executionID string, processID int64, stage string) error {
+	if s == nil {
+		return nil
+	}
+	_, err := s.db.Exec(rebindQuery(s.dialect,
+		`UPDATE executions SET process_id = ?, tracking_stage = ?, tracking_status = ?, tracking_updated_at = ? WHERE id = ?`),
+		processID, stage, "CREATED", time.Now().UnixMilli(), executionID)
+	return err
+}
+
+// RecordTrackingUpdate stores a status update of a built-in process record.
+func (s *ExecutionStore) RecordTrackingUpdate(processID int64, status, message string) error {
+	if s == nil {
+		return nil
+	}
+	_, err := s.db.Exec(rebindQuery(s.dialect,
+		`UPDATE executions SET tracking_status = ?, tracking_message = ?, tracking_updated_at = ? WHERE process_id = ?`),
+		status, message, time.Now().UnixMilli(), processID)
+	return err
+}
+
+// ExecutionRecord is an execution as recorded in the history store
+type ExecutionRecord struct {
+	ID            string            `json:"id"`
+	TrackingID    string            `json:"trackingId"`
+	ProcessID     int64             `json:"processId,omitempty"`
+	Script        string            `json:"script"`
+	ScriptVersion string            `json:"scriptVersion,omitempty"`
+	TaskName      string            `json:"taskName,omitempty"`
+	Caller        string            `json:"caller,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`  // Same as on the resources the execution created
+	RerunOf       string            `json:"rerunOf,omitempty"` // Execution this one re-ran
+	TargetPod     string            `json:"targetPod,omitempty"`
+	Status        string            `json:"status"`
+	Output        string            `json:"output,omitempty"` // Only when fetching a single execution
+	Error         string            `json:"error,omitempty"`
+	Category      string            `json:"category,omitempty"`    // Failure category of a failed execution, see failure*
+	Termination   string            `json:"termination,omitempty"` // How the script was stopped, if it was
+	StartedAt     time.Time         `json:"startedAt"`
+	FinishedAt    *time.Time        `json:"finishedAt,omitempty"`
+	// Built-in process tracking state; empty when a process tracking service is used
+	TrackingStage     string     `json:"trackingStage,omitempty"`
+	TrackingStatus    string     `json:"trackingStatus,omitempty"`
+	TrackingMessage   string     `json:"trackingMessage,omitempty"`
+	TrackingUpdatedAt *time.Time `json:"trackingUpdatedAt,omitempty"`
+}
+
+// executionColumns are the columns scanned by scanExecution, output last
+const executionColumns = `id, tracking_id, process_id, script_name, script_version, task_name, caller, labels, rerun_of, target_pod, status, error,
+	failure_category, termination, started_at, finished_at, tracking_stage, tracking_status, tracking_message, tracking_updated_at`
+
+// scanExecution reads a row selected with executionColumns, plus output if withOutput is set.
+func scanExecution(scanner interface{ Scan(...interface{}) error }, withOutput bool) (ExecutionRecord, error) {
+	var record ExecutionRecord
+	var startedAt, finishedAt, trackingUpdatedAt int64
+	var labels string
+	dest := []interface{}{&record.ID, &record.TrackingID, &record.ProcessID, &record.Script, &record.ScriptVersion, &record.TaskName,
+		&record.Caller, &labels, &record.RerunOf, &record.TargetPod, &record.Status, &record.Error, &record.Category, &record.Termination, &startedAt, &finishedAt,
+		&record.TrackingStage, &record.TrackingStatus, &record.TrackingMessage, &trackingUpdatedAt}
+	if withOutput {
+		dest = append(dest, &record.Output)
+	}
+	if err := scanner.Scan(dest...); err != nil {
+		return record, err
+	}
+	if labels != "" {
+		if err := json.Unmarshal([]byte(labels), &record.Labels); err != nil {
+			return record, fmt.Errorf("failed to decode labels: %v", err)
+		}
+	}
+	record.StartedAt = time.UnixMilli(startedAt).UTC()
+	if finishedAt > 0 {
+		finished := time.UnixMilli(finishedAt).UTC()
+		record.FinishedAt = &finished
+	}
+	if trackingUpdatedAt > 0 {
+		updated := time.UnixMilli(trackingUpdatedAt).UTC()
+		record.TrackingUpdatedAt = &updated
+	}
+	return record, nil
+}
+
+// GetExecution returns an execution, including its output, by execution ID or numeric process ID.
+// It returns nil if there is no such execution.
+func (s *ExecutionStore) GetExecution(id string) (*ExecutionRecord, error) {
+	if s == nil {
+		return nil, nil
+	}
+	processID, _ := strconv.ParseInt(id, 10, 64)
+	row := s.db.QueryRow(rebindQuery(s.dialect,
+		`SELECT `+executionColumns+`, output FROM executions WHERE id = ? OR (process_id <> 0 AND process_id = ?) ORDER BY started_at DESC LIMIT 1`),
+		id, processID)
+	record, err := scanExecution(row, true)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution '%s': %v", id, err)
+	}
+	return &record, nil
+}
+
+// executionQuery filters the execution list
+type executionQuery struct {
+	Script     string
+	TrackingID string
+	Status     string
+	Category   string
+	Scripts    []string // Unless nil, only executions of these scripts: those the caller's tag restriction admits
+	Limit      int
+}
+
+// ListExecutions returns matching executions, most recent first, without their output.
+func (s *ExecutionStore) ListExecutions(query executionQuery) ([]ExecutionRecord, error) {
+	if s == nil {
+		return []ExecutionRecord{}, nil
+	}
+	sqlQuery := `SELECT ` + executionColumns + ` FROM executions WHERE 1 = 1`
+	var args []interface{}
+	if query.Script != "" {
+		sqlQuery += ` AND script_name = ?`
+		args = append(args, query.Script)
+	}
+	if query.TrackingID != "" {
+		sqlQuery += ` AND tracking_id = ?`
+		args = append(args, query.TrackingID)
+	}
+	if query.Status != "" {
+		sqlQuery += ` AND status = ?`
+		args = append(args, query.Status)
+	}
+	if query.Category != "" {
+		sqlQuery += ` AND failure_category = ?`
+		args = append(args, query.Category)
+	}
+	if query.Scripts != nil {
+		sqlQuery += ` AND script_name IN (NULL` + strings.Repeat(", ?", len(query.Scripts)) + `)`
+		for _, script := range query.Scripts {
+			args = append(args, script)
+		}
+	}
+	sqlQuery += fmt.Sprintf(` ORDER BY started_at DESC LIMIT %d`, query.Limit)
+
+	rows, err := s.db.Query(rebindQuery(s.dialect, sqlQuery), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query executions: %v", err)
+	}
+	defer rows.Close()
+	records := []ExecutionRecord{}
+	for rows.Next() {
+		record, err := scanExecution(rows, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read execution: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
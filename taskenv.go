@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// baseEnvVar is an environment variable every execution of a script gets, before its parameters
+type baseEnvVar struct {
+	Name  string
+	Value string
+}
+
+// shellQuote single-quotes a value as one shell word, so the shell expands nothing in it ($(...),
+// backticks, variables). An embedded single quote closes the quoting, is escaped and reopens it.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// envAssignment renders an environment variable as a command prefix assignment, NAME='value'.
+func envAssignment(name, value string) string {
+	return name + "=" + shellQuote(value)
+}
+
+// lastRunTimeMillisThreshold tells epoch seconds from milliseconds in lastRunTime: as seconds, it
+// would be past the year 5000.
+const lastRunTimeMillisThreshold = 100_000_000_000
+
+// taskContextEnv returns the variables every execution gets, so scripts can implement "since the
+// last run" without the caller duplicating lastRunTime into taskData. Values that are unknown (no
+// process record, no previous run) are empty rather than unset, for scripts running with set -u.
+func taskContextEnv(request TaskServiceRequest, executionID, trackingID string, processID int64) []baseEnvVar {
+	processIDValue := ""
+	if processID > 0 {
+		processIDValue = strconv.FormatInt(processID, 10)
+	}
+	lastRunEpoch, lastRunRFC3339 := "", ""
+	if request.LastRunTime > 0 {
+		lastRun := time.Unix(request.LastRunTime, 0)
+		if request.LastRunTime >= lastRunTimeMillisThreshold {
+			lastRun = time.UnixMilli(request.LastRunTime)
+		}
+		lastRunEpoch = strconv.FormatInt(lastRun.Unix(), 10)
+		lastRunRFC3339 = lastRun.UTC().Format(time.RFC3339)
+	}
+	return []baseEnvVar{
+		{"TASK_NAME", request.TaskName},
+		{"EXECUTION_ID", executionID},
+		{"TRACKING_ID", trackingID},
+		{"PROCESS_ID", processIDValue},
+		{"LAST_RUN_TIME", lastRunEpoch},
+		{"LAST_RUN_TIME_RFC3339", lastRunRFC3339},
+	}
+}
+
+// staticEnv returns the "env" of a script definition, sorted by name so the rendered command is stable.
+func staticEnv(def *ScriptDefinition) []baseEnvVar {
+	vars := make([]baseEnvVar, 0, len(def.Env))
+	for name, value := range def.Env {
+		vars = append(vars, baseEnvVar{name, value})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+	return vars
+}
+
+// validateStaticEnv checks the "env" field of a script definition.
+func validateStaticEnv(def *ScriptDefinition) error {
+	for name := range def.Env {
+		if !isValidEnvVarName(name) {
+			return fmt.Errorf("'%s' is not a valid environment variable name", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestEnvAssignmentExpandsNothing(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"plain", "nightly restore"},
+		{"command substitution", "$(id)"},
+		{"backticks", "`id`"},
+		{"variable", "$HOME"},
+		{"single quote", "it's"},
+		{"quote breakout", "'; id; echo '"},
+		{"double quotes", `say "hi"`},
+		{"backslashes", `C:\temp\n`},
+		{"newline", "first\nsecond"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command := envAssignment("TASK_NAME", tt.value) + " printenv TASK_NAME"
+			// As bash -c receives it from kubectl exec or the local backend
+			output, err := exec.Command("bash", "-c", command).Output()
+			if err != nil {
+				t.Fatalf("bash -c %s: %v", command, err)
+			}
+			if string(output) != tt.value+"\n" {
+				t.Errorf("TASK_NAME = %q, want %q", output, tt.value+"\n")
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "''"},
+		{"abc", "'abc'"},
+		{"$(id)", "'$(id)'"},
+		{"it's", `'it'\''s'`},
+		{"''", `''\'''\'''`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.value); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
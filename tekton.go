@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// kubeDynamicClient is used for custom resources such as Tekton PipelineRuns, set in main
+var kubeDynamicClient dynamic.Interface
+
+// pipelineRunResource is the Tekton PipelineRun custom resource
+var pipelineRunResource = schema.GroupVersionResource{Group: "tekton.dev", Version: "v1", Resource: "pipelineruns"}
+
+// tektonPollInterval is how often a PipelineRun's status is checked
+const tektonPollInterval = 5 * time.Second
+
+// runTektonPipeline creates a PipelineRun of the script's Tekton Pipeline with the request parameters
+// as Pipeline params, then polls it until the Succeeded condition is resolved. This bridges Task
+// Service tasks to existing Tekton pipelines. It returns the PipelineRun name and a status summary.
+func runTektonPipeline(run *executorRun) (string, string, error) {
+	config, def, params := run.Config, run.Definition, run.Params
+	if kubeDynamicClient == nil {
+		return "", "", fmt.Errorf("kubernetes dynamic client not initialized")
+	}
+	namespace := def.TektonNamespace
+	if namespace == "" {
+		namespace = config.Namespace
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.TektonTimeout)
+	defer cancel()
+	pipelineRuns := kubeDynamicClient.Resource(pipelineRunResource).Namespace(namespace)
+
+	pipelineParams := make([]interface{}, 0, len(params))
+	for _, param := range def.Parameters {
+		if value, ok := params[param.Name]; ok {
+			pipelineParams = append(pipelineParams, map[string]interface{}{"name": param.Name, "value": value})
+		}
+	}
+
+	labels := make(map[string]interface{})
+	for key, value := range ownerLabels(run) {
+		labels[key] = value
+	}
+	annotations := make(map[string]interface{})
+	for key, value := range ownerAnnotations(run) {
+		annotations[key] = value
+	}
+	pipelineRun := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "tekton.dev/v1",
+		"kind":       "PipelineRun",
+		"metadata": map[string]interface{}{
+			"generateName": def.TektonPipeline + "-",
+			"namespace":    namespace,
+			"labels":       labels,
+			"annotations":  annotations,
+		},
+		"spec": map[string]interface{}{
+			"pipelineRef": map[string]interface{}{"name": def.TektonPipeline},
+			"params":      pipelineParams,
+		},
+	}}
+
+	created, err := pipelineRuns.Create(ctx, pipelineRun, metav1.CreateOptions{})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create PipelineRun for pipeline '%s' (namespace: %s): %v", def.TektonPipeline, namespace, err)
+	}
+	runName := created.GetName()
+	logger.Info().Msgf("Created PipelineRun '%s' (namespace: %s) for script '%s'.", runName, namespace, def.Name)
+
+	for {
+		current, err := pipelineRuns.Get(ctx, runName, metav1.GetOptions{})
+		if err != nil {
+			return runName, "", fmt.Errorf("failed to get PipelineRun '%s': %v", runName, err)
+		}
+		conditions, _, _ := unstructured.NestedSlice(current.Object, "status", "conditions")
+		for _, raw := range conditions {
+			condition, ok := raw.(map[string]interface{})
+			if !ok || condition["type"] != "Succeeded" {
+				continue
+			}
+			summary := fmt.Sprintf("PipelineRun %s/%s: %v: %v", namespace, runName, condition["reason"], condition["message"])
+			switch condition["status"] {
+			case "True":
+				return runName, summary, nil
+			case "False":
+				return runName, summary, fmt.Errorf("PipelineRun '%s' failed: %v", runName, condition["reason"])
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return runName, "", fmt.Errorf("timed out waiting for PipelineRun '%s': %v", runName, ctx.Err())
+		case <-time.After(tektonPollInterval):
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tlsReloadCheckInterval is how often the certificate files are checked for changes
+const tlsReloadCheckInterval = 30 * time.Second
+
+// reloadingTLSFiles serves the server certificate and client CA pool from files and reloads them
+// when they change on disk (e.g. a cert-manager Secret rotation), without restarting the server.
+type reloadingTLSFiles struct {
+	certFile, keyFile, clientCAFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+	checked   time.Time
+}
+
+// newReloadingTLSFiles loads the certificate (and client CA, if configured) for the first time.
+func newReloadingTLSFiles(certFile, keyFile, clientCAFile string) (*reloadingTLSFiles, error) {
+	files := &reloadingTLSFiles{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	if err := files.load(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// load reads every file and swaps in the new certificate and CA pool.
+func (f *reloadingTLSFiles) load() error {
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate '%s' / key '%s': %v", f.certFile, f.keyFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if f.clientCAFile != "" {
+		pemData, err := os.ReadFile(f.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS client CA '%s': %v", f.clientCAFile, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pemData) {
+			return fmt.Errorf("TLS client CA '%s' contains no PEM certificates", f.clientCAFile)
+		}
+	}
+
+	f.mu.Lock()
+	f.cert = &cert
+	f.clientCAs = clientCAs
+	f.modTimes = f.currentModTimes()
+	f.checked = time.Now()
+	f.mu.Unlock()
+	return nil
+}
+
+// currentModTimes returns the modification times of the configured files.
+func (f *reloadingTLSFiles) currentModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{f.certFile, f.keyFile, f.clientCAFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// reloadIfChanged reloads the files if any changed since the last load, checking at most every
+// tlsReloadCheckInterval. A failed reload keeps serving the previous certificate.
+func (f *reloadingTLSFiles) reloadIfChanged() {
+	f.mu.RLock()
+	due := time.Since(f.checked) >= tlsReloadCheckInterval
+	previous := f.modTimes
+	f.mu.RUnlock()
+	if !due {
+		return
+	}
+
+	changed := false
+	for file, modTime := range f.currentModTimes() {
+		if !modTime.Equal(previous[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		f.mu.Lock()
+		f.checked = time.Now()
+		f.mu.Unlock()
+		return
+	}
+	if err := f.load(); err != nil {
+		logger.Warn().Msgf("TLS certificate files changed but could not be reloaded, keeping the previous certificate: %v", err)
+		f.mu.Lock()
+		f.checked = time.Now()
+		f.mu.Unlock()
+		return
+	}
+	logger.Info().Msgf("Reloaded TLS certificate from '%s'.", f.certFile)
+}
+
+// tlsConfig returns a server TLS configuration that picks up reloaded files on new handshakes.
+// With a client CA, clients must present a certificate signed by it (mTLS) unless clientAuth is
+// "optional", in which case certificates are verified only when presented.
+func (f *reloadingTLSFiles) tlsConfig(clientAuth string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			f.reloadIfChanged()
+			f.mu.RLock()
+			defer f.mu.RUnlock()
+			config := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{*f.cert}}
+			if f.clientCAs != nil {
+				config.ClientCAs = f.clientCAs
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				if clientAuth == "optional" {
+					config.ClientAuth = tls.VerifyClientCertIfGiven
+				}
+			}
+			return config, nil
+		},
+	}
+}
+
+// authenticateClientCertificate is middleware that takes the caller identity from a verified mTLS
+// client certificate: the subject common name is the caller name and the organizations its groups,
+// following the Kubernetes client certificate convention.
+func authenticateClientCertificate(c *gin.Context) {
+	if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0 {
+		subject := c.Request.TLS.VerifiedChains[0][0].Subject
+		if subject.CommonName != "" {
+			c.Set(callerContextKey, Caller{Name: subject.CommonName, Groups: subject.Organization})
+		}
+	}
+	c.Next()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// serviceAccountUserPrefix starts the usernames of ServiceAccounts
+const serviceAccountUserPrefix = "system:serviceaccount:"
+
+// tokenReviewResult is a cached TokenReview outcome
+type tokenReviewResult struct {
+	caller        Caller
+	authenticated bool
+	expires       time.Time
+}
+
+// tokenReviewCache caches TokenReview outcomes by token digest, so callers sending the same
+// ServiceAccount token on every request don't cost an API server round trip each time.
+type tokenReviewCache struct {
+	mu      sync.Mutex
+	results map[string]tokenReviewResult
+}
+
+var tokenReviews = &tokenReviewCache{results: make(map[string]tokenReviewResult)}
+
+// review returns the identity of a bearer token, asking the API server through a TokenReview when
+// the token isn't cached.
+func (t *tokenReviewCache) review(config *Config, token string) (Caller, bool, error) {
+	digest := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(digest[:])
+
+	t.mu.Lock()
+	cached, found := t.results[cacheKey]
+	if found && time.Now().After(cached.expires) {
+		delete(t.results, cacheKey)
+		found = false
+	}
+	t.mu.Unlock()
+	if found {
+		return cached.caller, cached.authenticated, nil
+	}
+
+	review := &authenticationv1.TokenReview{Spec: authenticationv1.TokenReviewSpec{Token: token, Audiences: config.TokenReviewAudiences}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := kubeClient.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return Caller{}, false, err
+	}
+
+	caller := Caller{Name: result.Status.User.Username, Groups: result.Status.User.Groups,
+		ServiceAccount: strings.HasPrefix(result.Status.User.Username, serviceAccountUserPrefix)}
+	authenticated := result.Status.Authenticated
+	if !authenticated {
+		logger.Warn().Msgf("TokenReview rejected bearer token: %s", result.Status.Error)
+	}
+
+	t.mu.Lock()
+	// Drop expired entries while we hold the lock so the cache can't grow without bound
+	now := time.Now()
+	for key, entry := range t.results {
+		if now.After(entry.expires) {
+			delete(t.results, key)
+		}
+	}
+	t.results[cacheKey] = tokenReviewResult{caller: caller, authenticated: authenticated, expires: now.Add(config.TokenReviewCacheTTL)}
+	t.mu.Unlock()
+	return caller, authenticated, nil
+}
+
+// authenticateServiceAccountToken is middleware that authenticates in-cluster callers (like the
+// Task Service) by their ServiceAccount token, validated with the Kubernetes TokenReview API. The
+// caller becomes the ServiceAccount's username (system:serviceaccount:<namespace>:<name>) and
+// groups (e.g. system:serviceaccounts:<namespace>), which allowedCallers/allowedGroups and policies
+// can refer to. The trigger endpoint is skipped, as its bearer token is TRIGGER_TOKEN.
+func authenticateServiceAccountToken(c *gin.Context) {
+	config := configFromContext(c)
+	authorization := c.GetHeader("Authorization")
+	if !config.TokenReviewEnabled || !strings.HasPrefix(authorization, "Bearer ") || strings.HasPrefix(c.FullPath(), "/v1/trigger/") {
+		c.Next()
+		return
+	}
+
+	caller, authenticated, err := tokenReviews.review(config, strings.TrimPrefix(authorization, "Bearer "))
+	if err != nil {
+		logger.Error().Msgf("TokenReview failed for request to %s from %s: %v", c.FullPath(), c.ClientIP(), err)
+		writeJSON(c, http.StatusServiceUnavailable, problem(http.StatusServiceUnavailable, codeInternal, "Failed to validate bearer token"))
+		c.Abort()
+		return
+	}
+	if !authenticated {
+		writeJSON(c, http.StatusUnauthorized, problem(http.StatusUnauthorized, codeUnauthenticated, "Invalid bearer token"))
+		c.Abort()
+		return
+	}
+	c.Set(callerContextKey, caller)
+	c.Next()
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// How finishing status updates carry the execution outcome (PROCESS_TRACKING_METADATA)
+const (
+	trackingMetadataFields  = "fields"  // exitCode, durationSeconds and pod fields of the payload
+	trackingMetadataMessage = "message" // A "[exitCode=.. duration=.. pod=..]" line appended to the message, for services rejecting unknown fields
+	trackingMetadataOff     = "off"
+)
+
+// withOutcomeMetadata adds the exit code, duration and pod of a finished execution to a status
+// update, so tracking consumers don't have to parse them from the message text.
+func withOutcomeMetadata(config *Config, payload ProcessTrackingUpdatePayload, data trackingMessageData) ProcessTrackingUpdatePayload {
+	switch config.ProcessTrackingMetadata {
+	case trackingMetadataOff:
+		return payload
+	case trackingMetadataMessage:
+		payload.Message += fmt.Sprintf("\n[exitCode=%s duration=%s pod=%s]", data.ExitCode, data.Duration, data.Pod)
+		return payload
+	}
+	if exitCode, err := strconv.Atoi(data.ExitCode); err == nil {
+		payload.ExitCode = &exitCode
+	}
+	durationSeconds := data.Duration.Seconds()
+	payload.DurationSeconds = &durationSeconds
+	payload.Pod = data.Pod
+	return payload
+}
+
+// retryableTrackingStatus reports whether a process tracking response status is worth retrying:
+// throttling and server errors are, client errors (bad payload, unknown process) are not.
+func retryableTrackingStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+// trackingBackoff returns the wait before retry number attempt (1-based): exponential from
+// PROCESS_TRACKING_RETRY_BASE_MS, capped at PROCESS_TRACKING_RETRY_MAX_MS, with jitter over the
+// upper half so executions failing at the same moment don't retry in lockstep.
+func trackingBackoff(config *Config, attempt int) time.Duration {
+	backoff := config.ProcessTrackingRetryBase << uint(attempt-1)
+	if backoff <= 0 || backoff > config.ProcessTrackingRetryMax {
+		backoff = config.ProcessTrackingRetryMax
+	}
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// setProcessTrackingAuth adds the configured credentials to a process tracking request: a bearer
+// token or basic auth (the token wins if both are set), plus a Cookie header for services that
+// authorize through session cookies, e.g. "rights=1; rights_0=<cookie>".
+func setProcessTrackingAuth(req *http.Request, config *Config) {
+	switch {
+	case config.ProcessTrackingToken != "":
+		req.Header.Set("Authorization", "Bearer "+config.ProcessTrackingToken)
+	case config.ProcessTrackingUsername != "":
+		req.SetBasicAuth(config.ProcessTrackingUsername, config.ProcessTrackingPassword)
+	}
+	if config.ProcessTrackingCookie != "" {
+		req.Header.Set("Cookie", config.ProcessTrackingCookie)
+	}
+}
+
+// sendProcessTrackingRequest POSTs a JSON body to the process tracking service, retrying network
+// errors, 429 and 5xx responses up to PROCESS_TRACKING_MAX_ATTEMPTS times with backoff. It returns
+// the final response (its body already read and closed) and body. The request carries the trace
+// context of ctx but outlives a disconnecting caller.
+//
+// A create whose response was lost may be repeated; the tracking service receives the same
+// TrackingID again and is expected to treat it idempotently.
+func sendProcessTrackingRequest(ctx context.Context, config *Config, operation string, url string, body []byte) (*http.Response, []byte, error) {
+	xlog := executionLog(ctx)
+	maxAttempts := config.ProcessTrackingMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+		setProcessTrackingAuth(req, config)
+
+		resp, err := httpClient.Do(req)
+		var respBody []byte
+		if err == nil {
+			respBody, _ = io.ReadAll(resp.Body) // Read body for logging context
+			resp.Body.Close()
+			if !retryableTrackingStatus(resp.StatusCode) {
+				return resp, respBody, nil
+			}
+		}
+
+		if attempt >= maxAttempts {
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to send request after %d attempt(s): %w", attempt, err)
+			}
+			return resp, respBody, nil
+		}
+		reason := fmt.Sprintf("%v", err)
+		if err == nil {
+			reason = fmt.Sprintf("status %d", resp.StatusCode)
+		}
+		wait := trackingBackoff(config, attempt)
+		xlog.Warn().Msgf("[ProcessTracking %s] Attempt %d/%d failed (%s); retrying in %s.", operation, attempt, maxAttempts, reason, wait.Round(time.Millisecond))
+		time.Sleep(wait)
+	}
+}
+
+// deliverProcessTrackingUpdate POSTs a marshaled update payload to /{id} of the process tracking
+// service. On failure, retryable reports whether the update may still succeed later (network
+// errors, 429, 5xx) rather than being rejected for good.
+func deliverProcessTrackingUpdate(ctx context.Context, config *Config, numericProcessID int64, payload []byte) (retryable bool, err error) {
+	updateURL := strings.TrimSuffix(config.ProcessTrackingURL, "/") + "/" + strconv.FormatInt(numericProcessID, 10)
+	resp, body, err := sendProcessTrackingRequest(ctx, config, "UPDATE", updateURL, payload)
+	if err != nil {
+		return true, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return retryableTrackingStatus(resp.StatusCode), fmt.Errorf("expected status 200, got %d, body: %s", resp.StatusCode, string(body))
+	}
+	return false, nil
+}
+
+// configureProcessTrackingTLS sets up TLS of the process tracking client for services behind an
+// internal CA (PROCESS_TRACKING_CA_CERT, trusted in addition to the system roots) and/or requiring
+// a client certificate (PROCESS_TRACKING_CLIENT_CERT / PROCESS_TRACKING_CLIENT_KEY). The client
+// certificate is reloaded when its files change; a changed CA needs a restart.
+func configureProcessTrackingTLS(config *Config) error {
+	if config.ProcessTrackingCACert == "" && config.ProcessTrackingClientCert == "" {
+		return nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.ProcessTrackingCACert != "" {
+		pemData, err := os.ReadFile(config.ProcessTrackingCACert)
+		if err != nil {
+			return fmt.Errorf("failed to read PROCESS_TRACKING_CA_CERT '%s': %v", config.ProcessTrackingCACert, err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pemData) {
+			return fmt.Errorf("PROCESS_TRACKING_CA_CERT '%s' contains no PEM certificates", config.ProcessTrackingCACert)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if config.ProcessTrackingClientCert != "" {
+		if config.ProcessTrackingClientKey == "" {
+			return fmt.Errorf("PROCESS_TRACKING_CLIENT_CERT requires PROCESS_TRACKING_CLIENT_KEY")
+		}
+		clientFiles, err := newReloadingTLSFiles(config.ProcessTrackingClientCert, config.ProcessTrackingClientKey, "")
+		if err != nil {
+			return err
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			clientFiles.reloadIfChanged()
+			clientFiles.mu.RLock()
+			defer clientFiles.mu.RUnlock()
+			return clientFiles.cert, nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	httpClient.Transport = transport
+	return nil
+}
+
+// createBuiltinProcessRecord starts built-in process tracking, used when no tracking service is
+// configured: the execution record in the history store doubles as the process record, with a
+// numeric ProcessID derived from the execution ID, so status is visible through GET /v1/executions.
+// Without a history store the execution runs untracked.
+func createBuiltinProcessRecord(ctx context.Context, executionID string, payload ProcessTrackingCreatePayload) (int64, error) {
+	xlog := executionLog(ctx)
+	if executionStore == nil {
+		xlog.Info().Msgf("[ProcessTracking CREATE] Neither PROCESS_TRACKING_SERVICE_URL nor HISTORY_DB_DSN is set; execution of '%s' is not tracked.", payload.Name)
+		return 0, nil
+	}
+	processID, err := builtinProcessID(executionID)
+	if err != nil {
+		return 0, err
+	}
+	if err := executionStore.RecordTrackingCreate(executionID, processID, payload.Stage); err != nil {
+		return 0, fmt.Errorf("failed to create built-in process record: %w", err)
+	}
+	xlog.Info().Msgf("[ProcessTracking CREATE] Tracking execution in the history store (built-in tracking), ProcessID %d.", processID)
+	return processID, nil
+}
+
+// builtinProcessID derives the numeric ProcessID of a built-in process record from its UUIDv7
+// execution ID: the millisecond timestamp followed by 21 of its random bits. The IDs stay positive,
+// time-ordered, and above the nanosecond timestamps that identified earlier executions.
+func builtinProcessID(executionID string) (int64, error) {
+	id, err := uuid.Parse(executionID)
+	if err != nil || id.Version() != 7 {
+		return 0, fmt.Errorf("execution ID '%s' is not a UUIDv7", executionID)
+	}
+	millis := binary.BigEndian.Uint64(id[:8]) >> 16
+	random := binary.BigEndian.Uint32(id[12:]) & (1<<21 - 1)
+	return int64(millis<<21 | uint64(random)), nil
+}
+
+// updateBuiltinProcessRecord stores a status update of a built-in process record. Failures are
+// logged like those of the tracking service.
+func updateBuiltinProcessRecord(ctx context.Context, numericProcessID int64, payload ProcessTrackingUpdatePayload) {
+	xlog := executionLog(ctx)
+	if err := executionStore.RecordTrackingUpdate(numericProcessID, strings.ToUpper(payload.Status), payload.Message); err != nil {
+		xlog.Error().Msgf("[ProcessTracking UPDATE] Failed to store status '%s' of built-in ProcessID %d: %v", payload.Status, numericProcessID, err)
+		return
+	}
+	xlog.Debug().Msgf("[ProcessTracking UPDATE] Stored status '%s' of built-in ProcessID %d.", payload.Status, numericProcessID)
+}
+
+// trackingThrottleWindow is the window PROCESS_TRACKING_MAX_UPDATES_PER_MINUTE applies to
+const trackingThrottleWindow = time.Minute
+
+// progressThrottle rate-limits the PROGRESS updates of each execution, coalescing the excess
+type progressThrottle struct {
+	mu        sync.Mutex
+	processes map[int64]*processThrottle
+}
+
+// processThrottle is the throttling state of one process
+type processThrottle struct {
+	sent      []time.Time                   // Sends within the current window
+	pending   *ProcessTrackingUpdatePayload // Latest held-back update, replacing earlier ones
+	coalesced int                           // Updates replaced by pending
+	timer     *time.Timer                   // Sends pending once the window has room
+}
+
+// trackingThrottle is the process-wide PROGRESS update throttle
+var trackingThrottle = &progressThrottle{processes: make(map[int64]*processThrottle)}
+
+// allow reports whether an update may be sent now. PROGRESS updates beyond
+// PROCESS_TRACKING_MAX_UPDATES_PER_MINUTE per process are held back: the latest one is sent when the
+// window has room again and earlier ones are dropped. Final updates are always sent, discarding any
+// held-back PROGRESS so it can't arrive after them.
+func (t *progressThrottle) allow(ctx context.Context, config *Config, numericProcessID int64, payload ProcessTrackingUpdatePayload) bool {
+	limit := config.ProcessTrackingMaxUpdatesPerMinute
+	if limit <= 0 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.processes[numericProcessID]
+	if !strings.EqualFold(payload.Status, "PROGRESS") {
+		if state != nil {
+			if state.timer != nil {
+				state.timer.Stop()
+			}
+			if state.pending != nil {
+				executionLog(ctx).Debug().Msgf("[ProcessTracking UPDATE] Dropping held-back PROGRESS update of numeric ProcessID %d, superseded by status '%s'.", numericProcessID, payload.Status)
+			}
+			delete(t.processes, numericProcessID)
+		}
+		return true
+	}
+	if state == nil {
+		state = &processThrottle{}
+		t.processes[numericProcessID] = state
+	}
+
+	now := time.Now()
+	for len(state.sent) > 0 && now.Sub(state.sent[0]) >= trackingThrottleWindow {
+		state.sent = state.sent[1:]
+	}
+	if len(state.sent) < limit && state.pending == nil {
+		state.sent = append(state.sent, now)
+		return true
+	}
+
+	if state.pending != nil {
+		state.coalesced++
+	}
+	state.pending = &payload
+	if state.timer == nil {
+		wait := trackingThrottleWindow - now.Sub(state.sent[0])
+		ctx := context.WithoutCancel(ctx)
+		state.timer = time.AfterFunc(wait, func() { t.flush(ctx, config, numericProcessID) })
+	}
+	return false
+}
+
+// flush sends the held-back update of a process, unless a final update closed it meanwhile.
+func (t *progressThrottle) flush(ctx context.Context, config *Config, numericProcessID int64) {
+	t.mu.Lock()
+	state := t.processes[numericProcessID]
+	if state == nil || state.pending == nil {
+		t.mu.Unlock()
+		return
+	}
+	payload, coalesced := *state.pending, state.coalesced
+	state.pending, state.coalesced, state.timer = nil, 0, nil
+	t.mu.Unlock()
+
+	if coalesced > 0 {
+		executionLog(ctx).Info().Msgf("[ProcessTracking UPDATE] Coalesced %d PROGRESS update(s) of numeric ProcessID %d into the latest.", coalesced, numericProcessID)
+	}
+	notifyProcessTrackingUpdate(ctx, config, numericProcessID, payload)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// trackingOutputTailLines is how many trailing output lines {{.OutputTail}} holds
+const trackingOutputTailLines = 10
+
+// TrackingMessages replaces the process record name and status messages of a script with Go
+// text/templates, rendered with a trackingMessageData. Empty templates keep the default messages.
+type TrackingMessages struct {
+	Name      string `json:"name,omitempty"`      // Name of the process record; defaults to the request taskName
+	Started   string `json:"started,omitempty"`   // PROGRESS message when the execution starts; defaults to "Script execution starting"
+	Succeeded string `json:"succeeded,omitempty"` // SUCCESSFUL message; defaults to the output
+	Failed    string `json:"failed,omitempty"`    // FAILED message; defaults to the error followed by the output
+	Warning   string `json:"warning,omitempty"`   // Message of an exit code mapped to WARNING; defaults to the succeeded template
+	Skipped   string `json:"skipped,omitempty"`   // Message of an exit code mapped to SKIPPED; defaults to the succeeded template
+}
+
+// trackingMessageData is what tracking message templates are rendered with. Pod, Duration, ExitCode,
+// Error and the outputs are only known once the execution finished.
+type trackingMessageData struct {
+	Script     string
+	Version    string
+	TaskName   string
+	TrackingID string
+	RerunOf    string // Execution re-run by this one, if any
+	Stage      string
+	Pod        string
+	Duration   time.Duration
+	ExitCode   string // "0" on success, the script's exit status on failure, "unknown" if it never ran
+	Error      string
+	Output     string // Full output; prefer OutputTail, messages are truncated to 1000 characters
+	OutputTail string // Last lines of the output
+}
+
+// validateTrackingMessages checks the templates of a script definition.
+func validateTrackingMessages(def *ScriptDefinition) error {
+	if def.TrackingMessages == nil {
+		return nil
+	}
+	for kind, text := range def.TrackingMessages.templates() {
+		if text == "" {
+			continue
+		}
+		if _, err := template.New(kind).Parse(text); err != nil {
+			return fmt.Errorf("invalid '%s' template: %v", kind, err)
+		}
+	}
+	return nil
+}
+
+// templates returns the templates by their JSON field name.
+func (m *TrackingMessages) templates() map[string]string {
+	return map[string]string{"name": m.Name, "started": m.Started, "succeeded": m.Succeeded, "failed": m.Failed, "warning": m.Warning, "skipped": m.Skipped}
+}
+
+// templateOf returns the template of kind; "warning" and "skipped" fall back to "succeeded".
+func (m *TrackingMessages) templateOf(kind string) string {
+	text := m.templates()[kind]
+	if text == "" && (kind == "warning" || kind == "skipped") {
+		return m.Succeeded
+	}
+	return text
+}
+
+// render renders the template of kind ("name", "started", "succeeded", "failed", "warning" or
+// "skipped"), truncated to the tracking message limit. Without a template, or if it fails to
+// render, fallback is returned.
+func (m *TrackingMessages) render(ctx context.Context, kind string, data trackingMessageData, fallback string) string {
+	if m == nil || m.templateOf(kind) == "" {
+		return fallback
+	}
+	tmpl, err := template.New(kind).Parse(m.templateOf(kind))
+	if err != nil {
+		executionLog(ctx).Warn().Msgf("Invalid '%s' tracking message template, using the default message: %v", kind, err)
+		return fallback
+	}
+	var message bytes.Buffer
+	if err := tmpl.Execute(&message, data); err != nil {
+		executionLog(ctx).Warn().Msgf("Failed to render '%s' tracking message template, using the default message: %v", kind, err)
+		return fallback
+	}
+	return truncateHead(message.String(), maxProcessTrackingMessageLength)
+}
+
+// outputTail returns the last trackingOutputTailLines lines of an output, at most
+// maxProcessTrackingMessageLength characters.
+func outputTail(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > trackingOutputTailLines {
+		lines = lines[len(lines)-trackingOutputTailLines:]
+	}
+	tail := strings.Join(lines, "\n")
+	if len(tail) > maxProcessTrackingMessageLength {
+		tail = tail[len(tail)-maxProcessTrackingMessageLength:]
+	}
+	return tail
+}
+
+// How output is fitted into tracking messages (TRACKING_OUTPUT_MODE, or trackingOutput.mode per script)
+const (
+	trackingOutputHead       = "head"       // The first characters
+	trackingOutputTail       = "tail"       // The last characters, where errors usually are
+	trackingOutputErrors     = "errors"     // Only lines matching the error pattern, falling back to the tail
+	trackingOutputAttachment = "attachment" // The tail plus a link to the full, gzip-compressed output
+)
+
+// trackingTruncatedMarker marks where output was cut
+const trackingTruncatedMarker = "... (truncated)"
+
+// TrackingOutput configures how a script's output is fitted into tracking messages
+type TrackingOutput struct {
+	Mode         string `json:"mode,omitempty"`         // "head", "tail", "errors" or "attachment"; defaults to TRACKING_OUTPUT_MODE
+	ErrorPattern string `json:"errorPattern,omitempty"` // Regular expression of error lines ("errors" mode); defaults to TRACKING_OUTPUT_ERROR_PATTERN
+}
+
+// validateTrackingOutput checks the trackingOutput of a script definition.
+func validateTrackingOutput(def *ScriptDefinition) error {
+	if def.TrackingOutput == nil {
+		return nil
+	}
+	if def.TrackingOutput.Mode != "" && !isTrackingOutputMode(def.TrackingOutput.Mode) {
+		return fmt.Errorf("unsupported mode '%s' (supported: head, tail, errors, attachment)", def.TrackingOutput.Mode)
+	}
+	if def.TrackingOutput.ErrorPattern != "" {
+		if _, err := regexp.Compile(def.TrackingOutput.ErrorPattern); err != nil {
+			return fmt.Errorf("invalid errorPattern: %v", err)
+		}
+	}
+	return nil
+}
+
+// isTrackingOutputMode reports whether mode is a supported output mode.
+func isTrackingOutputMode(mode string) bool {
+	switch mode {
+	case trackingOutputHead, trackingOutputTail, trackingOutputErrors, trackingOutputAttachment:
+		return true
+	}
+	return false
+}
+
+// summarizeTrackingOutput fits the output of an execution into a tracking message according to
+// the script's or the global output mode.
+func summarizeTrackingOutput(ctx context.Context, config *Config, def *ScriptDefinition, output string, executionID string) string {
+	mode, errorPattern := config.TrackingOutputMode, config.TrackingOutputErrorPattern
+	if def.TrackingOutput != nil {
+		if def.TrackingOutput.Mode != "" {
+			mode = def.TrackingOutput.Mode
+		}
+		if def.TrackingOutput.ErrorPattern != "" {
+			errorPattern = def.TrackingOutput.ErrorPattern
+		}
+	}
+
+	switch mode {
+	case trackingOutputTail:
+		return truncateTail(output, maxProcessTrackingMessageLength)
+	case trackingOutputErrors:
+		pattern, err := regexp.Compile(errorPattern)
+		if err != nil {
+			executionLog(ctx).Warn().Msgf("Invalid tracking output error pattern '%s', sending the output tail: %v", errorPattern, err)
+			return truncateTail(output, maxProcessTrackingMessageLength)
+		}
+		var matched []string
+		for _, line := range strings.Split(output, "\n") {
+			if pattern.MatchString(line) {
+				matched = append(matched, line)
+			}
+		}
+		if len(matched) == 0 {
+			return truncateTail(output, maxProcessTrackingMessageLength)
+		}
+		return truncateTail(strings.Join(matched, "\n"), maxProcessTrackingMessageLength)
+	case trackingOutputAttachment:
+		if len(output) <= maxProcessTrackingMessageLength {
+			return output
+		}
+		link := outputAttachmentLink(config, executionID)
+		if link == "" {
+			executionLog(ctx).Warn().Msg("Tracking output mode 'attachment' needs HISTORY_DB_DSN and TRACKING_OUTPUT_ATTACHMENT_URL; sending the output tail.")
+			return truncateTail(output, maxProcessTrackingMessageLength)
+		}
+		reference := fmt.Sprintf("\nFull output (%d bytes, gzip): %s", len(output), link)
+		return truncateTail(output, maxProcessTrackingMessageLength-len(reference)) + reference
+	default:
+		return truncateHead(output, maxProcessTrackingMessageLength)
+	}
+}
+
+// outputAttachmentLink renders TRACKING_OUTPUT_ATTACHMENT_URL for an execution, or returns "" if the
+// output can't be served because the URL or the history store is not configured.
+func outputAttachmentLink(config *Config, executionID string) string {
+	if config.TrackingOutputAttachmentURL == "" || executionStore == nil {
+		return ""
+	}
+	return strings.ReplaceAll(config.TrackingOutputAttachmentURL, "{executionId}", executionID)
+}
+
+// truncateHead keeps the first limit bytes of text, cut at a character boundary.
+func truncateHead(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + trackingTruncatedMarker
+}
+
+// truncateTail keeps the last limit bytes of text, cut at a character boundary.
+func truncateTail(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := len(text) - limit
+	for cut < len(text) && !utf8.RuneStart(text[cut]) {
+		cut++
+	}
+	return "(truncated) ..." + text[cut:]
+}
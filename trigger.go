@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Query parameters of /v1/trigger that configure the request itself rather than being script parameters
+const (
+	triggerParamTrackingID = "trackingId"
+	triggerParamTaskName   = "taskName"
+	triggerParamVersion    = "version"
+	triggerParamStage      = "stage"
+)
+
+// triggerScript handles GET/POST /v1/trigger/:script.
+// It is a minimal, token-authenticated alternative to /v1/execute intended to be curled from a
+// Kubernetes CronJob: query parameters (and form fields for POST) become the script's parameters.
+// Runs started here belong to triggerCaller, so allowedCallers/allowedGroups and OPA policies apply to them.
+//
+//	curl -fsS -H "Authorization: Bearer $TRIGGER_TOKEN" \
+//	  "http://script-executor/v1/trigger/nightly-restore?START_DATE=2024-01-01"
+func triggerScript(c *gin.Context) {
+	config := configFromContext(c)
+	if config.TriggerToken == "" {
+		writeJSON(c, http.StatusForbidden, problem(http.StatusForbidden, codeNotEnabled, "Trigger endpoint is disabled: TRIGGER_TOKEN is not configured"))
+		return
+	}
+
+	// Compare in constant time so the token can't be recovered through response timing
+	presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(presented), []byte(config.TriggerToken)) != 1 {
+		logger.Warn().Msgf("Rejected trigger request for script '%s' from %s: invalid or missing token", c.Param("script"), c.ClientIP())
+		writeJSON(c, http.StatusUnauthorized, problem(http.StatusUnauthorized, codeUnauthenticated, "Invalid or missing trigger token"))
+		return
+	}
+
+	// Resolve the path segment as a script ID first (URL friendly), then as a script name
+	scriptName := c.Param("script")
+	if registry, err := currentScripts(config.ScriptsPath); err == nil {
+		if def := registry.findByID(scriptName); def != nil {
+			scriptName = def.Name
+		}
+	}
+
+	// Query parameters (GET) and form fields (POST) map to script parameters; the first value wins
+	if err := c.Request.ParseForm(); err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, "Invalid query or form parameters: "+err.Error()))
+		return
+	}
+	taskData := map[string]interface{}{"name": scriptName}
+	for key, values := range c.Request.Form {
+		if key == triggerParamTrackingID || key == triggerParamTaskName || key == triggerParamVersion || key == triggerParamStage || key == "name" || len(values) == 0 {
+			continue
+		}
+		taskData[key] = values[0]
+	}
+
+	request := TaskServiceRequest{
+		TaskName:   c.Request.Form.Get(triggerParamTaskName),
+		TrackingID: c.Request.Form.Get(triggerParamTrackingID),
+		TaskData:   taskData,
+		Version:    c.Request.Form.Get(triggerParamVersion),
+		Stage:      c.Request.Form.Get(triggerParamStage),
+	}
+	if request.TaskName == "" {
+		request.TaskName = scriptName
+	}
+
+	logger.Info().Msgf("Received trigger request for script '%s' from %s with %d parameter(s).", scriptName, c.ClientIP(), len(taskData)-1)
+	writeOutcome(c, runTask(c.Request.Context(), config, request, executionOptions{Caller: triggerCaller, ClientIP: c.ClientIP()}))
+}
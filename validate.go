@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"sigs.k8s.io/yaml"
+)
+
+// validateScripts handles POST /v1/scripts/validate: it validates a candidate definitions file
+// sent as the body, JSON or (with a YAML content type) YAML, exactly as a reload would, including
+// the command policy, and returns the errors of every invalid definition. Nothing is loaded, so
+// CI pipelines can gate changes to the scripts repository on it. The signature isn't checked;
+// candidates are validated before they are signed.
+func validateScripts(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		writeJSON(c, http.StatusBadRequest, problem(http.StatusBadRequest, codeInvalidRequest, "Failed to read request body: "+err.Error()))
+		return
+	}
+	if strings.Contains(c.ContentType(), "yaml") {
+		if body, err = yaml.YAMLToJSON(body); err != nil {
+			writeJSON(c, http.StatusUnprocessableEntity, problemWith(http.StatusUnprocessableEntity, codeDefinitionsInvalid,
+				"failed to parse script definitions YAML: "+err.Error(), gin.H{"valid": false}))
+			return
+		}
+	}
+	policy, err := loadCommandPolicy(configFromContext(c).CommandPolicyPath)
+	if err != nil {
+		writeJSON(c, http.StatusInternalServerError, problem(http.StatusInternalServerError, codeInternal, err.Error()))
+		return
+	}
+
+	definitions, invalid, err := parseScriptDefinitions(body, "request body", policy)
+	if err != nil {
+		writeJSON(c, http.StatusUnprocessableEntity, problemWith(http.StatusUnprocessableEntity, codeDefinitionsInvalid, err.Error(), gin.H{"valid": false}))
+		return
+	}
+	if len(invalid) > 0 {
+		writeJSON(c, http.StatusUnprocessableEntity, problemWith(http.StatusUnprocessableEntity, codeDefinitionsInvalid,
+			fmt.Sprintf("%d of %d script definition(s) are invalid", len(invalid), len(definitions)),
+			gin.H{"valid": false, "definitions": len(definitions), "errors": invalid}))
+		return
+	}
+	writeJSON(c, http.StatusOK, gin.H{"valid": true, "definitions": len(definitions)})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareScriptVersions orders two script versions: dot- or dash-separated numeric segments are
+// compared numerically ("1.10" > "1.9"), other segments lexically, and an optional "v" prefix is
+// ignored. A definition without a version sorts before any versioned one.
+// It returns -1, 0 or 1.
+func compareScriptVersions(a, b string) int {
+	splitVersion := func(v string) []string {
+		v = strings.TrimPrefix(strings.TrimPrefix(v, "v"), "V")
+		if v == "" {
+			return nil
+		}
+		return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' })
+	}
+	segmentsA, segmentsB := splitVersion(a), splitVersion(b)
+	for i := 0; i < len(segmentsA) || i < len(segmentsB); i++ {
+		if i >= len(segmentsA) {
+			return -1
+		}
+		if i >= len(segmentsB) {
+			return 1
+		}
+		numA, errA := strconv.Atoi(segmentsA[i])
+		numB, errB := strconv.Atoi(segmentsB[i])
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case segmentsA[i] != segmentsB[i]:
+			if segmentsA[i] < segmentsB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// findScriptVersion returns the definition of the named script with the given version, or the latest
+// version when version is empty. It returns nil if there is no such script or version.
+func findScriptVersion(definitions []ScriptDefinition, name, version string) *ScriptDefinition {
+	var found *ScriptDefinition
+	for i := range definitions {
+		def := &definitions[i]
+		if def.Name != name {
+			continue
+		}
+		if version != "" {
+			if def.Version == version {
+				return def
+			}
+			continue
+		}
+		if found == nil || compareScriptVersions(def.Version, found.Version) > 0 {
+			found = def
+		}
+	}
+	return found
+}
+
+// latestScriptVersions returns only the latest version of each script, in the order the scripts
+// first appear in the definitions file. Listings, catalogs and schedules only advertise these.
+func latestScriptVersions(definitions []ScriptDefinition) []ScriptDefinition {
+	latest := make([]ScriptDefinition, 0, len(definitions))
+	seen := make(map[string]bool)
+	for _, def := range definitions {
+		if seen[def.Name] {
+			continue
+		}
+		seen[def.Name] = true
+		latest = append(latest, *findScriptVersion(definitions, def.Name, ""))
+	}
+	return latest
+}